@@ -0,0 +1,103 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BaseBackupFormat is the output format of pg_basebackup.
+type BaseBackupFormat string
+
+// Predefined base backup formats.
+const (
+	BaseBackupFormatPlain = BaseBackupFormat("plain")
+	BaseBackupFormatTar   = BaseBackupFormat("tar")
+)
+
+// BaseBackupOptions configures a BaseBackup. The zero value writes a plain copy of the data directory, including the
+// WAL needed to start it, after an immediate checkpoint.
+type BaseBackupOptions struct {
+	// Format of the backup, BaseBackupFormatPlain when empty.
+	Format BaseBackupFormat
+	// Gzip compresses a backup in tar format.
+	Gzip bool
+	// WALMethod is how the WAL is included, one of "stream", "fetch" or "none". It is "stream" when empty.
+	WALMethod string
+	// SpreadCheckpoint waits for a regular checkpoint rather than requesting an immediate one.
+	SpreadCheckpoint bool
+	// Slot is the replication slot used to stream the WAL.
+	Slot string
+	// CreateSlot creates the replication slot before the backup.
+	CreateSlot bool
+	// WriteRecoveryConf configures the backup to start as a standby of this server.
+	WriteRecoveryConf bool
+	// Label of the backup.
+	Label string
+}
+
+// BaseBackup writes a base backup of the server to destDir using the bundled pg_basebackup, for example to test backup
+// tooling or to provision a standby. destDir must be empty or not exist.
+func (ep *EmbeddedPostgres) BaseBackup(ctx context.Context, destDir string, opts BaseBackupOptions) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	output := &bytes.Buffer{}
+	baseBackupProcess := clientCommand(ctx, ep.config, "pg_basebackup", baseBackupArgs(destDir, opts)...)
+	baseBackupProcess.Stdout = output
+	baseBackupProcess.Stderr = output
+
+	if err := baseBackupProcess.Run(); err != nil {
+		return fmt.Errorf("unable to create base backup using '%s': %w\n%s", baseBackupProcess.String(), err, output.String())
+	}
+
+	return nil
+}
+
+func baseBackupArgs(destDir string, opts BaseBackupOptions) []string {
+	format := opts.Format
+	if format == "" {
+		format = BaseBackupFormatPlain
+	}
+
+	walMethod := opts.WALMethod
+	if walMethod == "" {
+		walMethod = "stream"
+	}
+
+	args := []string{
+		"-D", destDir,
+		"--format=" + string(format),
+		"--wal-method=" + walMethod,
+	}
+
+	if opts.SpreadCheckpoint {
+		args = append(args, "--checkpoint=spread")
+	} else {
+		args = append(args, "--checkpoint=fast")
+	}
+
+	if opts.Gzip {
+		args = append(args, "--gzip")
+	}
+
+	if opts.Slot != "" {
+		args = append(args, "--slot="+opts.Slot)
+	}
+
+	if opts.CreateSlot {
+		args = append(args, "--create-slot")
+	}
+
+	if opts.WriteRecoveryConf {
+		args = append(args, "--write-recovery-conf")
+	}
+
+	if opts.Label != "" {
+		args = append(args, "--label="+opts.Label)
+	}
+
+	return args
+}
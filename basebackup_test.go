@@ -0,0 +1,73 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BaseBackup(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		Port(9878))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	plainDir := filepath.Join(tempDir, "plain")
+	require.NoError(t, database.BaseBackup(context.Background(), plainDir, BaseBackupOptions{Label: "beer"}))
+	assert.FileExists(t, filepath.Join(plainDir, "PG_VERSION"))
+	assert.FileExists(t, filepath.Join(plainDir, "backup_label"))
+
+	tarDir := filepath.Join(tempDir, "tar")
+	require.NoError(t, database.BaseBackup(context.Background(), tarDir, BaseBackupOptions{Format: BaseBackupFormatTar}))
+	assert.FileExists(t, filepath.Join(tarDir, "base.tar"))
+}
+
+func Test_BaseBackup_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase()
+
+	err := database.BaseBackup(context.Background(), t.TempDir(), BaseBackupOptions{})
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_baseBackupArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-D", "/backup", "--format=plain", "--wal-method=stream", "--checkpoint=fast"},
+		baseBackupArgs("/backup", BaseBackupOptions{}))
+
+	assert.Equal(t,
+		[]string{"-D", "/backup", "--format=tar", "--wal-method=fetch", "--checkpoint=spread", "--gzip", "--slot=standby", "--create-slot", "--write-recovery-conf", "--label=nightly"},
+		baseBackupArgs("/backup", BaseBackupOptions{
+			Format:            BaseBackupFormatTar,
+			Gzip:              true,
+			WALMethod:         "fetch",
+			SpreadCheckpoint:  true,
+			Slot:              "standby",
+			CreateSlot:        true,
+			WriteRecoveryConf: true,
+			Label:             "nightly",
+		}))
+}
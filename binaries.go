@@ -0,0 +1,111 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binariesMarkerName is the file recording which archive was extracted into the runtime directory, so that the
+// binaries can be reused by later starts rather than extracted again.
+const binariesMarkerName = ".embedded-postgres-binaries"
+
+// cleanRuntimeDirectory empties the runtime directory. Binaries extracted into it by an earlier start from the same
+// archive are kept, as recorded by the binaries marker.
+func (ep *EmbeddedPostgres) cleanRuntimeDirectory(cacheLocation string, cacheExists bool) error {
+	keep := map[string]bool{}
+
+	if ep.config.binariesPath == ep.config.runtimePath && cacheExists {
+		if entries, ok := readBinariesMarker(ep.config.runtimePath, ep.config.version, cacheLocation); ok {
+			keep[binariesMarkerName] = true
+			for _, entry := range entries {
+				keep[entry] = true
+			}
+		}
+	}
+
+	if len(keep) == 0 {
+		if err := os.RemoveAll(ep.config.runtimePath); err != nil {
+			return fmt.Errorf("unable to clean up runtime directory %s with error: %s", ep.config.runtimePath, err)
+		}
+
+		return nil
+	}
+
+	entries, err := os.ReadDir(ep.config.runtimePath)
+	if err != nil {
+		return fmt.Errorf("unable to clean up runtime directory %s with error: %s", ep.config.runtimePath, err)
+	}
+
+	for _, entry := range entries {
+		if keep[entry.Name()] {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(ep.config.runtimePath, entry.Name())); err != nil {
+			return fmt.Errorf("unable to clean up runtime directory %s with error: %s", ep.config.runtimePath, err)
+		}
+	}
+
+	return nil
+}
+
+// writeBinariesMarker records the version and archive fingerprint of the binaries extracted into dir, along with the
+// entries they were extracted to.
+func writeBinariesMarker(dir string, version PostgresVersion, archivePath string) error {
+	fingerprint, err := archiveFingerprint(archivePath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{string(version), fingerprint}
+	for _, entry := range entries {
+		if entry.Name() != binariesMarkerName {
+			lines = append(lines, entry.Name())
+		}
+	}
+
+	return os.WriteFile(filepath.Join(dir, binariesMarkerName), []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// readBinariesMarker returns the entries of the binaries extracted into dir when they match the version and archive.
+func readBinariesMarker(dir string, version PostgresVersion, archivePath string) ([]string, bool) {
+	content, err := os.ReadFile(filepath.Join(dir, binariesMarkerName))
+	if err != nil {
+		return nil, false
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) < 3 || lines[0] != string(version) {
+		return nil, false
+	}
+
+	fingerprint, err := archiveFingerprint(archivePath)
+	if err != nil || lines[1] != fingerprint {
+		return nil, false
+	}
+
+	return lines[2:], true
+}
+
+// archiveFingerprint identifies an archive by its path, size and modification time, which change whenever the cache
+// is updated, without reading the archive on every start as a checksum would.
+func archiveFingerprint(archivePath string) (string, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	absolutePath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %d %d", absolutePath, info.Size(), info.ModTime().UnixNano()), nil
+}
@@ -0,0 +1,82 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cleanRuntimeDirectory_KeepsMatchingBinaries(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "archive.txz")
+	runtimePath := filepath.Join(tempDir, "runtime")
+
+	require.NoError(t, os.WriteFile(archive, []byte("binaries"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "bin"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "lib"), 0700))
+	require.NoError(t, writeBinariesMarker(runtimePath, V15, archive))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "data"), 0700))
+
+	database := NewDatabase(DefaultConfig().RuntimePath(runtimePath).BinariesPath(runtimePath))
+
+	require.NoError(t, database.cleanRuntimeDirectory(archive, true))
+
+	assert.DirExists(t, filepath.Join(runtimePath, "bin"))
+	assert.DirExists(t, filepath.Join(runtimePath, "lib"))
+	assert.FileExists(t, filepath.Join(runtimePath, binariesMarkerName))
+	assert.NoDirExists(t, filepath.Join(runtimePath, "data"))
+}
+
+func Test_cleanRuntimeDirectory_RemovesMismatchedBinaries(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "archive.txz")
+	runtimePath := filepath.Join(tempDir, "runtime")
+
+	require.NoError(t, os.WriteFile(archive, []byte("binaries"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "bin"), 0700))
+	require.NoError(t, writeBinariesMarker(runtimePath, V15, archive))
+
+	// a different version, then a different archive, must not reuse the binaries
+	database := NewDatabase(DefaultConfig().Version(V14).RuntimePath(runtimePath).BinariesPath(runtimePath))
+	require.NoError(t, database.cleanRuntimeDirectory(archive, true))
+	assert.NoDirExists(t, runtimePath)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "bin"), 0700))
+	require.NoError(t, writeBinariesMarker(runtimePath, V15, archive))
+	require.NoError(t, os.WriteFile(archive, []byte("other binaries"), 0600))
+
+	database = NewDatabase(DefaultConfig().RuntimePath(runtimePath).BinariesPath(runtimePath))
+	require.NoError(t, database.cleanRuntimeDirectory(archive, true))
+	assert.NoDirExists(t, runtimePath)
+}
+
+func Test_readBinariesMarker(t *testing.T) {
+	tempDir := t.TempDir()
+	archive := filepath.Join(tempDir, "archive.txz")
+	runtimePath := filepath.Join(tempDir, "runtime")
+
+	require.NoError(t, os.WriteFile(archive, []byte("binaries"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "bin"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimePath, "share"), 0700))
+
+	_, ok := readBinariesMarker(runtimePath, V15, archive)
+	assert.False(t, ok)
+
+	require.NoError(t, writeBinariesMarker(runtimePath, V15, archive))
+
+	entries, ok := readBinariesMarker(runtimePath, V15, archive)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"bin", "share"}, entries)
+
+	_, ok = readBinariesMarker(runtimePath, V14, archive)
+	assert.False(t, ok)
+
+	// an archive replaced in the cache no longer matches
+	require.NoError(t, os.WriteFile(archive, []byte("other binaries"), 0600))
+
+	_, ok = readBinariesMarker(runtimePath, V15, archive)
+	assert.False(t, ok)
+}
@@ -10,11 +10,10 @@ import (
 // The result of whether this cache is present will be returned to exists.
 type CacheLocator func() (location string, exists bool)
 
-func defaultCacheLocator(versionStrategy VersionStrategy) CacheLocator {
+func defaultCacheLocator(cacheDirectory string, versionStrategy VersionStrategy) CacheLocator {
 	return func() (string, bool) {
-		cacheDirectory := ".embedded-postgres-go"
-		if userHome, err := os.UserHomeDir(); err == nil {
-			cacheDirectory = filepath.Join(userHome, ".embedded-postgres-go")
+		if cacheDirectory == "" {
+			cacheDirectory = defaultCacheDirectory()
 		}
 
 		operatingSystem, architecture, version := versionStrategy()
@@ -33,3 +32,11 @@ func defaultCacheLocator(versionStrategy VersionStrategy) CacheLocator {
 		return cacheLocation, !info.IsDir()
 	}
 }
+
+func defaultCacheDirectory() string {
+	if userHome, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(userHome, ".embedded-postgres-go")
+	}
+
+	return ".embedded-postgres-go"
+}
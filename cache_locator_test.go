@@ -1,13 +1,14 @@
 package embeddedpostgres
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_defaultCacheLocator_NotExists(t *testing.T) {
-	locator := defaultCacheLocator(func() (string, string, PostgresVersion) {
+	locator := defaultCacheLocator("", func() (string, string, PostgresVersion) {
 		return "a", "b", "1.2.3"
 	})
 
@@ -16,3 +17,14 @@ func Test_defaultCacheLocator_NotExists(t *testing.T) {
 	assert.Contains(t, cacheLocation, ".embedded-postgres-go/embedded-postgres-binaries-a-b-1.2.3.txz")
 	assert.False(t, exists)
 }
+
+func Test_defaultCacheLocator_CustomCachePath(t *testing.T) {
+	locator := defaultCacheLocator("/custom/cache", func() (string, string, PostgresVersion) {
+		return "a", "b", "1.2.3"
+	})
+
+	cacheLocation, exists := locator()
+
+	assert.Equal(t, filepath.FromSlash("/custom/cache/embedded-postgres-binaries-a-b-1.2.3.txz"), cacheLocation)
+	assert.False(t, exists)
+}
@@ -0,0 +1,60 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockCache takes an exclusive lock on a file next to the cache location, blocking until any other process holding the
+// lock releases it. The mutex only serializes instances within one process, while the file lock stops concurrent test
+// binaries on a fresh machine from each downloading and extracting the same archive. Without a cache location there is
+// nothing to lock, rather than a lock file in the working directory. The lock file is removed again on release.
+func lockCache(cacheLocation string) (func(), error) {
+	if cacheLocation == "" {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cacheLocation), 0755); err != nil {
+		return nil, fmt.Errorf("unable to lock cache %s: %w", cacheLocation, err)
+	}
+
+	lockPath := cacheLocation + ".lock"
+
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to lock cache %s: %w", cacheLocation, err)
+		}
+
+		if err := lockFileExclusive(lockFile); err != nil {
+			_ = lockFile.Close()
+			return nil, fmt.Errorf("unable to lock cache %s: %w", cacheLocation, err)
+		}
+
+		// the holder removes the lock file on release, so a lock taken on a file which was removed while waiting for it
+		// excludes nobody, and is taken again on the file now at the path
+		if lockedFileIsCurrent(lockFile, lockPath) {
+			return func() {
+				releaseLockFile(lockFile, lockPath)
+			}, nil
+		}
+
+		_ = unlockFile(lockFile)
+		_ = lockFile.Close()
+	}
+}
+
+func lockedFileIsCurrent(lockFile *os.File, lockPath string) bool {
+	locked, err := lockFile.Stat()
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(lockPath)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(locked, current)
+}
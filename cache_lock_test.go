@@ -0,0 +1,102 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lockCache_BlocksUntilReleased(t *testing.T) {
+	cacheLocation := filepath.Join(t.TempDir(), "cache", "embedded-postgres-binaries.txz")
+
+	unlock, err := lockCache(cacheLocation)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+
+	go func() {
+		unlockSecond, err := lockCache(cacheLocation)
+		if err == nil {
+			unlockSecond()
+		}
+
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+
+	assert.NoFileExists(t, cacheLocation+".lock")
+}
+
+func Test_lockCache_ExcludesAfterRemoval(t *testing.T) {
+	cacheLocation := filepath.Join(t.TempDir(), "embedded-postgres-binaries.txz")
+
+	unlock, err := lockCache(cacheLocation)
+	require.NoError(t, err)
+
+	holders := make(chan func(), 2)
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			unlockWaiter, err := lockCache(cacheLocation)
+			if err == nil {
+				holders <- unlockWaiter
+			}
+		}()
+	}
+
+	// lets both waiters open the lock file before it is removed
+	time.Sleep(100 * time.Millisecond)
+	unlock()
+
+	first := <-holders
+
+	select {
+	case <-holders:
+		t.Fatal("lock acquired by both waiters")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first()
+
+	select {
+	case second := <-holders:
+		second()
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+}
+
+func Test_lockCache_ErrorWhenDirectoryCannotBeCreated(t *testing.T) {
+	_, err := lockCache(filepath.Join("/dev/null", "embedded-postgres-binaries.txz"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to lock cache")
+}
+
+func Test_lockCache_EmptyLocation(t *testing.T) {
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+
+	unlock, err := lockCache("")
+	require.NoError(t, err)
+	unlock()
+
+	assert.NoFileExists(t, filepath.Join(workingDir, ".lock"))
+}
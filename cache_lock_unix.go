@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package embeddedpostgres
+
+import (
+	"os"
+	"syscall"
+)
+
+func lockFileExclusive(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// releaseLockFile removes the lock file before unlocking it, so that processes waiting for the lock find it removed
+// once they take it, rather than another process creating a new one.
+func releaseLockFile(file *os.File, path string) {
+	_ = os.Remove(path)
+	_ = unlockFile(file)
+	_ = file.Close()
+}
@@ -0,0 +1,48 @@
+//go:build windows
+// +build windows
+
+package embeddedpostgres
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const lockfileExclusiveLock = 0x2
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+func lockFileExclusive(file *os.File) error {
+	var overlapped syscall.Overlapped
+
+	result, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+
+	result, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if result == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// releaseLockFile removes the lock file once unlocked and closed, as open files cannot be removed on Windows. The
+// removal fails, leaving the file in place, while another process has it open waiting for the lock.
+func releaseLockFile(file *os.File, path string) {
+	_ = unlockFile(file)
+	_ = file.Close()
+	_ = os.Remove(path)
+}
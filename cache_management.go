@@ -0,0 +1,204 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// cachedArchivePrefix and cachedArchiveExtension surround the platform and version in the names of cached archives.
+	cachedArchivePrefix    = "embedded-postgres-binaries-"
+	cachedArchiveExtension = ".txz"
+)
+
+// CachedBinaries describes an archive of Postgres binaries in the cache directory, together with the binaries extracted
+// from it.
+type CachedBinaries struct {
+	OperatingSystem string
+	Architecture    string
+	Version         PostgresVersion
+	// ArchivePath is the downloaded archive.
+	ArchivePath string
+	// BinariesPath is the directory the archive was extracted to, or empty when it has not been extracted.
+	BinariesPath string
+	// Size is the number of bytes used by the archive and the extracted binaries.
+	Size int64
+}
+
+// ListCache returns the binaries in the cache directory, or in the default cache directory when empty, ordered by
+// version and platform.
+func ListCache(cacheDirectory string) ([]CachedBinaries, error) {
+	if cacheDirectory == "" {
+		cacheDirectory = defaultCacheDirectory()
+	}
+
+	entries, err := os.ReadDir(cacheDirectory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to list cache directory %s: %w", cacheDirectory, err)
+	}
+
+	var cached []CachedBinaries
+
+	for _, entry := range entries {
+		binaries, ok := parseCachedArchiveName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		binaries.ArchivePath = filepath.Join(cacheDirectory, entry.Name())
+		binaries.Size = directorySize(binaries.ArchivePath)
+
+		binariesPath := filepath.Join(cacheDirectory, "bin", strings.TrimSuffix(strings.TrimPrefix(entry.Name(), cachedArchivePrefix), cachedArchiveExtension))
+		if info, err := os.Stat(binariesPath); err == nil && info.IsDir() {
+			binaries.BinariesPath = binariesPath
+			binaries.Size += directorySize(binariesPath)
+		}
+
+		cached = append(cached, binaries)
+	}
+
+	sort.Slice(cached, func(i, j int) bool {
+		if cached[i].Version != cached[j].Version {
+			return versionLess(cached[i].Version, cached[j].Version)
+		}
+
+		return cached[i].ArchivePath < cached[j].ArchivePath
+	})
+
+	return cached, nil
+}
+
+// RemoveFromCache removes the archives and extracted binaries of a version from the cache directory, or from the
+// default cache directory when empty, for every platform. A major version such as "14" removes all its minor versions.
+// It returns the removed binaries.
+func RemoveFromCache(cacheDirectory string, version PostgresVersion) ([]CachedBinaries, error) {
+	cached, err := ListCache(cacheDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []CachedBinaries
+
+	for _, binaries := range cached {
+		if binaries.Version != version && !strings.HasPrefix(string(binaries.Version), string(version)+".") {
+			continue
+		}
+
+		if err := removeCachedBinaries(binaries); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, binaries)
+	}
+
+	return removed, nil
+}
+
+// removeCachedBinaries removes cached binaries while holding the cache lock, so that they are not removed while being
+// downloaded or extracted.
+func removeCachedBinaries(binaries CachedBinaries) error {
+	unlock, err := lockCache(binaries.ArchivePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if binaries.BinariesPath != "" {
+		if err := os.RemoveAll(binaries.BinariesPath); err != nil {
+			return fmt.Errorf("unable to remove binaries %s: %w", binaries.BinariesPath, err)
+		}
+	}
+
+	if err := os.Remove(binaries.ArchivePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove archive %s: %w", binaries.ArchivePath, err)
+	}
+
+	return nil
+}
+
+// PurgeCache removes the cache directory, or the default cache directory when empty, with all cached archives,
+// extracted binaries and initialized data directories. Servers using the shared binaries must be stopped first.
+func PurgeCache(cacheDirectory string) error {
+	if cacheDirectory == "" {
+		cacheDirectory = defaultCacheDirectory()
+	}
+
+	if err := os.RemoveAll(cacheDirectory); err != nil {
+		return fmt.Errorf("unable to purge cache directory %s: %w", cacheDirectory, err)
+	}
+
+	return nil
+}
+
+// parseCachedArchiveName parses the platform and version from the name of a cached archive, such as
+// embedded-postgres-binaries-linux-amd64-alpine-15.3.0.txz.
+func parseCachedArchiveName(name string) (CachedBinaries, bool) {
+	if !strings.HasPrefix(name, cachedArchivePrefix) || !strings.HasSuffix(name, cachedArchiveExtension) {
+		return CachedBinaries{}, false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(name, cachedArchivePrefix), cachedArchiveExtension), "-")
+	if len(parts) < 3 {
+		return CachedBinaries{}, false
+	}
+
+	return CachedBinaries{
+		OperatingSystem: parts[0],
+		Architecture:    strings.Join(parts[1:len(parts)-1], "-"),
+		Version:         PostgresVersion(parts[len(parts)-1]),
+	}, true
+}
+
+// versionLess compares versions by their numeric parts, so that 9.6.24 sorts before 10.23.0.
+func versionLess(a, b PostgresVersion) bool {
+	aParts, bParts := strings.Split(string(a), "."), strings.Split(string(b), ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.Atoi(aParts[i])
+		bNumber, bErr := strconv.Atoi(bParts[i])
+
+		if aErr != nil || bErr != nil {
+			if aParts[i] != bParts[i] {
+				return aParts[i] < bParts[i]
+			}
+
+			continue
+		}
+
+		if aNumber != bNumber {
+			return aNumber < bNumber
+		}
+	}
+
+	return len(aParts) < len(bParts)
+}
+
+// directorySize returns the number of bytes used by the files in a directory, or by a single file.
+func directorySize(path string) int64 {
+	var size int64
+
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+
+		return nil
+	})
+
+	return size
+}
@@ -0,0 +1,122 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCachedBinaries(t *testing.T, cacheDirectory, platform, version string, extracted bool) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(cacheDirectory, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDirectory, "embedded-postgres-binaries-"+platform+"-"+version+".txz"), make([]byte, 100), 0600))
+
+	if extracted {
+		binDir := filepath.Join(cacheDirectory, "bin", platform+"-"+version, "bin")
+		require.NoError(t, os.MkdirAll(binDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(binDir, "postgres"), make([]byte, 50), 0700))
+	}
+}
+
+func Test_ListCache(t *testing.T) {
+	cacheDirectory := t.TempDir()
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "15.3.0", true)
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64-alpine", "9.6.24", false)
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "10.23.0", false)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDirectory, "embedded-postgres-binaries-linux-amd64-15.3.0.txz.lock"), nil, 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDirectory, "initdb", "0123456789abcdef"), 0755))
+
+	cached, err := ListCache(cacheDirectory)
+	require.NoError(t, err)
+
+	assert.Equal(t, []CachedBinaries{
+		{
+			OperatingSystem: "linux",
+			Architecture:    "amd64-alpine",
+			Version:         "9.6.24",
+			ArchivePath:     filepath.Join(cacheDirectory, "embedded-postgres-binaries-linux-amd64-alpine-9.6.24.txz"),
+			Size:            100,
+		},
+		{
+			OperatingSystem: "linux",
+			Architecture:    "amd64",
+			Version:         "10.23.0",
+			ArchivePath:     filepath.Join(cacheDirectory, "embedded-postgres-binaries-linux-amd64-10.23.0.txz"),
+			Size:            100,
+		},
+		{
+			OperatingSystem: "linux",
+			Architecture:    "amd64",
+			Version:         "15.3.0",
+			ArchivePath:     filepath.Join(cacheDirectory, "embedded-postgres-binaries-linux-amd64-15.3.0.txz"),
+			BinariesPath:    filepath.Join(cacheDirectory, "bin", "linux-amd64-15.3.0"),
+			Size:            150,
+		},
+	}, cached)
+}
+
+func Test_ListCache_EmptyWhenMissing(t *testing.T) {
+	cached, err := ListCache(filepath.Join(t.TempDir(), "missing"))
+
+	require.NoError(t, err)
+	assert.Empty(t, cached)
+}
+
+func Test_RemoveFromCache(t *testing.T) {
+	cacheDirectory := t.TempDir()
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "15.3.0", true)
+	writeCachedBinaries(t, cacheDirectory, "linux-arm64v8", "15.4.0", false)
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "14.8.0", true)
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "1.0.0", false)
+
+	removed, err := RemoveFromCache(cacheDirectory, "15")
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	assert.Equal(t, PostgresVersion("15.3.0"), removed[0].Version)
+	assert.Equal(t, PostgresVersion("15.4.0"), removed[1].Version)
+
+	assert.NoDirExists(t, filepath.Join(cacheDirectory, "bin", "linux-amd64-15.3.0"))
+	assert.NoFileExists(t, filepath.Join(cacheDirectory, "embedded-postgres-binaries-linux-amd64-15.3.0.txz"))
+
+	remaining, err := ListCache(cacheDirectory)
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, PostgresVersion("1.0.0"), remaining[0].Version)
+	assert.Equal(t, PostgresVersion("14.8.0"), remaining[1].Version)
+
+	removed, err = RemoveFromCache(cacheDirectory, "14.8.0")
+	require.NoError(t, err)
+	assert.Len(t, removed, 1)
+}
+
+func Test_PurgeCache(t *testing.T) {
+	cacheDirectory := filepath.Join(t.TempDir(), "cache")
+	writeCachedBinaries(t, cacheDirectory, "linux-amd64", "15.3.0", true)
+
+	require.NoError(t, PurgeCache(cacheDirectory))
+
+	assert.NoDirExists(t, cacheDirectory)
+}
+
+func Test_parseCachedArchiveName(t *testing.T) {
+	binaries, ok := parseCachedArchiveName("embedded-postgres-binaries-darwin-arm64v8-14.8.0.txz")
+	assert.True(t, ok)
+	assert.Equal(t, CachedBinaries{OperatingSystem: "darwin", Architecture: "arm64v8", Version: "14.8.0"}, binaries)
+
+	_, ok = parseCachedArchiveName("embedded-postgres-binaries-linux-amd64-15.3.0.txz.lock")
+	assert.False(t, ok)
+
+	_, ok = parseCachedArchiveName("embedded-postgres-binaries-15.3.0.txz")
+	assert.False(t, ok)
+}
+
+func Test_versionLess(t *testing.T) {
+	assert.True(t, versionLess("9.6.24", "10.23.0"))
+	assert.True(t, versionLess("15.3.0", "15.10.0"))
+	assert.False(t, versionLess("15.3.0", "15.3.0"))
+	assert.True(t, versionLess("15", "15.3.0"))
+}
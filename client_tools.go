@@ -0,0 +1,46 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ClientCommand builds a command running one of the bundled client binaries, such as psql, pg_dump or pg_restore,
+// connected to the server as the configured user and to the configured database unless the arguments name another. The
+// server need not have been started by this process, so that tools can connect to a server started by another one.
+func (ep *EmbeddedPostgres) ClientCommand(ctx context.Context, binary string, args ...string) *exec.Cmd {
+	config := ep.config
+	config.binariesPath = ep.BinariesPath()
+
+	clientProcess := clientCommand(ctx, config, binary, args...)
+	clientProcess.Env = append(clientProcess.Env, "PGDATABASE="+config.database)
+
+	return clientProcess
+}
+
+// clientCommand builds a command running one of the bundled client binaries, such as psql or pg_dump, connected to the
+// server as the configured user.
+func clientCommand(ctx context.Context, config Config, binary string, args ...string) *exec.Cmd {
+	connectionArgs := []string{
+		"-h", "localhost",
+		"-p", strconv.FormatUint(uint64(config.port), 10),
+		"-U", config.username,
+	}
+
+	clientBinary := filepath.Join(config.binariesPath, "bin", binary)
+	clientProcess := exec.CommandContext(ctx, clientBinary, append(connectionArgs, args...)...)
+	clientProcess.Env = append(os.Environ(), "PGPASSWORD="+config.password)
+
+	// the client binaries take the SSL settings from the environment, such as PGSSLCERT for sslcert
+	if config.requireClientCertificates {
+		for key, values := range config.clientSSLSettings() {
+			clientProcess.Env = append(clientProcess.Env, "PG"+strings.ToUpper(key)+"="+values[0])
+		}
+	}
+
+	return clientProcess
+}
@@ -0,0 +1,37 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_clientCommand(t *testing.T) {
+	config := DefaultConfig().
+		BinariesPath("/tmp/pg").
+		Port(9855).
+		Username("beer").
+		Password("wine")
+
+	cmd := clientCommand(context.Background(), config, "psql", "-d", "gin")
+
+	assert.Equal(t, []string{filepath.Join("/tmp/pg", "bin", "psql"), "-h", "localhost", "-p", "9855", "-U", "beer", "-d", "gin"}, cmd.Args)
+	assert.Contains(t, cmd.Env, "PGPASSWORD=wine")
+}
+
+func Test_ClientCommand(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		BinariesPath("/tmp/pg").
+		Port(9855).
+		Username("beer").
+		Password("wine").
+		Database("gin"))
+
+	cmd := database.ClientCommand(context.Background(), "psql", "-c", "SELECT 1")
+
+	assert.Equal(t, []string{filepath.Join("/tmp/pg", "bin", "psql"), "-h", "localhost", "-p", "9855", "-U", "beer", "-c", "SELECT 1"}, cmd.Args)
+	assert.Contains(t, cmd.Env, "PGPASSWORD=wine")
+	assert.Contains(t, cmd.Env, "PGDATABASE=gin")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+const cacheUsage = `Usage: embedded-postgres cache <command> [flags]
+
+Commands:
+  ls              list the cached binaries
+  rm <version>    remove the cached binaries of a version, such as 15 or 14.8.0
+  purge           remove the whole cache directory
+`
+
+// runCache manages the cache directory of downloaded and extracted binaries.
+func runCache(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, cacheUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	cacheDir := flags.String("cache-dir", "", "cache directory (default $HOME/.embedded-postgres-go)")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "ls":
+		return listCache(*cacheDir, stdout, stderr)
+	case "rm":
+		if flags.NArg() != 1 {
+			_, _ = fmt.Fprintf(stderr, "embedded-postgres: cache rm takes one version\n\n%s", cacheUsage)
+			return 2
+		}
+
+		return removeFromCache(*cacheDir, embeddedpostgres.PostgresVersion(flags.Arg(0)), stdout, stderr)
+	case "purge":
+		if err := embeddedpostgres.PurgeCache(*cacheDir); err != nil {
+			return fail(stderr, err)
+		}
+
+		return 0
+	default:
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: unknown cache command %q\n\n%s", args[0], cacheUsage)
+		return 2
+	}
+}
+
+func listCache(cacheDir string, stdout, stderr io.Writer) int {
+	cached, err := embeddedpostgres.ListCache(cacheDir)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "VERSION\tOS\tARCH\tSIZE\tEXTRACTED")
+
+	for _, binaries := range cached {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%t\n",
+			binaries.Version, binaries.OperatingSystem, binaries.Architecture, formatSize(binaries.Size), binaries.BinariesPath != "")
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+func removeFromCache(cacheDir string, version embeddedpostgres.PostgresVersion, stdout, stderr io.Writer) int {
+	removed, err := embeddedpostgres.RemoveFromCache(cacheDir, version)
+
+	for _, binaries := range removed {
+		_, _ = fmt.Fprintf(stdout, "removed %s %s-%s\n", binaries.Version, binaries.OperatingSystem, binaries.Architecture)
+	}
+
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if len(removed) == 0 {
+		return fail(stderr, errors.New("no cached binaries of version "+string(version)))
+	}
+
+	return 0
+}
+
+// formatSize formats a number of bytes for humans.
+func formatSize(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	size, exponent := float64(bytes)/unit, 0
+	for size >= unit && exponent < 3 {
+		size /= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", size, "KMGT"[exponent])
+}
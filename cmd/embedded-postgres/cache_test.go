@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCachedArchive(t *testing.T, cacheDir, name string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(cacheDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, name), make([]byte, 2048), 0600))
+}
+
+func Test_runCache_ls(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedArchive(t, cacheDir, "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+
+	stdout := &bytes.Buffer{}
+
+	assert.Equal(t, 0, run([]string{"cache", "ls", "--cache-dir", cacheDir}, stdout, &bytes.Buffer{}))
+	assert.Equal(t, "VERSION  OS     ARCH   SIZE     EXTRACTED\n15.3.0   linux  amd64  2.0 KiB  false\n", stdout.String())
+}
+
+func Test_runCache_rm(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedArchive(t, cacheDir, "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+	writeCachedArchive(t, cacheDir, "embedded-postgres-binaries-linux-amd64-14.8.0.txz")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	assert.Equal(t, 0, run([]string{"cache", "rm", "--cache-dir", cacheDir, "15"}, stdout, stderr))
+	assert.Equal(t, "removed 15.3.0 linux-amd64\n", stdout.String())
+	assert.NoFileExists(t, filepath.Join(cacheDir, "embedded-postgres-binaries-linux-amd64-15.3.0.txz"))
+	assert.FileExists(t, filepath.Join(cacheDir, "embedded-postgres-binaries-linux-amd64-14.8.0.txz"))
+
+	assert.Equal(t, 1, run([]string{"cache", "rm", "--cache-dir", cacheDir, "15"}, stdout, stderr))
+	assert.Contains(t, stderr.String(), "no cached binaries of version 15")
+
+	assert.Equal(t, 2, run([]string{"cache", "rm", "--cache-dir", cacheDir}, stdout, stderr))
+}
+
+func Test_runCache_purge(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	writeCachedArchive(t, cacheDir, "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+
+	assert.Equal(t, 0, run([]string{"cache", "purge", "--cache-dir", cacheDir}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.NoDirExists(t, cacheDir)
+}
+
+func Test_runCache_UnknownCommand(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"cache", "clean"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), `unknown cache command "clean"`)
+}
+
+func Test_formatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "40.0 MiB", formatSize(40*1024*1024))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// controlStatus is the response of /status.
+type controlStatus struct {
+	embeddedpostgres.ConnectionInfo
+	PID int `json:"pid"`
+}
+
+// controlEndpoint is where the control API is served and the token authenticating requests to it, as published with
+// --json and recorded in the state file.
+type controlEndpoint struct {
+	URL   string `json:"control_url,omitempty"`
+	Token string `json:"control_token,omitempty"`
+}
+
+// controlAPI serves the HTTP control API of a running server, letting test harnesses not written in Go inspect it, create
+// and reset databases and stop it.
+type controlAPI struct {
+	database *embeddedpostgres.EmbeddedPostgres
+	// token must be sent as a bearer token with every request.
+	token string
+	// stops receives a channel for each stop request, on which the command reports the outcome of stopping the server.
+	stops chan chan error
+}
+
+func newControlAPI(database *embeddedpostgres.EmbeddedPostgres, token string) *controlAPI {
+	return &controlAPI{database: database, token: token, stops: make(chan chan error)}
+}
+
+// newControlToken generates the random token authenticating requests to the control API of a server.
+func newControlToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("unable to generate control API token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func (c *controlAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", only(http.MethodGet, c.status))
+	mux.HandleFunc("/stop", only(http.MethodPost, c.stop))
+	mux.HandleFunc("/create-database", only(http.MethodPost, c.createDatabase))
+	mux.HandleFunc("/snapshot", only(http.MethodPost, c.snapshot))
+	mux.HandleFunc("/reset", only(http.MethodPost, c.reset))
+
+	return c.authorize(mux)
+}
+
+// authorize only passes on requests addressed to a loopback host and port, so that web pages cannot reach the API
+// through a DNS name rebound to the loopback address, and carrying the bearer token of the API.
+func (c *controlAPI) authorize(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, err := net.SplitHostPort(r.Host); err != nil || !isLoopback(host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("requests must be addressed to a localhost address and port, not %q", r.Host))
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+c.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("the bearer token of the control API is required"))
+
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (c *controlAPI) status(w http.ResponseWriter, _ *http.Request) {
+	pid, err := c.database.PID()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, controlStatus{ConnectionInfo: c.database.ConnectionInfo(), PID: pid})
+}
+
+// stop hands the request to the command, which stops the server and exits once the response has been written.
+func (c *controlAPI) stop(w http.ResponseWriter, r *http.Request) {
+	stopped := make(chan error, 1)
+
+	select {
+	case c.stops <- stopped:
+	case <-r.Context().Done():
+		return
+	}
+
+	if err := <-stopped; err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+}
+
+func (c *controlAPI) createDatabase(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the name parameter is required"))
+		return
+	}
+
+	if err := c.database.CreateDatabase(name); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	info := c.database.ConnectionInfo()
+	writeJSON(w, http.StatusCreated, map[string]string{"database": name, "dsn": databaseDSN(info, name)})
+}
+
+func (c *controlAPI) snapshot(w http.ResponseWriter, r *http.Request) {
+	name := c.databaseName(r)
+
+	if err := c.database.SnapshotTemplate(name); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"database": name})
+}
+
+func (c *controlAPI) reset(w http.ResponseWriter, r *http.Request) {
+	name := c.databaseName(r)
+
+	if err := c.database.ResetFromTemplate(name); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"database": name})
+}
+
+// databaseName is the database named by the name parameter, or the configured database.
+func (c *controlAPI) databaseName(r *http.Request) string {
+	if name := r.URL.Query().Get("name"); name != "" {
+		return name
+	}
+
+	return c.database.ConnectionInfo().Database
+}
+
+// databaseDSN is the DSN of another database on the server, with the same credentials and parameters.
+func databaseDSN(info embeddedpostgres.ConnectionInfo, database string) string {
+	dsn, err := url.Parse(info.DSN)
+	if err != nil {
+		return ""
+	}
+
+	dsn.Path = "/" + database
+
+	return dsn.String()
+}
+
+func only(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires %s", r.URL.Path, method))
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// listenControl listens on the address of the control API, which must be on the loopback interface so that only local
+// programs can reach it.
+func listenControl(address string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid control API address %s: %w", address, err)
+	}
+
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("the control API must listen on localhost, not %s", host)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the control API on %s: %w", address, err)
+	}
+
+	return listener, nil
+}
+
+// startControlServer serves the control API on the listener until the returned function is called, which lets the response to
+// a stop request be written before shutting down.
+func startControlServer(control *controlAPI, listener net.Listener) func() {
+	server := &http.Server{Handler: control.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(ctx)
+	}
+}
+
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// controlStartTimeout bounds how long start --daemon waits for the control process to serve the control API.
+const controlStartTimeout = 10 * time.Second
+
+// runControl serves the control API of the server recorded in the state file by start --daemon --http, for as long as
+// the server runs. It is run in the background by start, as the server outlives the start command.
+func runControl(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("control", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stateFile := flags.String("state-file", defaultStateFile(), "file recording the server started with --daemon")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	state, err := readState(*stateFile)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if state.URL == "" {
+		return fail(stderr, fmt.Errorf("no control API is recorded in %s, start the server with --http", *stateFile))
+	}
+
+	address, err := url.Parse(state.URL)
+	if err != nil {
+		return fail(stderr, fmt.Errorf("invalid control API URL %s: %w", state.URL, err))
+	}
+
+	listener, err := listenControl(address.Host)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	database := state.database(stderr)
+
+	if err := database.Attach(); err != nil {
+		_ = listener.Close()
+		return fail(stderr, err)
+	}
+
+	control := newControlAPI(database, state.Token)
+	defer startControlServer(control, listener)()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- database.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		// leaves the server running, to be stopped by the stop command
+		if err := database.Detach(); err != nil {
+			return fail(stderr, err)
+		}
+
+		return 0
+	case stopped := <-control.stops:
+		err := database.Stop()
+		if err == nil {
+			err = removeState(*stateFile, state)
+		}
+
+		stopped <- err
+
+		if err != nil {
+			return fail(stderr, err)
+		}
+
+		return 0
+	case err := <-exited:
+		return fail(stderr, err)
+	}
+}
+
+// startControlProcess runs the control command in the background for the server recorded in the state file, and waits
+// until it serves the control API.
+func startControlProcess(stateFile string, endpoint controlEndpoint) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to start the control API: %w", err)
+	}
+
+	// the output is discarded, as inheriting it would keep pipes to the start command open for as long as the server runs
+	cmd := exec.Command(executable, "control", "--state-file", stateFile)
+	detachProcess(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start the control API: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	if err := waitForControl(endpoint, exited); err != nil {
+		_ = cmd.Process.Kill()
+		return err
+	}
+
+	return nil
+}
+
+// waitForControl polls the status of the control API until it answers, the control process exits or it times out.
+func waitForControl(endpoint controlEndpoint, exited <-chan error) error {
+	timeout := time.After(controlStartTimeout)
+
+	for {
+		if callControl(endpoint, http.MethodGet, "/status") == nil {
+			return nil
+		}
+
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the control API exited before serving requests: %v", err)
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for the control API at %s", endpoint.URL)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// stopThroughControl stops the server through its control API.
+func stopThroughControl(endpoint controlEndpoint) error {
+	return callControl(endpoint, http.MethodPost, "/stop")
+}
+
+// callControl sends a request to the control API, returning the error it responded with, if any.
+func callControl(endpoint controlEndpoint, method, path string) error {
+	request, err := http.NewRequest(method, endpoint.URL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+endpoint.Token)
+
+	client := &http.Client{Timeout: time.Minute}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode/100 == 2 {
+		return nil
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil || body["error"] == "" {
+		return fmt.Errorf("the control API responded with %s", response.Status)
+	}
+
+	return errors.New(body["error"])
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is the DETACHED_PROCESS creation flag, which starts a process without a console.
+const detachedProcess = 0x00000008
+
+// detachProcess starts the process without a console and in a process group of its own, so that it is not stopped
+// along with the console of the command starting it.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// daemonState records a server left running by start --daemon, so that stop can take it over again. It is written as
+// JSON with the connection details alongside the process ID of the server.
+type daemonState struct {
+	embeddedpostgres.ConnectionInfo
+	// controlEndpoint is set when the control API of the server is served by a control process.
+	controlEndpoint
+	PID         int    `json:"pid"`
+	RuntimeDir  string `json:"runtime_dir"`
+	BinariesDir string `json:"binaries_dir"`
+	PortFile    string `json:"port_file,omitempty"`
+}
+
+// defaultStateFile is the state file used unless another is given, allowing one daemon per user.
+func defaultStateFile() string {
+	if userHome, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(userHome, ".embedded-postgres-go", "daemon.json")
+	}
+
+	return filepath.Join(".embedded-postgres-go", "daemon.json")
+}
+
+// startDaemon records the started server in the state file and detaches it, so that it keeps running once the command
+// exits. When the control API is to be served, a control process is started to serve it for as long as the server runs.
+func startDaemon(database *embeddedpostgres.EmbeddedPostgres, stateFile string, endpoint controlEndpoint, output *outputFlags, stdout, stderr io.Writer) int {
+	pid, err := database.PID()
+	if err == nil {
+		err = writeState(stateFile, daemonState{
+			ConnectionInfo:  database.ConnectionInfo(),
+			controlEndpoint: endpoint,
+			PID:             pid,
+			RuntimeDir:      database.RuntimePath(),
+			BinariesDir:     database.BinariesPath(),
+			PortFile:        output.portFile,
+		})
+	}
+
+	if err != nil {
+		_ = database.Stop()
+		return fail(stderr, err)
+	}
+
+	if err := database.Detach(); err != nil {
+		return fail(stderr, err)
+	}
+
+	// the control process takes the server over, so it is only started once the server has been detached
+	if endpoint.URL != "" {
+		if err := startControlProcess(stateFile, endpoint); err != nil {
+			return fail(stderr, fmt.Errorf("%w, the server is left running, stop it with embedded-postgres stop", err))
+		}
+
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: control API at %s, authenticate with the bearer token recorded in %s\n",
+			endpoint.URL, stateFile)
+	}
+
+	// the connection details are published last, so that programs waiting for them can use the control API right away
+	if err := output.publish(database, endpoint, stdout); err != nil {
+		return fail(stderr, fmt.Errorf("%w, the server is left running, stop it with embedded-postgres stop", err))
+	}
+
+	_, _ = fmt.Fprintf(stderr, "embedded-postgres: running in the background at %s with process ID %d, "+
+		"stop with embedded-postgres stop\n", database.GetConnectionURL(), pid)
+
+	return 0
+}
+
+// runStop stops the server recorded in the state file by start --daemon.
+func runStop(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("stop", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stateFile := flags.String("state-file", defaultStateFile(), "file recording the server started with --daemon")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	state, err := readState(*stateFile)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	// a control process stops the server and removes the state file itself, the server is only taken over when the
+	// control process is no longer running
+	if state.URL != "" {
+		if err := stopThroughControl(state.controlEndpoint); err == nil {
+			return 0
+		}
+	}
+
+	database := state.database(stderr)
+
+	if err := database.Attach(); err != nil {
+		return fail(stderr, fmt.Errorf("%w, remove %s if the server is no longer running", err, *stateFile))
+	}
+
+	if err := database.Stop(); err != nil {
+		return fail(stderr, err)
+	}
+
+	if err := removeState(*stateFile, state); err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+// removeState removes the state file of a stopped server, along with its port file.
+func removeState(path string, state daemonState) error {
+	if state.PortFile != "" {
+		_ = os.Remove(state.PortFile)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("unable to remove state file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// database describes the recorded server, which is not supervised until attached.
+func (s daemonState) database(logger io.Writer) *embeddedpostgres.EmbeddedPostgres {
+	return embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(s.Port).
+		Username(s.Username).
+		Password(s.Password).
+		Database(s.Database).
+		RuntimePath(s.RuntimeDir).
+		DataPath(s.DataDir).
+		BinariesPath(s.BinariesDir).
+		Logger(logger))
+}
+
+func writeState(path string, state daemonState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("unable to write state file %s: %w", path, err)
+	}
+
+	// the state includes the password
+	if err := os.WriteFile(path, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("unable to write state file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func readState(path string) (daemonState, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return daemonState{}, fmt.Errorf("no server started with --daemon is recorded in %s", path)
+	}
+
+	if err != nil {
+		return daemonState{}, fmt.Errorf("unable to read state file %s: %w", path, err)
+	}
+
+	var state daemonState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return daemonState{}, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// runDoctor checks the environment for problems which would stop a server from starting, printing a finding for each
+// check. It fails when any check found an error.
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := addServerFlags(flags)
+	cacheDir := flags.String("cache-dir", "", "cache directory (default $HOME/.embedded-postgres-go)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	config, err := server.config()
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if *cacheDir != "" {
+		config = config.CachePath(*cacheDir)
+	}
+
+	exitCode := 0
+
+	for _, finding := range embeddedpostgres.NewDatabase(config).Diagnose() {
+		_, _ = fmt.Fprintln(stdout, finding)
+
+		if finding.Severity == embeddedpostgres.SeverityError {
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runDoctor(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	stdout := &bytes.Buffer{}
+
+	assert.Equal(t, 1, run([]string{"doctor", "--port", port, "--cache-dir", t.TempDir()}, stdout, &bytes.Buffer{}))
+	assert.Contains(t, stdout.String(), "[error] port: ")
+	assert.Contains(t, stdout.String(), "] libc: ")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// runDump dumps a database of the server recorded by start --daemon using the bundled pg_dump.
+func runDump(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stateFile := flags.String("state-file", defaultStateFile(), "file recording the server started with --daemon")
+	database := flags.String("database", "", "database to dump (default the database of the server)")
+	format := flags.String("format", "plain", "format of the dump, plain SQL or custom for pg_restore")
+	schemaOnly := flags.Bool("schema-only", false, "dump only the object definitions")
+	dataOnly := flags.Bool("data-only", false, "dump only the data")
+	output := flags.String("output", "", "file to write the dump to (default stdout)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	options := embeddedpostgres.DumpOptions{
+		Format:     embeddedpostgres.DumpFormat(*format),
+		SchemaOnly: *schemaOnly,
+		DataOnly:   *dataOnly,
+	}
+
+	if options.Format != embeddedpostgres.DumpFormatPlain && options.Format != embeddedpostgres.DumpFormatCustom {
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: unknown dump format %q, use plain or custom\n", *format)
+		return 2
+	}
+
+	w := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fail(stderr, fmt.Errorf("unable to create dump file %s: %w", *output, err))
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		w = file
+	}
+
+	err := withDaemon(*stateFile, stderr, func(server *embeddedpostgres.EmbeddedPostgres) error {
+		return server.Dump(context.Background(), databaseOrDefault(*database, server), w, options)
+	})
+	if err != nil {
+		if *output != "" {
+			_ = os.Remove(*output)
+		}
+
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+// runRestore restores a dump written by dump, or by pg_dump, into a database of the server recorded by start --daemon.
+func runRestore(args []string, stdin io.Reader, stderr io.Writer) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stateFile := flags.String("state-file", defaultStateFile(), "file recording the server started with --daemon")
+	database := flags.String("database", "", "database to restore into (default the database of the server)")
+	create := flags.Bool("create", false, "create the database before restoring into it")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "Usage: embedded-postgres restore [flags] <dump file, or - for stdin>")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	r := stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fail(stderr, fmt.Errorf("unable to open dump %s: %w", path, err))
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		r = file
+	}
+
+	err := withDaemon(*stateFile, stderr, func(server *embeddedpostgres.EmbeddedPostgres) error {
+		target := databaseOrDefault(*database, server)
+
+		if *create {
+			if err := server.CreateDatabase(target); err != nil {
+				return err
+			}
+		}
+
+		return server.Restore(context.Background(), target, r)
+	})
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+// withDaemon attaches to the server recorded by start --daemon for the duration of fn, detaching again so that it keeps
+// running once the command exits.
+func withDaemon(stateFile string, stderr io.Writer, fn func(server *embeddedpostgres.EmbeddedPostgres) error) error {
+	state, err := readState(stateFile)
+	if err != nil {
+		return err
+	}
+
+	server := state.database(stderr)
+	if err := server.Attach(); err != nil {
+		return fmt.Errorf("%w, remove %s if the server is no longer running", err, stateFile)
+	}
+
+	err = fn(server)
+
+	if detachErr := server.Detach(); err == nil {
+		err = detachErr
+	}
+
+	return err
+}
+
+func databaseOrDefault(database string, server *embeddedpostgres.EmbeddedPostgres) string {
+	if database != "" {
+		return database
+	}
+
+	return server.ConnectionInfo().Database
+}
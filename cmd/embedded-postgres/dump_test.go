@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runDump_UnknownFormat(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"dump", "--format", "tar"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), `unknown dump format "tar"`)
+}
+
+func Test_runDump_ErrorWithoutStateRemovesOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "dump.sql")
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 1, run([]string{"dump", "--state-file", filepath.Join(dir, "missing.json"), "--output", output}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "no server started with --daemon is recorded in")
+	assert.NoFileExists(t, output)
+}
+
+func Test_runRestore_RequiresDump(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"restore"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "Usage: embedded-postgres restore")
+}
+
+func Test_runRestore_MissingDump(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 1, run([]string{"restore", filepath.Join(t.TempDir(), "missing.dump")}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "unable to open dump")
+}
+
+func Test_runRestore_ErrorWithoutState(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 1, run([]string{"restore", "--state-file", filepath.Join(t.TempDir(), "missing.json"), "-"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "no server started with --daemon is recorded in")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// fetchFlags are the flags selecting the binaries to download.
+type fetchFlags struct {
+	version         string
+	operatingSystem string
+	architecture    string
+	cacheDir        string
+}
+
+func addFetchFlags(flags *flag.FlagSet) *fetchFlags {
+	fetch := &fetchFlags{}
+
+	flags.StringVar(&fetch.version, "version", "", "Postgres version, such as 15 or 15.4.0 (default 15)")
+	flags.StringVar(&fetch.operatingSystem, "os", "", "operating system of the binaries, such as linux, darwin or windows (default the current one)")
+	flags.StringVar(&fetch.architecture, "arch", "", "architecture of the binaries as published, such as amd64, arm64v8 or amd64-alpine (default the current one)")
+	flags.StringVar(&fetch.cacheDir, "cache-dir", "", "cache directory (default $HOME/.embedded-postgres-go)")
+
+	return fetch
+}
+
+func (f *fetchFlags) config() embeddedpostgres.Config {
+	config := embeddedpostgres.DefaultConfig()
+
+	if f.version != "" {
+		config = config.Version(embeddedpostgres.ParseVersion(f.version))
+	}
+
+	if f.cacheDir != "" {
+		config = config.CachePath(f.cacheDir)
+	}
+
+	if f.operatingSystem != "" || f.architecture != "" {
+		operatingSystem, architecture := f.operatingSystem, f.architecture
+		if operatingSystem == "" {
+			operatingSystem = runtime.GOOS
+		}
+
+		if architecture == "" {
+			architecture = runtime.GOARCH
+		}
+
+		config = config.Platform(operatingSystem, architecture)
+	}
+
+	return config
+}
+
+// runFetch downloads binaries into the cache without starting a server, printing the location of the cached archive.
+func runFetch(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fetch := addFetchFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	location, err := embeddedpostgres.NewDatabase(fetch.config()).Fetch()
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	_, _ = fmt.Fprintln(stdout, location)
+
+	return 0
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fetchFlags(t *testing.T) {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	fetch := addFetchFlags(flags)
+
+	require.NoError(t, flags.Parse([]string{"--version", "15.4.0", "--os", "linux", "--arch", "amd64", "--cache-dir", "/tmp/cache"}))
+
+	assert.Equal(t, &fetchFlags{
+		version:         "15.4.0",
+		operatingSystem: "linux",
+		architecture:    "amd64",
+		cacheDir:        "/tmp/cache",
+	}, fetch)
+}
+
+func Test_runFetch_InvalidFlag(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"fetch", "--platform", "linux"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "flag provided but not defined: -platform")
+}
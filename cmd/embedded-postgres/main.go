@@ -0,0 +1,75 @@
+// Command embedded-postgres runs an embedded Postgres server from the command line, giving shell scripts and projects
+// not written in Go the same zero install Postgres as the library.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: embedded-postgres <command> [flags]
+
+Commands:
+  run     start a server in the foreground until interrupted
+  start   start a server, in the background with --daemon
+  stop    stop the server started with start --daemon
+  control serve the control API of the server started with start --daemon --http, run by start itself
+  psql    run psql connected to the server started with start --daemon
+  dump    dump a database of the server started with start --daemon
+  restore restore a dump into the server started with start --daemon
+  matrix  run a command against a server of each of several versions
+  fetch   download binaries into the cache without starting a server
+  cache   list and remove cached binaries
+  doctor  check the environment for problems starting a server
+
+Run "embedded-postgres <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line, returning the exit code: 0 on success, 1 when the command failed and 2 when it was
+// used incorrectly.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "run", "start":
+		return runServer(args[0], args[1:], stdout, stderr)
+	case "stop":
+		return runStop(args[1:], stderr)
+	case "control":
+		return runControl(args[1:], stderr)
+	case "psql":
+		return runPsql(args[1:], stdout, stderr)
+	case "dump":
+		return runDump(args[1:], stdout, stderr)
+	case "restore":
+		return runRestore(args[1:], os.Stdin, stderr)
+	case "matrix":
+		return runMatrix(args[1:], stdout, stderr)
+	case "fetch":
+		return runFetch(args[1:], stdout, stderr)
+	case "doctor":
+		return runDoctor(args[1:], stdout, stderr)
+	case "cache":
+		return runCache(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		_, _ = fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// fail reports the error of a command and returns the exit code of a failed command.
+func fail(stderr io.Writer, err error) int {
+	_, _ = fmt.Fprintf(stderr, "embedded-postgres: %s\n", err)
+	return 1
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_run_Usage(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	assert.Equal(t, 2, run(nil, stdout, stderr))
+	assert.Equal(t, usage, stderr.String())
+
+	assert.Equal(t, 0, run([]string{"help"}, stdout, stderr))
+	assert.Equal(t, usage, stdout.String())
+}
+
+func Test_run_UnknownCommand(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"serve"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), `embedded-postgres: unknown command "serve"`)
+}
+
+func Test_run_InvalidFlag(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 2, run([]string{"run", "--port", "beer"}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), `invalid value "beer" for flag -port`)
+}
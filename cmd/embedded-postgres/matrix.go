@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// runMatrix runs a command against a server of each of the given versions, failing when the command failed for any of
+// them.
+func runMatrix(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	versions := flags.String("versions", "", "comma separated Postgres versions, such as 13,14,15")
+	parallel := flags.Bool("parallel", false, "run the versions at the same time on distinct ports, prefixing their output")
+	database := flags.String("database", "postgres", "database to create")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	command := flags.Args()
+	if *versions == "" || len(command) == 0 {
+		_, _ = fmt.Fprintln(stderr, "Usage: embedded-postgres matrix --versions <versions> [flags] -- <command> [args...]")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	labels := strings.Split(*versions, ",")
+	for i := range labels {
+		labels[i] = strings.TrimSpace(labels[i])
+	}
+
+	results := make([]error, len(labels))
+
+	if *parallel {
+		var wg sync.WaitGroup
+		var outputLock sync.Mutex
+
+		for i, label := range labels {
+			wg.Add(1)
+
+			go func(i int, label string) {
+				defer wg.Done()
+
+				prefix := fmt.Sprintf("[%s] ", label)
+				versionStdout, versionStderr := newPrefixWriter(stdout, prefix, &outputLock), newPrefixWriter(stderr, prefix, &outputLock)
+
+				results[i] = runMatrixVersion(ctx, label, *database, command, versionStdout, versionStderr)
+
+				_ = versionStdout.Flush()
+				_ = versionStderr.Flush()
+			}(i, label)
+		}
+
+		wg.Wait()
+	} else {
+		for i, label := range labels {
+			if ctx.Err() != nil {
+				results[i] = ctx.Err()
+				continue
+			}
+
+			_, _ = fmt.Fprintf(stderr, "embedded-postgres: running against Postgres %s\n", label)
+			results[i] = runMatrixVersion(ctx, label, *database, command, stdout, stderr)
+		}
+	}
+
+	exitCode := 0
+
+	for i, label := range labels {
+		if results[i] != nil {
+			_, _ = fmt.Fprintf(stderr, "embedded-postgres: Postgres %s failed: %s\n", label, results[i])
+			exitCode = 1
+
+			continue
+		}
+
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: Postgres %s passed\n", label)
+	}
+
+	return exitCode
+}
+
+// runMatrixVersion starts a server of the version on a free port and runs the command with the connection details of
+// the server in its environment, stopping the server once it exits.
+func runMatrixVersion(ctx context.Context, label, database string, command []string, stdout, stderr io.Writer) (err error) {
+	if label == "" {
+		return errors.New("empty version")
+	}
+
+	port, err := embeddedpostgres.FreePort()
+	if err != nil {
+		return fmt.Errorf("unable to find a free port: %w", err)
+	}
+
+	version := embeddedpostgres.ParseVersion(label)
+	server := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(version).
+		Port(port).
+		Database(database).
+		Logger(stderr))
+
+	if err := server.Start(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if stopErr := server.Stop(); err == nil {
+			err = stopErr
+		}
+	}()
+
+	process := exec.CommandContext(ctx, command[0], command[1:]...)
+	process.Env = append(os.Environ(), matrixEnv(version, server.ConnectionInfo())...)
+	process.Stdout = stdout
+	process.Stderr = stderr
+
+	return process.Run()
+}
+
+// matrixEnv are the environment variables describing the server to the command, using the variables of libpq which
+// most drivers honor, along with the DSN and version.
+func matrixEnv(version embeddedpostgres.PostgresVersion, info embeddedpostgres.ConnectionInfo) []string {
+	return []string{
+		"PGHOST=" + info.Host,
+		"PGPORT=" + strconv.FormatUint(uint64(info.Port), 10),
+		"PGUSER=" + info.Username,
+		"PGPASSWORD=" + info.Password,
+		"PGDATABASE=" + info.Database,
+		"DATABASE_URL=" + info.DSN,
+		"EMBEDDED_POSTGRES_VERSION=" + string(version),
+	}
+}
+
+// prefixWriter prefixes each line written to it, so that the output of versions run in parallel can be told apart.
+// Lines are written whole, holding a lock shared by the writers of all versions.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	lock    *sync.Mutex
+	pending []byte
+}
+
+func newPrefixWriter(w io.Writer, prefix string, lock *sync.Mutex) *prefixWriter {
+	return &prefixWriter{w: w, prefix: prefix, lock: lock}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = append(p.pending, b...)
+
+	for {
+		end := bytes.IndexByte(p.pending, '\n')
+		if end < 0 {
+			return len(b), nil
+		}
+
+		if _, err := p.w.Write(append([]byte(p.prefix), p.pending[:end+1]...)); err != nil {
+			return len(b), err
+		}
+
+		p.pending = p.pending[end+1:]
+	}
+}
+
+// Flush writes a final line which did not end with a newline.
+func (p *prefixWriter) Flush() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.pending) == 0 {
+		return nil
+	}
+
+	line := append(append([]byte(p.prefix), p.pending...), '\n')
+	p.pending = nil
+
+	_, err := p.w.Write(line)
+
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+)
+
+// runPsql runs the bundled psql connected to the server recorded by start --daemon, passing on the remaining arguments
+// and exiting with the exit code of psql.
+func runPsql(args []string, stdout, stderr io.Writer) int {
+	stateFile, psqlArgs, err := parseStateFileArg(args)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: %s\n", err)
+		return 2
+	}
+
+	state, err := readState(stateFile)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	psql := state.database(stderr).ClientCommand(context.Background(), "psql", psqlArgs...)
+	psql.Stdin = os.Stdin
+	psql.Stdout = stdout
+	psql.Stderr = stderr
+
+	// an interrupt cancels the running query in psql, which shares the terminal, rather than ending the session
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	if err := psql.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+
+		return fail(stderr, fmt.Errorf("unable to run psql: %w", err))
+	}
+
+	return 0
+}
+
+// parseStateFileArg takes a leading --state-file flag and an optional -- separator off the arguments of a command
+// passing the rest on to a client binary, whose own flags the flag package would reject.
+func parseStateFileArg(args []string) (string, []string, error) {
+	stateFile := defaultStateFile()
+
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--":
+			return stateFile, args[1:], nil
+		case args[0] == "--state-file" || args[0] == "-state-file":
+			if len(args) < 2 {
+				return "", nil, errors.New("--state-file requires a path")
+			}
+
+			stateFile, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--state-file="), strings.HasPrefix(args[0], "-state-file="):
+			stateFile, args = args[0][strings.Index(args[0], "=")+1:], args[1:]
+		default:
+			return stateFile, args, nil
+		}
+	}
+
+	return stateFile, args, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseStateFileArg(t *testing.T) {
+	tests := []struct {
+		args      []string
+		stateFile string
+		rest      []string
+	}{
+		{nil, defaultStateFile(), nil},
+		{[]string{"-c", "SELECT 1"}, defaultStateFile(), []string{"-c", "SELECT 1"}},
+		{[]string{"--state-file", "state.json", "-c", "SELECT 1"}, "state.json", []string{"-c", "SELECT 1"}},
+		{[]string{"--state-file=state.json", "--", "--state-file"}, "state.json", []string{"--state-file"}},
+		{[]string{"--", "-l"}, defaultStateFile(), []string{"-l"}},
+	}
+
+	for _, test := range tests {
+		stateFile, rest, err := parseStateFileArg(test.args)
+
+		require.NoError(t, err)
+		assert.Equal(t, test.stateFile, stateFile)
+		assert.Equal(t, test.rest, rest)
+	}
+
+	_, _, err := parseStateFileArg([]string{"--state-file"})
+	assert.EqualError(t, err, "--state-file requires a path")
+}
+
+func Test_runPsql_ErrorWithoutState(t *testing.T) {
+	stderr := &bytes.Buffer{}
+
+	assert.Equal(t, 1, run([]string{"psql", "--state-file", filepath.Join(t.TempDir(), "missing.json")}, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "no server started with --daemon is recorded in")
+}
+
+func Test_runPsql_PassesArgumentsAndExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of psql")
+	}
+
+	binaries := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(binaries, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(binaries, "bin", "psql"),
+		[]byte("#!/bin/sh\necho \"$PGDATABASE $PGPASSWORD $*\"\nexit 3\n"), 0755))
+
+	stateFile := filepath.Join(t.TempDir(), "daemon.json")
+	require.NoError(t, writeState(stateFile, daemonState{
+		ConnectionInfo: embeddedpostgres.ConnectionInfo{Port: 9999, Username: "beer", Password: "wine", Database: "gin"},
+		BinariesDir:    binaries,
+	}))
+
+	stdout := &bytes.Buffer{}
+
+	assert.Equal(t, 3, run([]string{"psql", "--state-file", stateFile, "-c", "SELECT 1"}, stdout, &bytes.Buffer{}))
+	assert.Equal(t, "gin wine -h localhost -p 9999 -U beer -c SELECT 1\n", stdout.String())
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// serverFlags are the flags configuring the server of a command.
+type serverFlags struct {
+	version  string
+	port     uint
+	dataDir  string
+	username string
+	password string
+	database string
+}
+
+func addServerFlags(flags *flag.FlagSet) *serverFlags {
+	server := &serverFlags{}
+
+	flags.StringVar(&server.version, "version", "", "Postgres version, such as 15 or 14.8.0 (default 15)")
+	flags.UintVar(&server.port, "port", 5432, "port to listen on, or 0 for a free port")
+	flags.StringVar(&server.dataDir, "data-dir", "", "data directory, which is kept between runs (default within the runtime directory)")
+	flags.StringVar(&server.username, "username", "postgres", "superuser name")
+	flags.StringVar(&server.password, "password", "postgres", "superuser password")
+	flags.StringVar(&server.database, "database", "postgres", "database to create")
+
+	return server
+}
+
+func (s *serverFlags) config() (embeddedpostgres.Config, error) {
+	port := uint32(s.port)
+	if port == 0 {
+		free, err := embeddedpostgres.FreePort()
+		if err != nil {
+			return embeddedpostgres.Config{}, fmt.Errorf("unable to find a free port: %w", err)
+		}
+
+		port = free
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Port(port).
+		Username(s.username).
+		Password(s.password).
+		Database(s.database)
+
+	if s.version != "" {
+		config = config.Version(embeddedpostgres.ParseVersion(s.version))
+	}
+
+	if s.dataDir != "" {
+		config = config.DataPath(s.dataDir)
+	}
+
+	return config, nil
+}
+
+// outputFlags are the flags publishing the connection details of a started server to other programs.
+type outputFlags struct {
+	json     bool
+	portFile string
+}
+
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+	output := &outputFlags{}
+
+	flags.BoolVar(&output.json, "json", false, "print the port, DSN and data directory as JSON to stdout once ready")
+	flags.StringVar(&output.portFile, "port-file", "", "write the port to this file once ready, removing it on exit")
+
+	return output
+}
+
+// publish writes the connection details of the started server as configured by the flags, along with the endpoint of
+// the control API when it is served.
+func (o *outputFlags) publish(database *embeddedpostgres.EmbeddedPostgres, endpoint controlEndpoint, stdout io.Writer) error {
+	info := database.ConnectionInfo()
+
+	if o.json {
+		published := struct {
+			embeddedpostgres.ConnectionInfo
+			controlEndpoint
+		}{info, endpoint}
+
+		if err := json.NewEncoder(stdout).Encode(published); err != nil {
+			return fmt.Errorf("unable to write connection details: %w", err)
+		}
+	}
+
+	if o.portFile != "" {
+		if err := writePortFile(o.portFile, info.Port); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePortFile writes the port to the file through a rename, so that programs polling for the file never read a
+// partially written port.
+func writePortFile(path string, port uint32) error {
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, []byte(fmt.Sprintf("%d\n", port)), 0600); err != nil {
+		return fmt.Errorf("unable to write port file %s: %w", path, err)
+	}
+
+	if err := os.Rename(temporary, path); err != nil {
+		_ = os.Remove(temporary)
+		return fmt.Errorf("unable to write port file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// runServer starts a server and keeps it running until the command is interrupted or terminated, or the server exits,
+// unless it is started as a daemon.
+func runServer(name string, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := addServerFlags(flags)
+	output := addOutputFlags(flags)
+	daemon := flags.Bool("daemon", false, "leave the server running in the background, to be stopped with the stop command")
+	stateFile := flags.String("state-file", defaultStateFile(), "file recording the server started with --daemon")
+	controlAddress := flags.String("http", "", "serve the HTTP control API on this localhost address, such as localhost:8432")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *daemon {
+		if _, err := os.Stat(*stateFile); err == nil {
+			return fail(stderr, fmt.Errorf("a server started with --daemon is recorded in %s, stop it first", *stateFile))
+		}
+	}
+
+	config, err := server.config()
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	// the control API is listened on before starting the server, so that an unusable address fails the command early
+	var (
+		endpoint        controlEndpoint
+		controlListener net.Listener
+	)
+
+	if *controlAddress != "" {
+		if controlListener, err = listenControl(*controlAddress); err != nil {
+			return fail(stderr, err)
+		}
+
+		defer func() {
+			_ = controlListener.Close()
+		}()
+
+		token, err := newControlToken()
+		if err != nil {
+			return fail(stderr, err)
+		}
+
+		endpoint = controlEndpoint{URL: "http://" + controlListener.Addr().String(), Token: token}
+	}
+
+	// the output of initdb and pg_ctl goes to stderr, keeping stdout for output meant for other programs
+	database := embeddedpostgres.NewDatabase(config.Logger(stderr))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := database.Start(); err != nil {
+		return fail(stderr, err)
+	}
+
+	if *daemon {
+		if controlListener != nil {
+			// the control API of a daemon is served by a process of its own, listening on the same address
+			_ = controlListener.Close()
+		}
+
+		return startDaemon(database, *stateFile, endpoint, output, stdout, stderr)
+	}
+
+	var stops chan chan error
+
+	if controlListener != nil {
+		control := newControlAPI(database, endpoint.Token)
+		stops = control.stops
+
+		defer startControlServer(control, controlListener)()
+
+		_, _ = fmt.Fprintf(stderr, "embedded-postgres: control API at %s, authenticate with the bearer token %s\n", endpoint.URL, endpoint.Token)
+	}
+
+	if output.portFile != "" {
+		defer func() {
+			_ = os.Remove(output.portFile)
+		}()
+	}
+
+	if err := output.publish(database, endpoint, stdout); err != nil {
+		_ = database.Stop()
+		return fail(stderr, err)
+	}
+
+	_, _ = fmt.Fprintf(stderr, "embedded-postgres: ready at %s, interrupt to stop\n", database.GetConnectionURL())
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- database.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+	case stopped := <-stops:
+		err := database.Stop()
+		stopped <- err
+
+		if err != nil {
+			return fail(stderr, err)
+		}
+
+		return 0
+	case err := <-exited:
+		_ = database.Stop()
+		return fail(stderr, err)
+	}
+
+	if err := database.Stop(); err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_serverFlags(t *testing.T) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	server := addServerFlags(flags)
+
+	require.NoError(t, flags.Parse([]string{"--version", "14", "--port", "0", "--data-dir", "/tmp/data", "--database", "app"}))
+
+	assert.Equal(t, &serverFlags{
+		version:  "14",
+		dataDir:  "/tmp/data",
+		username: "postgres",
+		password: "postgres",
+		database: "app",
+	}, server)
+
+	config, err := server.config()
+	require.NoError(t, err)
+	assert.NotContains(t, config.GetConnectionURL(), "localhost:0/")
+	assert.Contains(t, config.GetConnectionURL(), "/app")
+}
+
+func Test_outputFlags_publish(t *testing.T) {
+	portFile := filepath.Join(t.TempDir(), "port")
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	output := addOutputFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--json", "--port-file", portFile}))
+
+	database := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().Port(9999))
+	stdout := &bytes.Buffer{}
+
+	require.NoError(t, output.publish(database, controlEndpoint{}, stdout))
+
+	var info embeddedpostgres.ConnectionInfo
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &info))
+	assert.Equal(t, database.ConnectionInfo(), info)
+	assert.NotContains(t, stdout.String(), "control_url")
+
+	port, err := os.ReadFile(portFile)
+	require.NoError(t, err)
+	assert.Equal(t, "9999\n", string(port))
+}
+
+func Test_outputFlags_publishControlEndpoint(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	endpoint := controlEndpoint{URL: "http://127.0.0.1:8432", Token: "token"}
+
+	require.NoError(t, (&outputFlags{json: true}).publish(embeddedpostgres.NewDatabase(), endpoint, stdout))
+
+	var published controlEndpoint
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &published))
+	assert.Equal(t, endpoint, published)
+	assert.Contains(t, stdout.String(), `"port":5432`)
+}
+
+func Test_outputFlags_publishNothingByDefault(t *testing.T) {
+	stdout := &bytes.Buffer{}
+
+	require.NoError(t, (&outputFlags{}).publish(embeddedpostgres.NewDatabase(), controlEndpoint{}, stdout))
+	assert.Empty(t, stdout.String())
+}
+
+func Test_writePortFile_Error(t *testing.T) {
+	err := writePortFile(filepath.Join(t.TempDir(), "missing", "port"), 5432)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to write port file")
+}
@@ -1,26 +1,78 @@
 package embeddedpostgres
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Config maintains the runtime configuration for the Postgres process to be created.
 type Config struct {
-	version             PostgresVersion
-	port                uint32
-	database            string
-	username            string
-	password            string
-	runtimePath         string
-	dataPath            string
-	binariesPath        string
-	locale              string
-	binaryRepositoryURL string
-	startTimeout        time.Duration
-	logger              io.Writer
+	version                    PostgresVersion
+	port                       uint32
+	database                   string
+	username                   string
+	password                   string
+	runtimePath                string
+	dataPath                   string
+	binariesPath               string
+	locale                     string
+	binaryRepositoryURL        string
+	cachePath                  string
+	startTimeout               time.Duration
+	logger                     io.Writer
+	versionStrategy            VersionStrategy
+	libc                       Libc
+	platformOS                 string
+	platformArch               string
+	initScripts                []initScript
+	afterStart                 []afterStartStep
+	migrator                   Migrator
+	restoreFrom                string
+	extraDatabases             []string
+	roles                      []Role
+	extensions                 []string
+	databaseOptions            DatabaseOptions
+	stopTimeout                time.Duration
+	stopMode                   StopMode
+	onCrash                    func(err error)
+	maxRestarts                int
+	restartBackoff             time.Duration
+	terminateConnectionsOnStop bool
+	connectionLeakCheck        LeakCheck
+	cacheInitdb                bool
+	healthCheckInterval        time.Duration
+	healthCheckMaxInterval     time.Duration
+	startParameters            map[string]string
+	dataInMemory               bool
+	ssl                        bool
+	authMethod                 AuthMethod
+	requireClientCertificates  bool
+	binaryRepositoryTLS        *tls.Config
+	binaryRepositoryHeaders    map[string]string
+	generateUsername           bool
+	generatePassword           bool
+	sharedPreloadLibraries     []string
+	walArchiving               bool
+	readOnly                   bool
+	logAllStatements           bool
+	standbyFrom                string
+	lifecycleHooks             []LifecycleHook
+	serverLogger               io.Writer
+	cmdHook                    func(*exec.Cmd)
+	env                        map[string]string
+	dataChecksums              bool
+	initdbNoSync               bool
 }
 
 // DefaultConfig provides a default set of configuration to be used "as is" or modified using the provided builders.
@@ -31,17 +83,29 @@ type Config struct {
 // Username:     postgres
 // Password:     postgres
 // StartTimeout: 15 Seconds
+// StopTimeout:  15 Seconds
+// HealthCheck:  every 10 milliseconds, backing off to 250 milliseconds
+// StopMode:     fast
+// AuthMethod:   password
+//
+// The Version, CachePath and BinaryRepositoryURL defaults can be overridden with the EMBEDDED_POSTGRES_VERSION,
+// EMBEDDED_POSTGRES_CACHE_PATH and EMBEDDED_POSTGRES_BINARY_REPO_URL environment variables.
 func DefaultConfig() Config {
-	return Config{
-		version:             V15,
-		port:                5432,
-		database:            "postgres",
-		username:            "postgres",
-		password:            "postgres",
-		startTimeout:        15 * time.Second,
-		logger:              os.Stdout,
-		binaryRepositoryURL: "https://repo1.maven.org/maven2",
-	}
+	return applyEnvironmentOverrides(Config{
+		version:                V15,
+		port:                   5432,
+		database:               "postgres",
+		username:               "postgres",
+		password:               "postgres",
+		startTimeout:           15 * time.Second,
+		stopTimeout:            15 * time.Second,
+		healthCheckInterval:    10 * time.Millisecond,
+		healthCheckMaxInterval: 250 * time.Millisecond,
+		stopMode:               StopModeFast,
+		authMethod:             AuthMethodPassword,
+		logger:                 os.Stdout,
+		binaryRepositoryURL:    "https://repo1.maven.org/maven2",
+	})
 }
 
 // Version will set the Postgres binary version.
@@ -62,6 +126,35 @@ func (c Config) Database(database string) Config {
 	return c
 }
 
+// Databases sets the names of several databases that will be created. The first database is the one used to connect,
+// as if set with Database, and the remaining databases are created alongside it.
+func (c Config) Databases(database string, others ...string) Config {
+	c.database = database
+	c.extraDatabases = append([]string(nil), others...)
+	return c
+}
+
+// Extensions adds extensions, such as uuid-ossp or pgcrypto, which are created in the database if they do not already
+// exist every time the server has started. Start fails if the Postgres binaries do not include an extension.
+func (c Config) Extensions(extensions ...string) Config {
+	c.extensions = append(c.extensions[:len(c.extensions):len(c.extensions)], extensions...)
+	return c
+}
+
+// Roles adds login roles, along with the databases they own and their grants, which are created when the data directory
+// is initialized.
+func (c Config) Roles(roles ...Role) Config {
+	c.roles = append(c.roles[:len(c.roles):len(c.roles)], roles...)
+	return c
+}
+
+// DatabaseOptions sets the options used when creating the configured databases, so that tests can mirror the settings
+// of production databases. The postgres database already exists and is not affected.
+func (c Config) DatabaseOptions(options DatabaseOptions) Config {
+	c.databaseOptions = options
+	return c
+}
+
 // Username sets the username that will be used to connect.
 func (c Config) Username(username string) Config {
 	c.username = username
@@ -74,6 +167,21 @@ func (c Config) Password(password string) Config {
 	return c
 }
 
+// GeneratePassword replaces the password with a random one generated on Start, which is available from the Password
+// and GetConnectionURL methods of the started instance. This keeps credentials out of test code, at the cost of data
+// directories which can only be reused by the same instance, and disables CacheInitdb.
+func (c Config) GeneratePassword() Config {
+	c.generatePassword = true
+	return c
+}
+
+// GenerateUsername replaces the username with a random one generated on Start, which is available from the Username
+// and GetConnectionURL methods of the started instance. Like GeneratePassword, it disables CacheInitdb.
+func (c Config) GenerateUsername() Config {
+	c.generateUsername = true
+	return c
+}
+
 // RuntimePath sets the path that will be used for the extracted Postgres runtime directory.
 // If Postgres data directory is not set with DataPath(), this directory is also used as data directory.
 func (c Config) RuntimePath(path string) Config {
@@ -88,6 +196,14 @@ func (c Config) DataPath(path string) Config {
 	return c
 }
 
+// DataInMemory sets whether the data directory is placed on a memory backed file system, which speeds up I/O heavy test
+// suites on machines with slow disks. This is currently supported on Linux using /dev/shm; elsewhere, when /dev/shm has
+// less than 512MB free, or when a DataPath is configured, the data directory stays on disk. An in memory data directory is removed by Stop.
+func (c Config) DataInMemory(inMemory bool) Config {
+	c.dataInMemory = inMemory
+	return c
+}
+
 // BinariesPath sets the path of the pre-downloaded postgres binaries.
 // If this option is left unset, the binaries will be downloaded.
 func (c Config) BinariesPath(path string) Config {
@@ -101,28 +217,570 @@ func (c Config) Locale(locale string) Config {
 	return c
 }
 
+// DataChecksums sets whether initdb enables data page checksums, so that the server detects corrupted pages like
+// production clusters with checksums enabled. It only applies when the data directory is initialized.
+func (c Config) DataChecksums(enabled bool) Config {
+	c.dataChecksums = enabled
+	return c
+}
+
+// InitdbNoSync sets whether initdb skips flushing the new data directory to disk, which saves up to several seconds per
+// initialization on slow disks. A crash of the machine shortly after initialization may then corrupt the data directory.
+func (c Config) InitdbNoSync(enabled bool) Config {
+	c.initdbNoSync = enabled
+	return c
+}
+
+// CacheInitdb sets whether the data directory created by initdb is cached, per version, locale, username and password,
+// within the cache directory. New data directories are then created by copying the cached one, skipping the initdb run
+// which dominates the start time of test suites booting many instances.
+func (c Config) CacheInitdb(cache bool) Config {
+	c.cacheInitdb = cache
+	return c
+}
+
+// CachePath sets the directory where downloaded Postgres binary archives are cached.
+// If this option is left unset, $USER_HOME/.embedded-postgres-go is used.
+func (c Config) CachePath(path string) Config {
+	c.cachePath = path
+	return c
+}
+
 // StartTimeout sets the max timeout that will be used when starting the Postgres process and creating the initial database.
 func (c Config) StartTimeout(timeout time.Duration) Config {
 	c.startTimeout = timeout
 	return c
 }
 
-// Logger sets the logger for postgres output
+// minHealthCheckInterval keeps the health checks from spinning when configured with a zero or negative interval.
+const minHealthCheckInterval = 10 * time.Millisecond
+
+// HealthCheckInterval sets how long to wait between the checks that the database accepts connections during Start.
+// The interval doubles after each failed check, up to maxInterval, to limit CPU use and connection errors in the logs
+// while the server starts. Intervals below 10 milliseconds are raised to 10 milliseconds, and a maxInterval below the
+// interval to the interval.
+func (c Config) HealthCheckInterval(interval, maxInterval time.Duration) Config {
+	if interval < minHealthCheckInterval {
+		interval = minHealthCheckInterval
+	}
+
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+
+	c.healthCheckInterval = interval
+	c.healthCheckMaxInterval = maxInterval
+	return c
+}
+
+// StartParameters adds server parameters, such as max_connections or log_statement, which are applied every time the
+// server starts. Parameters set with ALTER SYSTEM take precedence over them.
+func (c Config) StartParameters(parameters map[string]string) Config {
+	merged := make(map[string]string, len(c.startParameters)+len(parameters))
+	for name, value := range c.startParameters {
+		merged[name] = value
+	}
+
+	for name, value := range parameters {
+		merged[name] = value
+	}
+
+	c.startParameters = merged
+	return c
+}
+
+// SharedPreloadLibraries adds libraries, such as pg_stat_statements, which are loaded when the server starts. They are
+// combined with any libraries set with StartParameters, and must be included in the binaries.
+func (c Config) SharedPreloadLibraries(libraries ...string) Config {
+	c.sharedPreloadLibraries = append(c.sharedPreloadLibraries[:len(c.sharedPreloadLibraries):len(c.sharedPreloadLibraries)], libraries...)
+	return c
+}
+
+// AuditLogging enables audit logging with pgaudit for the statement classes, such as "write" or "ddl", or for all
+// statements when none are given. pgaudit is not part of the default binaries, so binaries which include it must be
+// configured with BinariesPath or BinaryRepositoryURL.
+func (c Config) AuditLogging(classes ...string) Config {
+	log := "all"
+	if len(classes) > 0 {
+		log = strings.Join(classes, ",")
+	}
+
+	return c.SharedPreloadLibraries("pgaudit").
+		Extensions("pgaudit").
+		StartParameters(map[string]string{
+			"pgaudit.log":          log,
+			"pgaudit.log_relation": "on",
+		})
+}
+
+// StatStatements loads and creates the pg_stat_statements extension, which tracks the execution statistics of every
+// statement, so that performance tests can assert on query counts and timings with TopStatements.
+func (c Config) StatStatements() Config {
+	return c.SharedPreloadLibraries("pg_stat_statements").Extensions("pg_stat_statements")
+}
+
+// AutoExplain loads auto_explain, which writes the plans of statements taking at least minDuration to the server log at
+// ServerLogPath, so that slow queries in integration tests can be diagnosed. A minDuration of 0 logs the plan of every
+// statement, and durations are rounded up to whole milliseconds like those of StatementTimeout.
+func (c Config) AutoExplain(minDuration time.Duration) Config {
+	return c.SharedPreloadLibraries("auto_explain").
+		StartParameters(map[string]string{
+			"auto_explain.log_min_duration": timeoutParameter(minDuration),
+		})
+}
+
+// LogAllStatements logs every statement together with its duration to the server log at ServerLogPath, which can also
+// be forwarded with ServerLogger or LogToTest, to see the SQL an application or ORM actually sent. Parameters set with
+// StartParameters take precedence.
+func (c Config) LogAllStatements(enabled bool) Config {
+	c.logAllStatements = enabled
+	return c
+}
+
+// WalLevel sets the wal_level server parameter, for example WalLevelLogical to test change data capture pipelines and
+// outbox consumers with publications and logical replication slots.
+func (c Config) WalLevel(level WalLevel) Config {
+	return c.StartParameters(map[string]string{"wal_level": string(level)})
+}
+
+// WalArchiving archives every completed WAL file to the directory returned by WalArchivePath, so that point in time
+// recovery can be tested with RecoverToTime.
+func (c Config) WalArchiving(enabled bool) Config {
+	c.walArchiving = enabled
+	return c
+}
+
+// ReadOnly makes transactions read only by default once the databases have been created and populated, so that
+// applications can be tested against a read only database. Sessions can still opt out with
+// SET default_transaction_read_only = off, use StandbyFrom for a database which cannot be written to at all.
+func (c Config) ReadOnly(readOnly bool) Config {
+	c.readOnly = readOnly
+	return c
+}
+
+// StandbyFrom starts the server as a read only hot standby of the base backup in backupDir, as written in plain format
+// by BaseBackup or pg_basebackup, when the data directory has not been initialized yet. Databases, dumps, init scripts
+// and roles are not applied, the standby serves the data of the backup instead, and so must be configured with the
+// credentials and database of the server the backup was taken from.
+func (c Config) StandbyFrom(backupDir string) Config {
+	c.standbyFrom = backupDir
+	return c
+}
+
+// TuneForTests applies the usual speedups for throwaway databases: fsync, synchronous_commit, full_page_writes and
+// autovacuum are turned off, shared_buffers is reduced and initdb does not flush the data directory to disk. This is
+// unsafe for data which must survive a crash of the server or the machine, but makes tests considerably faster.
+func (c Config) TuneForTests() Config {
+	return c.InitdbNoSync(true).StartParameters(testParameters)
+}
+
+// DeterministicOutput sets lc_messages to C, TimeZone to UTC and DateStyle to ISO, and turns autovacuum off, so that
+// assertions on error messages, timestamps and statistics behave the same on every developer machine and in CI.
+// Sessions can still override the parameters.
+func (c Config) DeterministicOutput() Config {
+	return c.StartParameters(deterministicParameters)
+}
+
+// StatementTimeout sets the statement_timeout server parameter, so that every session aborts statements running longer
+// than timeout and a hanging query fails its test quickly rather than stalling the suite until the go test timeout. It
+// also applies to the statements run while starting the server, such as restoring a dump. A timeout of 0 disables it,
+// and timeouts are rounded up to whole milliseconds, so that a short timeout does not disable it.
+func (c Config) StatementTimeout(timeout time.Duration) Config {
+	return c.StartParameters(map[string]string{"statement_timeout": timeoutParameter(timeout)})
+}
+
+// LockTimeout sets the lock_timeout server parameter, so that every session aborts statements waiting longer than
+// timeout for a lock, making deadlocked tests fail quickly. A timeout of 0 disables it, and timeouts are rounded up to
+// whole milliseconds like those of StatementTimeout.
+func (c Config) LockTimeout(timeout time.Duration) Config {
+	return c.StartParameters(map[string]string{"lock_timeout": timeoutParameter(timeout)})
+}
+
+// timeoutParameter formats a timeout in the milliseconds of the server, rounding up so that positive timeouts below a
+// millisecond do not become 0, which disables the timeout. Negative timeouts, which the server rejects, disable it.
+func timeoutParameter(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0ms"
+	}
+
+	return fmt.Sprintf("%dms", (timeout+time.Millisecond-1)/time.Millisecond)
+}
+
+// MaxConnections sets the max_connections server parameter, for example to raise the default of 100 for heavily
+// parallel test suites.
+func (c Config) MaxConnections(n int) Config {
+	return c.StartParameters(map[string]string{"max_connections": strconv.Itoa(n)})
+}
+
+// SharedBuffers sets the shared_buffers server parameter to a size in the units of postgresql.conf, such as "16MB" to
+// shrink the memory used in constrained CI containers.
+func (c Config) SharedBuffers(size string) Config {
+	return c.StartParameters(map[string]string{"shared_buffers": size})
+}
+
+// WorkMem sets the work_mem server parameter, the memory used by each sort or hash operation before spilling to disk, to
+// a size in the units of postgresql.conf such as "64MB".
+func (c Config) WorkMem(size string) Config {
+	return c.StartParameters(map[string]string{"work_mem": size})
+}
+
+// RequireClientCertificates sets whether SSL connections must present a client certificate for the connecting user,
+// signed by the certificate authority of the server, in addition to the configured authentication. Certificates are
+// issued with ClientCertificate once the server has started. This enables SSL and requires Postgres 12 or later.
+// TCP connections without SSL are rejected, the library itself connecting with a certificate issued to the superuser
+// on start, which the DSN of ConnectionInfo and URL refer to. Connections through the Unix socket are not affected.
+func (c Config) RequireClientCertificates(required bool) Config {
+	c.requireClientCertificates = required
+	return c
+}
+
+// AuthMethod sets the method used to authenticate connections. With AuthMethodScramSHA256, passwords are also stored
+// as SCRAM verifiers, matching the defaults of production servers.
+func (c Config) AuthMethod(method AuthMethod) Config {
+	c.authMethod = method
+	return c
+}
+
+// Trust sets whether local connections are accepted without a password, skipping the password file used by initdb.
+// Clients may still send a password, which is ignored.
+func (c Config) Trust(trust bool) Config {
+	if trust {
+		c.authMethod = AuthMethodTrust
+	} else if c.authMethod == AuthMethodTrust {
+		c.authMethod = AuthMethodPassword
+	}
+
+	return c
+}
+
+// SSL sets whether the server accepts SSL connections. A certificate authority and a server certificate for localhost
+// signed by it are generated on every start, so that clients can connect with sslmode=verify-full using the
+// certificate at SSLRootCertPath.
+func (c Config) SSL(enabled bool) Config {
+	c.ssl = enabled
+	return c
+}
+
+// StopTimeout sets the max timeout that Stop waits for each shutdown mode before escalating to the next one.
+func (c Config) StopTimeout(timeout time.Duration) Config {
+	c.stopTimeout = timeout
+	return c
+}
+
+// TerminateConnectionsOnStop sets whether Stop first terminates the remaining client sessions, so that connections
+// leaked by tests cannot delay the shutdown. Each terminated session is written to the logger.
+func (c Config) TerminateConnectionsOnStop(terminate bool) Config {
+	c.terminateConnectionsOnStop = terminate
+	return c
+}
+
+// ConnectionLeakCheck sets whether Stop first looks for client sessions which are still connected, such as those of a
+// *sql.DB a test did not close, and reports them to the logger or fails once the server has stopped.
+func (c Config) ConnectionLeakCheck(check LeakCheck) Config {
+	c.connectionLeakCheck = check
+	return c
+}
+
+// StopMode sets the shutdown mode that Stop first tries. If the server has not stopped within the stop timeout, Stop
+// escalates to the next, less graceful, mode and finally kills the server process.
+func (c Config) StopMode(mode StopMode) Config {
+	c.stopMode = mode
+	return c
+}
+
+// OnLifecycleEvent adds hooks which are called with each lifecycle event, such as the download of the binaries
+// finishing or the server becoming ready, together with the time taken, for example to report progress or record CI
+// telemetry.
+func (c Config) OnLifecycleEvent(hooks ...LifecycleHook) Config {
+	c.lifecycleHooks = append(c.lifecycleHooks[:len(c.lifecycleHooks):len(c.lifecycleHooks)], hooks...)
+	return c
+}
+
+// CmdHook sets a function which is called with the initdb and pg_ctl start commands before they are run, for example to
+// set SysProcAttr, a nice level or additional environment variables. The server process started by pg_ctl inherits the
+// environment, priority and process group settings of pg_ctl.
+func (c Config) CmdHook(hook func(*exec.Cmd)) Config {
+	c.cmdHook = hook
+	return c
+}
+
+// Env sets environment variables of the initdb and server processes, such as LC_ALL, TZDIR or LD_LIBRARY_PATH, which
+// otherwise inherit the environment of this process unchanged. Variables set by earlier calls are kept unless
+// overridden.
+func (c Config) Env(env map[string]string) Config {
+	merged := make(map[string]string, len(c.env)+len(env))
+	for name, value := range c.env {
+		merged[name] = value
+	}
+
+	for name, value := range env {
+		merged[name] = value
+	}
+
+	c.env = merged
+	return c
+}
+
+// OnCrash sets a callback which is called from a background goroutine with an error describing the Postgres process
+// exiting unexpectedly while started, for example to fail a test or log the crash. The callback is also called for
+// exits which are followed by a restart configured with RestartOnCrash.
+func (c Config) OnCrash(callback func(err error)) Config {
+	c.onCrash = callback
+	return c
+}
+
+// RestartOnCrash restarts the Postgres process, up to maxRestarts times, when it exits unexpectedly while started, which
+// is useful for long-lived local development servers. The first restart waits for backoff, which doubles before each
+// following restart up to a minute. Once no restarts remain the exit is reported by Err and Stop.
+func (c Config) RestartOnCrash(maxRestarts int, backoff time.Duration) Config {
+	c.maxRestarts = maxRestarts
+	c.restartBackoff = backoff
+	return c
+}
+
+// Logger sets the logger for the output of initdb and pg_ctl, the server log is written to ServerLogPath instead
 func (c Config) Logger(logger io.Writer) Config {
 	c.logger = logger
 	return c
 }
 
+// ServerLogger forwards the server log to the logger as it is written while the server is running. The logger is
+// written to from a background goroutine.
+func (c Config) ServerLogger(logger io.Writer) Config {
+	c.serverLogger = logger
+	return c
+}
+
+// LogToTest forwards the output of initdb and pg_ctl and the server log line by line to t.Logf, prefixed with
+// "postgres: ", so that they appear with the output of the test, which go test only shows for failing tests or with -v.
+// The server must be stopped before the test completes, for example in a t.Cleanup function.
+func (c Config) LogToTest(t TestLogger) Config {
+	logger := NewTestLogWriter(t, "postgres: ")
+	return c.Logger(logger).ServerLogger(logger)
+}
+
 // BinaryRepositoryURL set BinaryRepositoryURL to fetch PG Binary in case of Maven proxy
 func (c Config) BinaryRepositoryURL(binaryRepositoryURL string) Config {
 	c.binaryRepositoryURL = binaryRepositoryURL
 	return c
 }
 
+// BinaryRepositoryTLSConfig sets the TLS configuration used to download binaries from the binary repository, for
+// example to trust a corporate proxy which re-signs TLS connections.
+func (c Config) BinaryRepositoryTLSConfig(tlsConfig *tls.Config) Config {
+	c.binaryRepositoryTLS = tlsConfig
+	return c
+}
+
+// BinaryRepositoryRootCAs sets the certificate authorities trusted when downloading binaries from the binary
+// repository, in place of those of the system.
+func (c Config) BinaryRepositoryRootCAs(rootCAs *x509.CertPool) Config {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.binaryRepositoryTLS != nil {
+		tlsConfig = c.binaryRepositoryTLS.Clone()
+	}
+
+	tlsConfig.RootCAs = rootCAs
+	c.binaryRepositoryTLS = tlsConfig
+
+	return c
+}
+
+// BinaryRepositoryHeaders adds headers sent with every request to the binary repository, such as the API key header
+// of a private Artifactory or Nexus instance.
+func (c Config) BinaryRepositoryHeaders(headers map[string]string) Config {
+	merged := make(map[string]string, len(c.binaryRepositoryHeaders)+len(headers))
+	for name, value := range c.binaryRepositoryHeaders {
+		merged[name] = value
+	}
+
+	for name, value := range headers {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+
+	c.binaryRepositoryHeaders = merged
+	return c
+}
+
+// BinaryRepositoryBasicAuth authenticates requests to the binary repository with a username and password.
+func (c Config) BinaryRepositoryBasicAuth(username, password string) Config {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+
+	return c.BinaryRepositoryHeaders(map[string]string{"Authorization": "Basic " + credentials})
+}
+
+// BinaryRepositoryBearerToken authenticates requests to the binary repository with a bearer token.
+func (c Config) BinaryRepositoryBearerToken(token string) Config {
+	return c.BinaryRepositoryHeaders(map[string]string{"Authorization": "Bearer " + token})
+}
+
+// VersionStrategy overrides the strategy used to determine the operating system, architecture and version of the
+// Postgres binaries to fetch, for example to download linux binaries while cross building or to pin amd64 binaries on
+// arm hosts. When left unset the binaries matching the host platform and the configured Version are used.
+func (c Config) VersionStrategy(versionStrategy VersionStrategy) Config {
+	c.versionStrategy = versionStrategy
+	return c
+}
+
+// Libc sets the C library variant of the Linux binaries to download.
+// If this option is left unset, the C library of the host is detected at runtime.
+func (c Config) Libc(libc Libc) Config {
+	c.libc = libc
+	return c
+}
+
+// Platform sets the operating system and architecture of the binaries to download, as named by the published binaries,
+// such as "linux" and "arm64v8" or "amd64-alpine", for example to Fetch binaries for a Docker image built on another
+// platform. If this option is left unset, the binaries of the host are used.
+func (c Config) Platform(operatingSystem, architecture string) Config {
+	c.platformOS = operatingSystem
+	c.platformArch = architecture
+	return c
+}
+
+// InitScripts adds the SQL files of fsys matching the glob pattern, such as files embedded with go:embed, to be
+// executed in lexical order against the database once the server is ready. Scripts are only executed when the data
+// directory is initialized, not when a previously initialized data directory is reused.
+func (c Config) InitScripts(fsys fs.FS, glob string) Config {
+	c.initScripts = append(c.initScripts[:len(c.initScripts):len(c.initScripts)], initScript{fsys: fsys, glob: glob})
+	return c
+}
+
+// RestoreFrom sets the path of a dump which is restored into the database after it has been created. Dumps written by
+// pg_dump using the custom format are restored with pg_restore, any other file is executed as plain SQL with psql.
+// Like InitScripts, the dump is only restored when the data directory is initialized.
+func (c Config) RestoreFrom(dumpPath string) Config {
+	c.restoreFrom = dumpPath
+	return c
+}
+
+// Migrator sets a Migrator which is invoked with the connection URL of the database every time the server has started
+// and passed its health check, before any AfterStart hooks are called.
+func (c Config) Migrator(migrator Migrator) Config {
+	c.migrator = migrator
+	return c
+}
+
+// AfterStart adds hooks which are called in order with a connection to the database every time the server has started
+// and passed its health check. If a hook returns an error the server is stopped and Start returns the error. The
+// connection uses the registered SQLDriver.
+func (c Config) AfterStart(hooks ...AfterStartHook) Config {
+	steps := make([]afterStartStep, 0, len(hooks))
+	for _, hook := range hooks {
+		steps = append(steps, afterStartStep{hook: hook})
+	}
+
+	c.afterStart = append(c.afterStart[:len(c.afterStart):len(c.afterStart)], steps...)
+
+	return c
+}
+
+// AfterStartSQL adds SQL statements which are executed in order against the database every time the server has
+// started, such as creating extensions or roles. Unlike AfterStart hooks, they need no registered SQLDriver.
+func (c Config) AfterStartSQL(statements ...string) Config {
+	steps := make([]afterStartStep, 0, len(statements))
+	for _, statement := range statements {
+		steps = append(steps, afterStartStep{statement: statement})
+	}
+
+	c.afterStart = append(c.afterStart[:len(c.afterStart):len(c.afterStart)], steps...)
+
+	return c
+}
+
 func (c Config) GetConnectionURL() string {
-	return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s", c.username, c.password, "localhost", c.port, c.database)
+	connectionURL := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(c.username, c.password),
+		Host:   fmt.Sprintf("localhost:%d", c.port),
+		Path:   "/" + c.database,
+	}
+
+	return connectionURL.String()
 }
 
+func (c Config) connectionDSN() string {
+	return fmt.Sprintf("%s?%s", c.GetConnectionURL(), c.clientSSLSettings().Encode())
+}
+
+// StopMode is a pg_ctl shutdown mode.
+type StopMode string
+
+// Shutdown modes, from the most to the least graceful.
+const (
+	// StopModeSmart waits for all clients to disconnect.
+	StopModeSmart = StopMode("smart")
+	// StopModeFast disconnects clients and rolls back their transactions.
+	StopModeFast = StopMode("fast")
+	// StopModeImmediate aborts all server processes, leading to crash recovery on the next start.
+	StopModeImmediate = StopMode("immediate")
+)
+
+// LeakCheck determines what Stop does with client sessions which are still connected.
+type LeakCheck string
+
+const (
+	// LeakCheckOff does not look for open connections, the default.
+	LeakCheckOff = LeakCheck("")
+	// LeakCheckReport writes each open connection to the logger.
+	LeakCheckReport = LeakCheck("report")
+	// LeakCheckFail writes each open connection to the logger, and makes Stop return an error naming them once the
+	// server has stopped.
+	LeakCheckFail = LeakCheck("fail")
+)
+
+// WalLevel determines how much information is written to the WAL.
+type WalLevel string
+
+const (
+	// WalLevelMinimal writes only the information needed to recover from a crash.
+	WalLevelMinimal = WalLevel("minimal")
+	// WalLevelReplica additionally supports WAL archiving and physical replication, the default.
+	WalLevelReplica = WalLevel("replica")
+	// WalLevelLogical additionally supports logical decoding, as used by logical replication and change data capture.
+	WalLevelLogical = WalLevel("logical")
+)
+
+// AuthMethod is the method used to authenticate connections, as configured by initdb.
+type AuthMethod string
+
+const (
+	// AuthMethodPassword sends the password in clear text.
+	AuthMethodPassword = AuthMethod("password")
+	// AuthMethodScramSHA256 performs a SCRAM-SHA-256 exchange against passwords stored as SCRAM verifiers, the default
+	// of modern Postgres installations. It requires Postgres 10 or later.
+	AuthMethodScramSHA256 = AuthMethod("scram-sha-256")
+	// AuthMethodTrust accepts every local connection without a password, which is only suitable for throwaway
+	// clusters.
+	AuthMethodTrust = AuthMethod("trust")
+)
+
+// DatabaseOptions are the options of CREATE DATABASE. Empty fields leave the server defaults in place.
+type DatabaseOptions struct {
+	// Owner is an existing role which owns the database, the configured user when empty.
+	Owner string
+	// Template is the database to copy, template1 when empty. Use template0 along with Encoding, Collation or
+	// CharacterType values which differ from those of template1.
+	Template string
+	// Encoding is the character set encoding, such as UTF8.
+	Encoding string
+	// Collation is the LC_COLLATE locale, such as en_US.UTF-8.
+	Collation string
+	// CharacterType is the LC_CTYPE locale, such as en_US.UTF-8.
+	CharacterType string
+	// ConnectionLimit is the maximum number of concurrent connections, unlimited when zero.
+	ConnectionLimit int
+}
+
+// Libc represents the C library variant the Linux Postgres binaries are linked against.
+type Libc string
+
+// Supported C library variants.
+const (
+	LibcGlibc = Libc("glibc")
+	LibcMusl  = Libc("musl")
+)
+
 // PostgresVersion represents the semantic version used to fetch and run the Postgres process.
 type PostgresVersion string
 
@@ -0,0 +1,57 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+)
+
+// Connect returns a *sql.DB connected to the configured database of the started server, using the registered
+// SQLDriver. It is closed by Stop, so callers need neither build a DSN nor close it themselves.
+func (ep *EmbeddedPostgres) Connect() (*sql.DB, error) {
+	return ep.ConnectDatabase(ep.config.database)
+}
+
+// ConnectDatabase returns a *sql.DB connected to a database of the started server as the configured user, which is
+// closed by Stop.
+func (ep *EmbeddedPostgres) ConnectDatabase(database string) (*sql.DB, error) {
+	if !ep.started {
+		return nil, errors.New("server has not been started")
+	}
+
+	db, err := OpenDB(ep.config.Database(database).connectionDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	ep.CloseOnStop(db)
+
+	return db, nil
+}
+
+// CloseOnStop registers a connection or pool to close when the server is stopped, before any connection leak check,
+// tying its lifetime to the server. Closers are closed in the reverse order of registration.
+func (ep *EmbeddedPostgres) CloseOnStop(closer io.Closer) {
+	ep.closersMu.Lock()
+	defer ep.closersMu.Unlock()
+
+	ep.closers = append(ep.closers, closer)
+}
+
+// closeOnStop closes the registered closers, returning the first error.
+func (ep *EmbeddedPostgres) closeOnStop() error {
+	ep.closersMu.Lock()
+	closers := ep.closers
+	ep.closers = nil
+	ep.closersMu.Unlock()
+
+	var firstErr error
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,82 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Connect(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9900).
+		Databases("app", "audit").
+		ConnectionLeakCheck(LeakCheckFail))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	db, err := database.Connect()
+	require.NoError(t, err)
+
+	audit, err := database.ConnectDatabase("audit")
+	require.NoError(t, err)
+
+	var current string
+	require.NoError(t, db.QueryRow("SELECT current_database()").Scan(&current))
+	assert.Equal(t, "app", current)
+
+	require.NoError(t, audit.QueryRow("SELECT current_database()").Scan(&current))
+	assert.Equal(t, "audit", current)
+
+	// the open connections are closed before the leak check
+	require.NoError(t, database.Stop())
+
+	assert.EqualError(t, db.Ping(), "sql: database is closed")
+	assert.EqualError(t, audit.Ping(), "sql: database is closed")
+}
+
+func Test_Connect_ErrorWhenNotStarted(t *testing.T) {
+	_, err := NewDatabase().Connect()
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+type recordingCloser struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c recordingCloser) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func Test_CloseOnStop(t *testing.T) {
+	database := NewDatabase()
+
+	var closed []string
+	database.CloseOnStop(recordingCloser{name: "first", closed: &closed, err: errors.New("first failed")})
+	database.CloseOnStop(recordingCloser{name: "second", closed: &closed, err: errors.New("second failed")})
+
+	assert.EqualError(t, database.closeOnStop(), "second failed")
+	assert.Equal(t, []string{"second", "first"}, closed)
+
+	assert.NoError(t, database.closeOnStop())
+	assert.Len(t, closed, 2)
+}
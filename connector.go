@@ -0,0 +1,135 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Connector is a driver.Connector which starts the embedded Postgres server on the first connection and stops it when
+// the *sql.DB using it is closed, allowing the server to be used by applications and tools that only accept a
+// *sql.DB or a DSN. Its connections use the registered SQLDriver.
+//
+//	db := sql.OpenDB(embeddedpostgres.NewConnector(embeddedpostgres.DefaultConfig()))
+type Connector struct {
+	mu        sync.Mutex
+	database  *EmbeddedPostgres
+	connector driver.Connector
+}
+
+// NewConnector creates a Connector for the Postgres server described by the configuration, following the same
+// conventions as NewDatabase.
+func NewConnector(config ...Config) *Connector {
+	return &Connector{database: NewDatabase(config...)}
+}
+
+// Connect starts the server if it is not already running and opens a new connection to it. The lock is only held while
+// starting the server, so that connections are dialled concurrently.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.startedConnector()
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(ctx)
+}
+
+// startedConnector starts the server if it is not already running, returning the connector for its connections.
+func (c *Connector) startedConnector() (driver.Connector, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.database.started {
+		if err := c.database.Start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.connector == nil {
+		connector, err := SQLConnector(c.database.config.connectionDSN())
+		if err != nil {
+			return nil, err
+		}
+
+		c.connector = connector
+	}
+
+	return c.connector, nil
+}
+
+// Driver returns the Driver which created the Connector.
+func (c *Connector) Driver() driver.Driver {
+	return Driver{}
+}
+
+// Close stops the server if it was started, it is called when the *sql.DB using the Connector is closed.
+func (c *Connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.database.started {
+		return nil
+	}
+
+	return c.database.Stop()
+}
+
+// Driver is a database/sql driver which lazily starts an embedded Postgres server configured from the DSN. The port,
+// user, password and dbname of key/value or URL style DSNs are used, with defaults from DefaultConfig for any left
+// unset. It can be registered with sql.Register, or through the sqldriver package under the name "embeddedpostgres".
+type Driver struct{}
+
+// Open starts the server described by the DSN and opens a connection to it. The server is not stopped when the
+// connection is closed, use sql.Open or OpenConnector so that it is stopped along with the *sql.DB.
+func (d Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(context.Background())
+}
+
+// OpenConnector parses the DSN into a lazily started Connector.
+func (d Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	config, err := configFromDSN(DefaultConfig(), dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnector(config), nil
+}
+
+// configFromDSN applies the port, user, password and dbname set by the DSN to the configuration, parsing it in the same
+// way as the internal connection so that quoting and escaping are understood.
+func configFromDSN(config Config, dsn string) (Config, error) {
+	settings, err := parseDSNSettings(dsn)
+	if err != nil {
+		return config, fmt.Errorf("unable to parse dsn: %w", err)
+	}
+
+	if value, ok := settings["port"]; ok {
+		port, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return config, fmt.Errorf("unable to parse dsn: invalid port %s", value)
+		}
+
+		config = config.Port(uint32(port))
+	}
+
+	if value, ok := settings["user"]; ok {
+		config = config.Username(value)
+	}
+
+	if value, ok := settings["password"]; ok {
+		config = config.Password(value)
+	}
+
+	if value, ok := settings["dbname"]; ok {
+		config = config.Database(value)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,87 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CopyFromCSV bulk loads CSV rows read from r into table using the COPY protocol, which is far faster than inserting
+// fixture rows one at a time. The first record must be a header naming the columns to load, and table may be qualified
+// with a schema. As with COPY, empty fields are loaded as NULL. All rows are loaded in a single transaction. db must use
+// the registered SQLDriver, as COPY is not part of database/sql, such as one opened with OpenDB.
+func CopyFromCSV(db *sql.DB, table string, r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read CSV header for %s: %w", table, err)
+	}
+
+	columns := append([]string(nil), header...)
+
+	sqlDriver, err := registeredSQLDriver()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	data := csvCopyData(table, reader)
+
+	err = sqlDriver.CopyFrom(ctx, conn, table, columns, data)
+
+	// unblocks reading the CSV when the copy failed before consuming it
+	_ = data.Close()
+
+	if err != nil {
+		return fmt.Errorf("unable to copy into %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// csvCopyData re-encodes the remaining CSV records as the data of a COPY in CSV format, read from the returned pipe. A
+// failure to read the CSV is returned by the pipe, and closing the pipe stops reading it.
+func csvCopyData(table string, reader *csv.Reader) *io.PipeReader {
+	data, dataWriter := io.Pipe()
+
+	go func() {
+		writer := csv.NewWriter(dataWriter)
+
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				_ = dataWriter.CloseWithError(fmt.Errorf("unable to read CSV for %s: %w", table, err))
+				return
+			}
+
+			if err := writer.Write(record); err != nil {
+				_ = dataWriter.CloseWithError(err)
+				return
+			}
+		}
+
+		writer.Flush()
+		_ = dataWriter.CloseWithError(writer.Error())
+	}()
+
+	return data
+}
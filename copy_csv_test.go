@@ -0,0 +1,22 @@
+package embeddedpostgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CopyFromCSV_ErrorWhenNoHeader(t *testing.T) {
+	err := CopyFromCSV(nil, "beers", strings.NewReader(""))
+
+	assert.EqualError(t, err, "unable to read CSV header for beers: EOF")
+}
+
+func Test_CopyFromCSV_ErrorWhenNoDriverIsRegistered(t *testing.T) {
+	withSQLDriver(t, nil)
+
+	err := CopyFromCSV(nil, "beers", strings.NewReader("name,abv\n"))
+
+	assert.ErrorContains(t, err, "no SQL driver is registered")
+}
@@ -0,0 +1,62 @@
+package embeddedpostgres
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// generateCredentials replaces the configured username and password with random ones when configured to, once per
+// instance so that restarts keep the credentials of the existing data directory.
+func (ep *EmbeddedPostgres) generateCredentials() error {
+	if ep.credentialsGenerated {
+		return nil
+	}
+
+	if ep.config.generateUsername {
+		suffix, err := randomHex(8)
+		if err != nil {
+			return fmt.Errorf("unable to generate username: %w", err)
+		}
+
+		ep.config.username = "user_" + suffix
+	}
+
+	if ep.config.generatePassword {
+		password, err := randomHex(16)
+		if err != nil {
+			return fmt.Errorf("unable to generate password: %w", err)
+		}
+
+		ep.config.password = password
+	}
+
+	ep.credentialsGenerated = true
+
+	return nil
+}
+
+func randomHex(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(random), nil
+}
+
+// Username returns the username of the superuser, which is generated by Start when configured with GenerateUsername.
+func (ep *EmbeddedPostgres) Username() string {
+	return ep.config.username
+}
+
+// Password returns the password of the superuser, which is generated by Start when configured with GeneratePassword.
+func (ep *EmbeddedPostgres) Password() string {
+	return ep.config.password
+}
+
+// GetConnectionURL returns the URL to connect to the configured database, including any generated credentials once
+// started.
+func (ep *EmbeddedPostgres) GetConnectionURL() string {
+	return ep.config.GetConnectionURL()
+}
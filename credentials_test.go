@@ -0,0 +1,64 @@
+package embeddedpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GeneratePassword(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9868).
+		GeneratePassword())
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB(database.GetConnectionURL() + "?sslmode=disable")
+	require.NoError(t, err)
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.Close())
+
+	db, err = OpenDB("host=localhost port=9868 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+	assert.Error(t, db.Ping())
+	require.NoError(t, db.Close())
+}
+
+func Test_generateCredentials(t *testing.T) {
+	database := NewDatabase(DefaultConfig().GenerateUsername().GeneratePassword())
+
+	require.NoError(t, database.generateCredentials())
+
+	username, password := database.Username(), database.Password()
+	assert.Regexp(t, "^user_[0-9a-f]{16}$", username)
+	assert.Regexp(t, "^[0-9a-f]{32}$", password)
+	assert.Contains(t, database.GetConnectionURL(), username+":"+password+"@")
+
+	require.NoError(t, database.generateCredentials())
+	assert.Equal(t, username, database.Username())
+	assert.Equal(t, password, database.Password())
+
+	other := NewDatabase(DefaultConfig().GeneratePassword())
+	require.NoError(t, other.generateCredentials())
+	assert.Equal(t, "postgres", other.Username())
+	assert.NotEqual(t, password, other.Password())
+}
+
+func Test_generateCredentials_KeepsConfiguredCredentials(t *testing.T) {
+	database := NewDatabase(DefaultConfig().Username("gin").Password("wine"))
+
+	require.NoError(t, database.generateCredentials())
+
+	assert.Equal(t, "gin", database.Username())
+	assert.Equal(t, "wine", database.Password())
+}
@@ -0,0 +1,24 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CreateDatabase creates a database on the running server, owned by the configured user and created with the
+// configured DatabaseOptions, as the database created on start is.
+func (ep *EmbeddedPostgres) CreateDatabase(database string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	if err := validateIdentifier("database", database); err != nil {
+		return err
+	}
+
+	if err := ep.createDatabase(ep.config, database, ep.config.databaseOptions); err != nil {
+		return fmt.Errorf("unable to create database %s: %w", database, err)
+	}
+
+	return nil
+}
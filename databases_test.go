@@ -0,0 +1,43 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateDatabase(t *testing.T) {
+	database := NewDatabase(DefaultConfig().Port(9999).Username("gin").Password("wine").DatabaseOptions(DatabaseOptions{Encoding: "LATIN1"}))
+	database.started = true
+
+	var created string
+	database.createDatabase = func(config Config, name string, options DatabaseOptions) error {
+		assert.Equal(t, uint32(9999), config.port)
+		assert.Equal(t, "gin", config.username)
+		assert.Equal(t, "wine", config.password)
+		assert.Equal(t, "LATIN1", options.Encoding)
+		created = name
+
+		return nil
+	}
+
+	require.NoError(t, database.CreateDatabase("orders"))
+	assert.Equal(t, "orders", created)
+}
+
+func Test_CreateDatabase_Error(t *testing.T) {
+	database := NewDatabase()
+	database.started = true
+	database.createDatabase = func(config Config, name string, options DatabaseOptions) error {
+		return errors.New("ah noes")
+	}
+
+	assert.EqualError(t, database.CreateDatabase("orders"), "unable to create database orders: ah noes")
+	assert.EqualError(t, database.CreateDatabase(""), "database name must not be empty")
+}
+
+func Test_CreateDatabase_NotStarted(t *testing.T) {
+	assert.EqualError(t, NewDatabase().CreateDatabase("orders"), "server has not been started")
+}
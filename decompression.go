@@ -2,6 +2,7 @@ package embeddedpostgres
 
 import (
 	"archive/tar"
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -10,6 +11,37 @@ import (
 	"github.com/xi2/xz"
 )
 
+// extractBufferSize is the size of the read buffer in front of the xz decoder.
+const extractBufferSize = 1 << 20
+
+// archiveDecodeError is an error decoding the archive itself, as opposed to writing the extracted files, which for a
+// cached archive means it is corrupt.
+type archiveDecodeError struct {
+	err error
+}
+
+func (e *archiveDecodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *archiveDecodeError) Unwrap() error {
+	return e.err
+}
+
+// archiveReader marks errors reading the content of an entry as errors decoding the archive.
+type archiveReader struct {
+	reader io.Reader
+}
+
+func (r archiveReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		return n, &archiveDecodeError{err}
+	}
+
+	return n, err
+}
+
 func defaultTarReader(xzReader *xz.Reader) (func() (*tar.Header, error), func() io.Reader) {
 	tarReader := tar.NewReader(xzReader)
 
@@ -21,6 +53,10 @@ func defaultTarReader(xzReader *xz.Reader) (func() (*tar.Header, error), func()
 }
 
 func decompressTarXz(tarReader func(*xz.Reader) (func() (*tar.Header, error), func() io.Reader), path, extractPath string) error {
+	if err := os.MkdirAll(filepath.Dir(extractPath), os.ModePerm); err != nil {
+		return errorUnableToExtract(path, extractPath, err)
+	}
+
 	tempExtractPath, err := os.MkdirTemp(filepath.Dir(extractPath), "temp_")
 	if err != nil {
 		return errorUnableToExtract(path, extractPath, err)
@@ -42,9 +78,9 @@ func decompressTarXz(tarReader func(*xz.Reader) (func() (*tar.Header, error), fu
 		}
 	}()
 
-	xzReader, err := xz.NewReader(tarFile, 0)
+	xzReader, err := xz.NewReader(bufio.NewReaderSize(tarFile, extractBufferSize), 0)
 	if err != nil {
-		return errorUnableToExtract(path, extractPath, err)
+		return errorUnableToExtract(path, extractPath, &archiveDecodeError{err})
 	}
 
 	readNext, reader := tarReader(xzReader)
@@ -57,7 +93,7 @@ func decompressTarXz(tarReader func(*xz.Reader) (func() (*tar.Header, error), fu
 		}
 
 		if err != nil {
-			return errorExtractingPostgres(err)
+			return errorExtractingPostgres(&archiveDecodeError{err})
 		}
 
 		targetPath := filepath.Join(tempExtractPath, header.Name)
@@ -78,13 +114,18 @@ func decompressTarXz(tarReader func(*xz.Reader) (func() (*tar.Header, error), fu
 				return errorExtractingPostgres(err)
 			}
 
-			if _, err := io.Copy(outFile, reader()); err != nil {
+			if _, err := io.Copy(outFile, archiveReader{reader()}); err != nil {
+				_ = outFile.Close()
 				return errorExtractingPostgres(err)
 			}
 
 			if err := outFile.Close(); err != nil {
 				return errorExtractingPostgres(err)
 			}
+
+			if err := renameOrIgnore(targetPath, finalPath); err != nil {
+				return errorExtractingPostgres(err)
+			}
 		case tar.TypeSymlink:
 			if err := os.RemoveAll(targetPath); err != nil {
 				return errorExtractingPostgres(err)
@@ -94,15 +135,13 @@ func decompressTarXz(tarReader func(*xz.Reader) (func() (*tar.Header, error), fu
 				return errorExtractingPostgres(err)
 			}
 
+			if err := renameOrIgnore(targetPath, finalPath); err != nil {
+				return errorExtractingPostgres(err)
+			}
 		case tar.TypeDir:
 			if err := os.MkdirAll(finalPath, os.FileMode(header.Mode)); err != nil {
 				return errorExtractingPostgres(err)
 			}
-			continue
-		}
-
-		if err := renameOrIgnore(targetPath, finalPath); err != nil {
-			return errorExtractingPostgres(err)
 		}
 	}
 
@@ -204,3 +204,22 @@ func Test_decompressTarXz_ErrorWithInvalidDestination(t *testing.T) {
 		fmt.Sprintf("unable to extract postgres archive: mkdir %s: invalid argument", op),
 	)
 }
+
+// BenchmarkDecompressTarXz extracts the archive at EMBEDDED_POSTGRES_BENCHMARK_ARCHIVE, such as a cached Postgres
+// binaries archive, falling back to a small test archive.
+func BenchmarkDecompressTarXz(b *testing.B) {
+	archive := os.Getenv("EMBEDDED_POSTGRES_BENCHMARK_ARCHIVE")
+	if archive == "" {
+		var cleanUp func()
+		archive, cleanUp = createTempXzArchive()
+		defer cleanUp()
+	}
+
+	for i := 0; i < b.N; i++ {
+		extractPath := filepath.Join(b.TempDir(), "extracted")
+
+		if err := decompressTarXz(defaultTarReader, archive, extractPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Detach releases a started server, leaving it running once this process exits, such as a long lived database started
+// from the command line. The server is no longer supervised, and Stop no longer stops it. Detached servers are not
+// treated as orphaned by later starts, use Attach to stop them again.
+func (ep *EmbeddedPostgres) Detach() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	ep.stopMonitor()
+	ep.stopServerLogForwarding()
+
+	removeOwnerPID(ep.config.dataPath)
+	ep.started = false
+	ep.markExited(nil)
+
+	return ep.syncedLogger.flush()
+}
+
+// Attach takes over a server running in the configured data directory which was started and detached by another
+// process, so that it is supervised and can be stopped with Stop as if it was started by Start. The configuration must
+// describe the server as it was started, including its runtime, data and binaries directories when those were set.
+func (ep *EmbeddedPostgres) Attach() error {
+	if ep.started {
+		return errors.New("server is already started")
+	}
+
+	logger, err := newSyncedLogger("", ep.config.logger)
+	if err != nil {
+		return errors.New("unable to create logger")
+	}
+
+	ep.syncedLogger = logger
+
+	ep.config.runtimePath = ep.RuntimePath()
+	ep.config.dataPath = ep.DataPath()
+	ep.config.binariesPath = ep.BinariesPath()
+
+	if !postgresRunning(ep) {
+		return fmt.Errorf("no server is running in data directory %s", ep.config.dataPath)
+	}
+
+	pid, err := readPostmasterPID(ep.config.dataPath)
+	if err != nil {
+		return err
+	}
+
+	lifeline, err := newLifeline(ep.pgCtlPath(), ep.config.dataPath)
+	if err != nil {
+		return err
+	}
+
+	// server processes which were started before attaching exit once the postmaster is stopped along with this process
+	_ = lifeline.adopt(pid)
+
+	if err := writeOwnerPID(ep.config.dataPath); err != nil {
+		lifeline.release()
+
+		return fmt.Errorf("unable to record owner of data directory %s: %w", ep.config.dataPath, err)
+	}
+
+	ep.lifeline = lifeline
+	ep.started = true
+	ep.startMonitor()
+
+	return nil
+}
+
+// PID returns the process ID of the started server, which changes when the server is restarted.
+func (ep *EmbeddedPostgres) PID() (int, error) {
+	if !ep.started {
+		return 0, errors.New("server has not been started")
+	}
+
+	return readPostmasterPID(ep.config.dataPath)
+}
@@ -0,0 +1,62 @@
+package embeddedpostgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DetachAndAttach(t *testing.T) {
+	runtimePath := t.TempDir()
+	config := DefaultConfig().
+		RuntimePath(runtimePath).
+		Port(9897)
+
+	database := NewDatabase(config)
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	pid, err := database.PID()
+	require.NoError(t, err)
+
+	require.NoError(t, database.Detach())
+	assert.False(t, database.started)
+	assert.NoError(t, database.Wait())
+	assert.True(t, processAlive(pid))
+
+	attached := NewDatabase(config)
+	require.NoError(t, attached.Attach())
+
+	attachedPID, err := attached.PID()
+	require.NoError(t, err)
+	assert.Equal(t, pid, attachedPID)
+
+	require.NoError(t, attached.Stop())
+	assert.True(t, waitForExit(pid, 5*time.Second))
+}
+
+func Test_Detach_ErrorWhenNotStarted(t *testing.T) {
+	assert.EqualError(t, NewDatabase().Detach(), "server has not been started")
+}
+
+func Test_Attach_ErrorWhenNotRunning(t *testing.T) {
+	runtimePath := t.TempDir()
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(runtimePath).
+		BinariesPath(t.TempDir()))
+
+	err := database.Attach()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no server is running in data directory")
+	assert.False(t, database.started)
+}
+
+func Test_PID_ErrorWhenNotStarted(t *testing.T) {
+	_, err := NewDatabase().PID()
+
+	assert.EqualError(t, err, "server has not been started")
+}
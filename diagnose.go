@@ -0,0 +1,255 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Severity is how serious the outcome of a diagnostic check is.
+type Severity string
+
+const (
+	// SeverityOK means the check found no problem.
+	SeverityOK = Severity("ok")
+	// SeverityWarning means the check found something which may cause problems or slow down starting.
+	SeverityWarning = Severity("warning")
+	// SeverityError means the check found a problem which stops the server from starting.
+	SeverityError = Severity("error")
+)
+
+// Finding is the outcome of a diagnostic check, together with a hint on how to resolve any problem it found.
+type Finding struct {
+	// Check is the name of the check, such as "platform" or "port".
+	Check    string
+	Severity Severity
+	Message  string
+	// Hint explains how to resolve the problem, and is empty when there is no problem.
+	Hint string
+}
+
+func (f Finding) String() string {
+	if f.Hint == "" {
+		return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Check, f.Message)
+	}
+
+	return fmt.Sprintf("[%s] %s: %s\n    %s", f.Severity, f.Check, f.Message, f.Hint)
+}
+
+// minimumFreeDiskSpace is the free disk space needed to extract the binaries and initialize a data directory.
+const minimumFreeDiskSpace = 512 * 1024 * 1024
+
+// xzMagic starts every xz compressed archive.
+var xzMagic = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+
+// Diagnose checks the environment for the problems which most often stop the configured server from starting: binaries
+// which are not published for the platform or cannot be downloaded, a C library mismatch, too little disk space, a port
+// in use, a corrupt cache, servers left running by exited tests and running as root. It returns a finding for each
+// check, and does not change anything.
+func (ep *EmbeddedPostgres) Diagnose() []Finding {
+	findings := []Finding{
+		ep.diagnosePlatform(),
+		diagnoseLibc(runtime.GOOS, ep.config.libc, shouldUseAlpineLinuxBuild),
+	}
+
+	cacheLocation, _ := ep.cacheLocator()
+	findings = append(findings, diagnoseDiskSpace(filepath.Dir(cacheLocation)))
+
+	if runtimePath := ep.RuntimePath(); !strings.HasPrefix(runtimePath, filepath.Dir(cacheLocation)) {
+		findings = append(findings, diagnoseDiskSpace(runtimePath))
+	}
+
+	return append(findings,
+		diagnosePort(ep.config.port),
+		ep.diagnoseCache(),
+		diagnoseOrphans(ep.DataPath()),
+		diagnoseUser(),
+	)
+}
+
+func (ep *EmbeddedPostgres) diagnosePlatform() Finding {
+	operatingSystem, architecture, version := ep.versionStrategy()
+	platform := fmt.Sprintf("Postgres %s for %s/%s", version, operatingSystem, architecture)
+
+	if _, cached := ep.cacheLocator(); cached {
+		return Finding{Check: "platform", Severity: SeverityOK, Message: platform + " is cached"}
+	}
+
+	host := ep.config.binaryRepositoryURL
+	if err := checkArtifactAvailable(binaryRepositoryClient(ep.config), artifactURL(host, operatingSystem, architecture, version), host, operatingSystem, architecture, version); err != nil {
+		return Finding{
+			Check:    "platform",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s cannot be downloaded: %s", platform, err),
+			Hint: "Choose a Version published for this platform, configure BinaryRepositoryURL with a reachable " +
+				"mirror, or download the binaries where the repository is reachable with embedded-postgres fetch.",
+		}
+	}
+
+	return Finding{Check: "platform", Severity: SeverityOK, Message: platform + " is available from " + host}
+}
+
+func diagnoseLibc(goos string, configured Libc, detectMusl func() bool) Finding {
+	if goos != "linux" {
+		return Finding{Check: "libc", Severity: SeverityOK, Message: "not applicable on " + goos}
+	}
+
+	detected := LibcGlibc
+	if detectMusl() {
+		detected = LibcMusl
+	}
+
+	if configured != "" && configured != detected {
+		return Finding{
+			Check:    "libc",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("binaries for %s are configured, but the host uses %s", configured, detected),
+			Hint:     "Remove the Libc option unless the host is detected incorrectly, as the binaries may not run.",
+		}
+	}
+
+	return Finding{Check: "libc", Severity: SeverityOK, Message: fmt.Sprintf("the host uses %s", detected)}
+}
+
+func diagnoseDiskSpace(path string) Finding {
+	// the directory may not have been created yet
+	existing := path
+	for {
+		if _, err := os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+
+		existing = filepath.Dir(existing)
+	}
+
+	available, err := freeDiskSpace(existing)
+	if err != nil {
+		return Finding{Check: "disk space", Severity: SeverityWarning, Message: fmt.Sprintf("unable to check free space for %s: %s", path, err)}
+	}
+
+	message := fmt.Sprintf("%d MiB free for %s", available/(1024*1024), path)
+	if available < minimumFreeDiskSpace {
+		return Finding{
+			Check:    "disk space",
+			Severity: SeverityError,
+			Message:  message,
+			Hint: fmt.Sprintf("At least %d MiB are needed for the binaries and a data directory. Free up space, for "+
+				"example with embedded-postgres cache purge, or configure CachePath and RuntimePath elsewhere.",
+				minimumFreeDiskSpace/(1024*1024)),
+		}
+	}
+
+	return Finding{Check: "disk space", Severity: SeverityOK, Message: message}
+}
+
+func diagnosePort(port uint32) Finding {
+	if err := ensurePortAvailable(port); err != nil {
+		return Finding{
+			Check:    "port",
+			Severity: SeverityError,
+			Message:  err.Error(),
+			Hint:     "Stop the process listening on the port, or configure another Port.",
+		}
+	}
+
+	return Finding{Check: "port", Severity: SeverityOK, Message: fmt.Sprintf("port %d is available", port)}
+}
+
+func (ep *EmbeddedPostgres) diagnoseCache() Finding {
+	cacheLocation, cached := ep.cacheLocator()
+	if !cached {
+		return Finding{Check: "cache", Severity: SeverityOK, Message: "the binaries are not cached yet and will be downloaded"}
+	}
+
+	if err := checkArchiveHeader(cacheLocation); err != nil {
+		return Finding{
+			Check:    "cache",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("the cached archive %s is corrupt: %s", cacheLocation, err),
+			Hint:     "Remove it with embedded-postgres cache rm or RemoveFromCache to download it again.",
+		}
+	}
+
+	binariesPath := ep.BinariesPath()
+	if _, err := os.Stat(binariesPath); err != nil {
+		return Finding{Check: "cache", Severity: SeverityOK, Message: fmt.Sprintf("the archive %s is cached and will be extracted", cacheLocation)}
+	}
+
+	if _, ok := readBinariesMarker(binariesPath, ep.config.version, cacheLocation); !ok {
+		return Finding{
+			Check:    "cache",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("the binaries in %s were not extracted from the cached archive %s", binariesPath, cacheLocation),
+			Hint:     "They are extracted again on the next start, unless BinariesPath points to binaries installed separately.",
+		}
+	}
+
+	return Finding{Check: "cache", Severity: SeverityOK, Message: fmt.Sprintf("the binaries in %s match the cached archive", binariesPath)}
+}
+
+// checkArchiveHeader checks that a cached archive starts like an xz archive, as truncated downloads and error pages
+// saved by older versions do not.
+func checkArchiveHeader(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	header := make([]byte, len(xzMagic))
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, xzMagic) {
+		return fmt.Errorf("not an xz archive")
+	}
+
+	return nil
+}
+
+func diagnoseOrphans(dataPath string) Finding {
+	pid, err := readPostmasterPID(dataPath)
+	if err != nil {
+		return Finding{Check: "orphans", Severity: SeverityOK, Message: "no server is running in " + dataPath}
+	}
+
+	switch {
+	case !processAlive(pid):
+		return Finding{
+			Check:    "orphans",
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("a server which has exited left its process ID in %s, which is cleaned up on start", dataPath),
+		}
+	case isOrphanedServer(dataPath):
+		return Finding{
+			Check:    "orphans",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("server process %d in %s was left running by an exited process", pid, dataPath),
+			Hint:     "It is stopped by the next start using the same data directory, or stop it with pg_ctl stop -D " + dataPath + ".",
+		}
+	default:
+		return Finding{
+			Check:    "orphans",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("server process %d is running in %s", pid, dataPath),
+			Hint:     "Stop that server first, or configure another RuntimePath or DataPath, as a data directory cannot be shared.",
+		}
+	}
+}
+
+func diagnoseUser() Finding {
+	if runningAsRoot() {
+		return Finding{
+			Check:    "user",
+			Severity: SeverityError,
+			Message:  "running as the root user",
+			Hint:     rootUserAdvice,
+		}
+	}
+
+	return Finding{Check: "user", Severity: SeverityOK, Message: "not running as the root user"}
+}
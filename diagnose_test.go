@@ -0,0 +1,159 @@
+package embeddedpostgres
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Diagnose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	database := NewDatabase(DefaultConfig().
+		CachePath(t.TempDir()).
+		RuntimePath(t.TempDir()).
+		BinaryRepositoryURL(server.URL).
+		Port(9898))
+
+	var checks []string
+	for _, finding := range database.Diagnose() {
+		checks = append(checks, finding.Check)
+	}
+
+	assert.Equal(t, []string{"platform", "libc", "disk space", "disk space", "port", "cache", "orphans", "user"}, checks)
+}
+
+func Test_diagnosePlatform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	database := NewDatabase(DefaultConfig().
+		CachePath(t.TempDir()).
+		BinaryRepositoryURL(server.URL).
+		Platform("linux", "amd64").
+		Version("15.99.0"))
+
+	finding := database.diagnosePlatform()
+
+	assert.Equal(t, SeverityError, finding.Severity)
+	assert.Contains(t, finding.Message, "Postgres 15.99.0 for linux/amd64 cannot be downloaded: version 15.99.0 is not published for linux/amd64")
+	assert.NotEmpty(t, finding.Hint)
+
+	database.cacheLocator = func() (string, bool) {
+		return "", true
+	}
+
+	finding = database.diagnosePlatform()
+
+	assert.Equal(t, Finding{Check: "platform", Severity: SeverityOK, Message: "Postgres 15.99.0 for linux/amd64 is cached"}, finding)
+}
+
+func Test_diagnoseLibc(t *testing.T) {
+	musl := func() bool { return true }
+	glibc := func() bool { return false }
+
+	assert.Equal(t, SeverityOK, diagnoseLibc("darwin", LibcMusl, musl).Severity)
+	assert.Equal(t, Finding{Check: "libc", Severity: SeverityOK, Message: "the host uses musl"}, diagnoseLibc("linux", "", musl))
+	assert.Equal(t, SeverityOK, diagnoseLibc("linux", LibcGlibc, glibc).Severity)
+
+	finding := diagnoseLibc("linux", LibcGlibc, musl)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+	assert.Equal(t, "binaries for glibc are configured, but the host uses musl", finding.Message)
+}
+
+func Test_diagnoseDiskSpace(t *testing.T) {
+	finding := diagnoseDiskSpace(filepath.Join(t.TempDir(), "not", "created"))
+
+	assert.Equal(t, "disk space", finding.Check)
+	assert.Contains(t, finding.Message, "MiB free for")
+}
+
+func Test_diagnosePort(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+
+	finding := diagnosePort(port)
+	assert.Equal(t, SeverityError, finding.Severity)
+	assert.NotEmpty(t, finding.Hint)
+
+	require.NoError(t, listener.Close())
+
+	assert.Equal(t, SeverityOK, diagnosePort(port).Severity)
+}
+
+func Test_diagnoseCache(t *testing.T) {
+	cacheLocation := filepath.Join(t.TempDir(), "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+	cached := false
+
+	database := NewDatabase(DefaultConfig().BinariesPath(filepath.Join(t.TempDir(), "missing")))
+	database.cacheLocator = func() (string, bool) {
+		return cacheLocation, cached
+	}
+
+	assert.Equal(t, "the binaries are not cached yet and will be downloaded", database.diagnoseCache().Message)
+
+	cached = true
+	require.NoError(t, os.WriteFile(cacheLocation, []byte("<html>"), 0600))
+
+	finding := database.diagnoseCache()
+	assert.Equal(t, SeverityError, finding.Severity)
+	assert.Contains(t, finding.Message, "is corrupt: not an xz archive")
+
+	archive, cleanUp := createTempXzArchive()
+	defer cleanUp()
+	require.NoError(t, copyFile(archive, cacheLocation, 0600))
+
+	finding = database.diagnoseCache()
+	assert.Equal(t, SeverityOK, finding.Severity)
+	assert.Contains(t, finding.Message, "is cached and will be extracted")
+
+	database.config.binariesPath = t.TempDir()
+
+	assert.Equal(t, SeverityWarning, database.diagnoseCache().Severity)
+}
+
+func Test_diagnoseOrphans(t *testing.T) {
+	dataPath := t.TempDir()
+
+	assert.Equal(t, SeverityOK, diagnoseOrphans(dataPath).Severity)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte(strconv.Itoa(os.Getpid())+"\n"), 0600))
+
+	finding := diagnoseOrphans(dataPath)
+	assert.Equal(t, SeverityWarning, finding.Severity)
+	assert.Contains(t, finding.Message, "is running in")
+}
+
+func Test_diagnoseUser(t *testing.T) {
+	original := runningAsRoot
+	defer func() {
+		runningAsRoot = original
+	}()
+
+	runningAsRoot = func() bool { return false }
+	assert.Equal(t, SeverityOK, diagnoseUser().Severity)
+
+	runningAsRoot = func() bool { return true }
+	finding := diagnoseUser()
+	assert.Equal(t, SeverityError, finding.Severity)
+	assert.Contains(t, finding.Hint, "Postgres refuses to run as the root user")
+}
+
+func Test_Finding_String(t *testing.T) {
+	assert.Equal(t, "[ok] port: port 5432 is available", Finding{Check: "port", Severity: SeverityOK, Message: "port 5432 is available"}.String())
+	assert.Equal(t, "[error] port: in use\n    Configure another Port.", Finding{Check: "port", Severity: SeverityError, Message: "in use", Hint: "Configure another Port."}.String())
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package embeddedpostgres
+
+import "syscall"
+
+// freeDiskSpace returns the number of bytes available to this user on the file system containing the path.
+func freeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+// +build windows
+
+package embeddedpostgres
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// freeDiskSpace returns the number of bytes available to this user on the volume containing the path.
+func freeDiskSpace(path string) (uint64, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+
+	result, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPointer)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if result == 0 {
+		return 0, err
+	}
+
+	return available, nil
+}
@@ -0,0 +1,120 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+// DumpFormat is the output format of pg_dump.
+type DumpFormat string
+
+// Predefined dump formats.
+const (
+	DumpFormatPlain  = DumpFormat("plain")
+	DumpFormatCustom = DumpFormat("custom")
+)
+
+// DumpOptions configures a Dump. The zero value dumps the schema and data of the whole database as plain SQL, without
+// ownership or privilege statements so that the output is stable between environments.
+type DumpOptions struct {
+	// Format of the dump, DumpFormatPlain when empty.
+	Format DumpFormat
+	// SchemaOnly dumps only the object definitions.
+	SchemaOnly bool
+	// DataOnly dumps only the data.
+	DataOnly bool
+	// Tables limits the dump to the tables matching these patterns.
+	Tables []string
+	// ExcludeTables excludes the tables matching these patterns from the dump.
+	ExcludeTables []string
+	// IncludeOwnership includes statements setting the ownership and privileges of objects.
+	IncludeOwnership bool
+}
+
+// Dump writes a dump of the database to w using the bundled pg_dump, for example to capture golden snapshots in tests
+// or export the state of a persistent database.
+func (ep *EmbeddedPostgres) Dump(ctx context.Context, database string, w io.Writer, opts DumpOptions) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	stderr := &bytes.Buffer{}
+	dumpProcess := clientCommand(ctx, ep.config, "pg_dump", dumpArgs(database, opts)...)
+	dumpProcess.Stdout = w
+	dumpProcess.Stderr = stderr
+
+	if err := dumpProcess.Run(); err != nil {
+		return fmt.Errorf("unable to dump database %s using '%s': %w\n%s", database, dumpProcess.String(), err, stderr.String())
+	}
+
+	return nil
+}
+
+func dumpArgs(database string, opts DumpOptions) []string {
+	format := opts.Format
+	if format == "" {
+		format = DumpFormatPlain
+	}
+
+	args := []string{"--format=" + string(format)}
+
+	if opts.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+
+	if opts.DataOnly {
+		args = append(args, "--data-only")
+	}
+
+	if !opts.IncludeOwnership {
+		args = append(args, "--no-owner", "--no-privileges")
+	}
+
+	for _, table := range opts.Tables {
+		args = append(args, "--table="+table)
+	}
+
+	for _, table := range opts.ExcludeTables {
+		args = append(args, "--exclude-table="+table)
+	}
+
+	return append(args, "-d", database)
+}
+
+// CloneSchema copies the schema of another server, such as a staging database, into the configured database. The schema
+// is dumped from sourceDSN, a connection string or URL, by the bundled pg_dump without ownership or privileges and then
+// applied with psql. The bundled pg_dump must be at least the version of the source server.
+func (ep *EmbeddedPostgres) CloneSchema(ctx context.Context, sourceDSN string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	schema := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	dumpBinary := filepath.Join(ep.config.binariesPath, "bin", "pg_dump")
+	dumpProcess := exec.CommandContext(ctx, dumpBinary, dumpArgs(sourceDSN, DumpOptions{SchemaOnly: true})...)
+	dumpProcess.Stdout = schema
+	dumpProcess.Stderr = stderr
+
+	// the command is not included in errors as the source DSN may contain a password
+	if err := dumpProcess.Run(); err != nil {
+		return fmt.Errorf("unable to dump schema from source database: %w\n%s", err, stderr.String())
+	}
+
+	applyProcess := clientCommand(ctx, ep.config, "psql", "-v", "ON_ERROR_STOP=1", "-q", "-d", ep.config.database)
+	applyProcess.Stdin = schema
+	applyProcess.Stdout = stderr
+	applyProcess.Stderr = stderr
+
+	if err := applyProcess.Run(); err != nil {
+		return fmt.Errorf("unable to apply schema to database %s using '%s': %w\n%s", ep.config.database, applyProcess.String(), err, stderr.String())
+	}
+
+	return nil
+}
@@ -3,26 +3,46 @@ package embeddedpostgres
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var mu sync.Mutex
 
 // EmbeddedPostgres maintains all configuration and runtime functions for maintaining the lifecycle of one Postgres process.
 type EmbeddedPostgres struct {
-	config              Config
-	cacheLocator        CacheLocator
-	remoteFetchStrategy RemoteFetchStrategy
-	initDatabase        initDatabase
-	createDatabase      createDatabase
-	started             bool
-	syncedLogger        *syncedLogger
+	config               Config
+	versionStrategy      VersionStrategy
+	cacheLocator         CacheLocator
+	remoteFetchStrategy  RemoteFetchStrategy
+	initDatabase         initDatabase
+	createDatabase       createDatabase
+	started              bool
+	syncedLogger         *syncedLogger
+	monitorStop          chan struct{}
+	monitorDone          chan struct{}
+	exitMu               sync.Mutex
+	exitStatus           ExitStatus
+	exited               chan struct{}
+	inMemoryData         bool
+	credentialsGenerated bool
+	primary              *EmbeddedPostgres
+	certificateAuthority *certificateAuthority
+	paused               bool
+	serverLogStop        chan struct{}
+	serverLogDone        chan struct{}
+	startReport          StartReport
+	closersMu            sync.Mutex
+	closers              []io.Closer
+	lifeline             *lifeline
 }
 
 // NewDatabase creates a new EmbeddedPostgres struct that can be used to start and stop a Postgres process.
@@ -37,18 +57,23 @@ func NewDatabase(config ...Config) *EmbeddedPostgres {
 }
 
 func newDatabaseWithConfig(config Config) *EmbeddedPostgres {
-	versionStrategy := defaultVersionStrategy(
-		config,
-		runtime.GOOS,
-		runtime.GOARCH,
-		linuxMachineName,
-		shouldUseAlpineLinuxBuild,
-	)
-	cacheLocator := defaultCacheLocator(versionStrategy)
-	remoteFetchStrategy := defaultRemoteFetchStrategy(config.binaryRepositoryURL, versionStrategy, cacheLocator)
+	versionStrategy := config.versionStrategy
+	if versionStrategy == nil {
+		versionStrategy = defaultVersionStrategy(
+			config,
+			runtime.GOOS,
+			runtime.GOARCH,
+			linuxMachineName,
+			shouldUseAlpineLinuxBuild,
+		)
+	}
+
+	cacheLocator := defaultCacheLocator(config.cachePath, versionStrategy)
+	remoteFetchStrategy := defaultRemoteFetchStrategy(config.binaryRepositoryURL, binaryRepositoryClient(config), versionStrategy, cacheLocator)
 
 	return &EmbeddedPostgres{
 		config:              config,
+		versionStrategy:     versionStrategy,
 		cacheLocator:        cacheLocator,
 		remoteFetchStrategy: remoteFetchStrategy,
 		initDatabase:        defaultInitDatabase,
@@ -61,14 +86,19 @@ func newDatabaseWithConfig(config Config) *EmbeddedPostgres {
 // If any error occurs Start will try to also Stop the Postgres process in order to not leave any sub-process running.
 //
 //nolint:funlen
-func (ep *EmbeddedPostgres) Start() error {
+func (ep *EmbeddedPostgres) Start() (err error) {
 	if ep.started {
 		return errors.New("server is already started")
 	}
 
-	if err := ensurePortAvailable(ep.config.port); err != nil {
-		return err
-	}
+	started := time.Now()
+	ep.startReport = StartReport{}
+
+	defer func() {
+		if err != nil {
+			ep.emitEvent(LifecycleEvent{Type: EventStartFailed, Err: err}, started)
+		}
+	}()
 
 	logger, err := newSyncedLogger("", ep.config.logger)
 	if err != nil {
@@ -77,22 +107,36 @@ func (ep *EmbeddedPostgres) Start() error {
 
 	ep.syncedLogger = logger
 
+	if err := ep.generateCredentials(); err != nil {
+		return err
+	}
+
 	cacheLocation, cacheExists := ep.cacheLocator()
 
-	if ep.config.runtimePath == "" {
-		ep.config.runtimePath = filepath.Join(filepath.Dir(cacheLocation), "extracted")
+	ep.inMemoryData = ep.inMemoryData || (ep.config.dataPath == "" && ep.config.dataInMemory && inMemoryDirectory() != "")
+	ep.config.runtimePath = ep.RuntimePath()
+	ep.config.dataPath = ep.DataPath()
+
+	// a server orphaned on the runtime directory of this port also listens on it, so it is stopped before the port is
+	// checked
+	if err := ep.removeStaleServer(); err != nil {
+		return err
 	}
 
-	if ep.config.dataPath == "" {
-		ep.config.dataPath = filepath.Join(ep.config.runtimePath, "data")
+	if err := ensurePortAvailable(ep.config.port); err != nil {
+		return err
 	}
 
-	if err := os.RemoveAll(ep.config.runtimePath); err != nil {
-		return fmt.Errorf("unable to clean up runtime directory %s with error: %s", ep.config.runtimePath, err)
+	ep.config.binariesPath = ep.BinariesPath()
+
+	if err := ep.cleanRuntimeDirectory(cacheLocation, cacheExists); err != nil {
+		return err
 	}
 
-	if ep.config.binariesPath == "" {
-		ep.config.binariesPath = ep.config.runtimePath
+	if ep.inMemoryData {
+		if err := os.RemoveAll(ep.config.dataPath); err != nil {
+			return fmt.Errorf("unable to clean up data directory %s with error: %s", ep.config.dataPath, err)
+		}
 	}
 
 	if err := ep.downloadAndExtractBinary(cacheExists, cacheLocation); err != nil {
@@ -111,66 +155,359 @@ func (ep *EmbeddedPostgres) Start() error {
 		}
 	}
 
+	if ep.config.ssl || ep.config.requireClientCertificates {
+		ca, err := writeServerCertificates(ep.config.dataPath)
+		if err != nil {
+			return err
+		}
+
+		ep.certificateAuthority = ca
+	}
+
+	// the library connects over TCP too, which requires a client certificate of the superuser
+	if ep.config.requireClientCertificates {
+		if _, _, err := issueClientCertificate(ep.certificateAuthority, ep.config.runtimePath, ep.config.username); err != nil {
+			return err
+		}
+	}
+
+	if err := writeClientCertificateRule(ep.config.dataPath, ep.config.authMethod, ep.config.requireClientCertificates); err != nil {
+		return err
+	}
+
+	if err := checkPreloadLibraries(ep.config); err != nil {
+		return err
+	}
+
+	if ep.config.walArchiving {
+		if err := os.MkdirAll(ep.WalArchivePath(), 0700); err != nil {
+			return fmt.Errorf("unable to create WAL archive directory %s with error: %s", ep.WalArchivePath(), err)
+		}
+	}
+
+	if err := writeServerParameters(ep.config.dataPath, ep.serverParameters()); err != nil {
+		return err
+	}
+
+	serverStarted := time.Now()
+
+	lifeline, err := newLifeline(ep.pgCtlPath(), ep.config.dataPath)
+	if err != nil {
+		return err
+	}
+
+	ep.lifeline = lifeline
+
 	if err := startPostgres(ep); err != nil {
+		ep.releaseLifeline()
+
 		return err
 	}
 
+	ep.emit(EventServerStarted, serverStarted)
+	ep.startServerLogForwarding()
+
 	if err := ep.syncedLogger.flush(); err != nil {
 		return err
 	}
 
 	ep.started = true
+	ep.startMonitor()
+
+	if err := writeOwnerPID(ep.config.dataPath); err != nil {
+		return ep.stopAfterError(fmt.Errorf("unable to record owner of data directory %s: %w", ep.config.dataPath, err))
+	}
 
+	// a standby is read only and receives its databases from its primary or backup
+	if err := ep.prepareDatabase(reuseData || ep.isStandby()); err != nil {
+		return ep.stopAfterError(err)
+	}
+
+	if ep.config.readOnly {
+		if err := ep.makeReadOnly(); err != nil {
+			return ep.stopAfterError(err)
+		}
+	}
+
+	ep.emit(EventReady, started)
+
+	return nil
+}
+
+// prepareDatabase creates and populates the configured databases once the server has started. Databases, dumps, init
+// scripts and roles are only applied to a freshly initialized data directory.
+func (ep *EmbeddedPostgres) prepareDatabase(reuseData bool) error {
 	if !reuseData {
-		if err := ep.createDatabase(ep.config.port, ep.config.username, ep.config.password, ep.config.database); err != nil {
-			if stopErr := stopPostgres(ep); stopErr != nil {
-				return fmt.Errorf("unable to stop database casused by error %s", err)
+		for _, database := range append([]string{ep.config.database}, ep.config.extraDatabases...) {
+			if err := ep.createDatabase(ep.config, database, ep.config.databaseOptions); err != nil {
+				return err
 			}
-
-			return err
 		}
 	}
 
 	if err := healthCheckDatabaseOrTimeout(ep.config); err != nil {
-		if stopErr := stopPostgres(ep); stopErr != nil {
-			return fmt.Errorf("unable to stop database casused by error %s", err)
+		return err
+	}
+
+	if err := createExtensions(ep.config); err != nil {
+		return err
+	}
+
+	if !reuseData {
+		if err := restoreDump(ep.config, ep.syncedLogger.file); err != nil {
+			return err
+		}
+
+		if err := runInitScripts(ep.config); err != nil {
+			return err
+		}
+
+		if err := createRoles(ep.config); err != nil {
+			return err
 		}
+	}
 
+	if err := runMigrator(ep.config); err != nil {
 		return err
 	}
 
-	return nil
+	return runAfterStartHooks(ep.config)
+}
+
+// removeStaleServer cleans up after a previous server in the data directory. A server left running by a process which
+// exited without stopping it, which its lifeline failed to stop or which was started by an older version, is stopped.
+func (ep *EmbeddedPostgres) removeStaleServer() error {
+	err := removeStalePostmasterPID(ep.config.dataPath)
+
+	var inUseErr *DataDirectoryInUseError
+	if errors.As(err, &inUseErr) && isOrphanedServer(ep.config.dataPath) {
+		return stopOrphanedServer(ep.config.dataPath, inUseErr.PID, ep.config.stopTimeout)
+	}
+
+	return err
+}
+
+// stopAfterError stops the Postgres process after a failure during Start, returning the error which caused it.
+func (ep *EmbeddedPostgres) stopAfterError(err error) error {
+	defer ep.stopServerLogForwarding()
+
+	ep.stopMonitor()
+
+	if stopErr := stopPostgres(ep); stopErr != nil {
+		return fmt.Errorf("unable to stop database casused by error %s", err)
+	}
+
+	removeOwnerPID(ep.config.dataPath)
+	ep.removeInMemoryData()
+	ep.started = false
+	ep.markExited(err)
+
+	return err
+}
+
+// RuntimePath returns the directory used for the extracted Postgres runtime. Unless configured with RuntimePath, a
+// directory named after the port is used within the cache directory, so that instances running on different ports
+// never share a runtime directory.
+func (ep *EmbeddedPostgres) RuntimePath() string {
+	if ep.config.runtimePath != "" {
+		return ep.config.runtimePath
+	}
+
+	cacheLocation, _ := ep.cacheLocator()
+
+	return filepath.Join(filepath.Dir(cacheLocation), "extracted", strconv.FormatUint(uint64(ep.config.port), 10))
+}
+
+// DataPath returns the directory used for the Postgres data directory. Unless configured with DataPath, the data
+// directory is placed within the runtime directory, or in memory when configured with DataInMemory and supported.
+func (ep *EmbeddedPostgres) DataPath() string {
+	if ep.config.dataPath != "" {
+		return ep.config.dataPath
+	}
+
+	if ep.config.dataInMemory {
+		if memoryPath := inMemoryDirectory(); memoryPath != "" {
+			return filepath.Join(memoryPath, "embedded-postgres-go", strconv.FormatUint(uint64(ep.config.port), 10), "data")
+		}
+	}
+
+	return filepath.Join(ep.RuntimePath(), "data")
+}
+
+// serverParameters returns the configured start parameters together with those required by other options.
+func (ep *EmbeddedPostgres) serverParameters() map[string]string {
+	parameters := serverLogParameters(ep.ServerLogPath())
+
+	if ep.config.ssl || ep.config.requireClientCertificates {
+		for name, value := range sslParameters(ep.config.requireClientCertificates) {
+			parameters[name] = value
+		}
+	}
+
+	// so that the passwords of roles created after initdb are stored as SCRAM verifiers too
+	if ep.config.authMethod == AuthMethodScramSHA256 {
+		parameters["password_encryption"] = string(AuthMethodScramSHA256)
+	}
+
+	if ep.config.walArchiving {
+		for name, value := range archiveParameters(runtime.GOOS, ep.WalArchivePath()) {
+			parameters[name] = value
+		}
+	}
+
+	// hot standby is off by default before Postgres 10, which would refuse all connections
+	if ep.config.standbyFrom != "" {
+		parameters["hot_standby"] = "on"
+	}
+
+	if ep.config.logAllStatements {
+		for name, value := range statementLogParameters() {
+			parameters[name] = value
+		}
+	}
+
+	for name, value := range ep.config.startParameters {
+		parameters[name] = value
+	}
+
+	if len(ep.config.sharedPreloadLibraries) > 0 {
+		parameters["shared_preload_libraries"] = mergePreloadLibraries(parameters["shared_preload_libraries"], ep.config.sharedPreloadLibraries)
+	}
+
+	return parameters
+}
+
+// removeInMemoryData frees the memory used by an in memory data directory once the server has stopped.
+func (ep *EmbeddedPostgres) removeInMemoryData() {
+	if ep.inMemoryData {
+		_ = os.RemoveAll(ep.config.dataPath)
+	}
+}
+
+// BinariesPath returns the directory the Postgres binaries are extracted to. Unless configured with BinariesPath, the
+// binaries are extracted once into a directory named after the binaries archive within the cache directory, which is
+// shared by every instance using the same binaries.
+func (ep *EmbeddedPostgres) BinariesPath() string {
+	if ep.config.binariesPath != "" {
+		return ep.config.binariesPath
+	}
+
+	return ep.sharedBinariesPath()
+}
+
+// BinaryPath returns the absolute path of one of the bundled binaries, such as pg_dump, pg_restore, psql or pg_isready,
+// so that callers can run the tools matching the version of the server rather than those installed on the host. The
+// binaries are only extracted by Start.
+func (ep *EmbeddedPostgres) BinaryPath(tool string) string {
+	binaryPath := filepath.Join(ep.BinariesPath(), "bin", tool)
+
+	if absolutePath, err := filepath.Abs(binaryPath); err == nil {
+		return absolutePath
+	}
+
+	return binaryPath
+}
+
+func (ep *EmbeddedPostgres) sharedBinariesPath() string {
+	cacheLocation, _ := ep.cacheLocator()
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(cacheLocation), "embedded-postgres-binaries-"), filepath.Ext(cacheLocation))
+
+	return filepath.Join(filepath.Dir(cacheLocation), "bin", name)
 }
 
 func (ep *EmbeddedPostgres) downloadAndExtractBinary(cacheExists bool, cacheLocation string) error {
-	// lock to prevent collisions with duplicate downloads
+	// lock to prevent collisions with duplicate downloads, both within this process and from other processes
 	mu.Lock()
 	defer mu.Unlock()
 
+	unlock, err := lockCache(cacheLocation)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	// another process may have downloaded the archive while waiting for the lock
+	if !cacheExists {
+		_, cacheExists = ep.cacheLocator()
+	}
+
+	if _, extracted := readBinariesMarker(ep.config.binariesPath, ep.config.version, cacheLocation); extracted && cacheExists {
+		return nil
+	}
+
+	// a configured binaries directory is used as is, whatever version of the binaries it contains
 	_, binDirErr := os.Stat(filepath.Join(ep.config.binariesPath, "bin"))
-	if os.IsNotExist(binDirErr) {
+	if os.IsNotExist(binDirErr) || ep.config.binariesPath == ep.sharedBinariesPath() {
 		if !cacheExists {
-			if err := ep.remoteFetchStrategy(); err != nil {
+			if err := ep.download(cacheLocation); err != nil {
 				return err
 			}
 		}
 
-		if err := decompressTarXz(defaultTarReader, cacheLocation, ep.config.binariesPath); err != nil {
+		extractStarted := time.Now()
+
+		if err := ep.extractCachedArchive(cacheExists, cacheLocation); err != nil {
 			return err
 		}
+
+		ep.emit(EventExtracted, extractStarted)
+
+		if err := writeBinariesMarker(ep.config.binariesPath, ep.config.version, cacheLocation); err != nil {
+			return fmt.Errorf("unable to record extracted binaries in %s: %w", ep.config.binariesPath, err)
+		}
 	}
 	return nil
 }
 
+// extractCachedArchive extracts the cached binaries archive. An archive which was already cached, rather than just
+// downloaded, and cannot be decoded is assumed to be corrupt, so it is removed and downloaded again. Other errors, such
+// as a full disk or missing permissions, are returned as they would not be resolved by downloading again.
+func (ep *EmbeddedPostgres) extractCachedArchive(cacheExists bool, cacheLocation string) error {
+	err := decompressTarXz(defaultTarReader, cacheLocation, ep.config.binariesPath)
+
+	var decodeErr *archiveDecodeError
+	if err == nil || !cacheExists || !errors.As(err, &decodeErr) {
+		return err
+	}
+
+	if removeErr := os.Remove(cacheLocation); removeErr != nil {
+		return fmt.Errorf("unable to remove corrupt cached archive %s: %v after error: %w", cacheLocation, removeErr, err)
+	}
+
+	if fetchErr := ep.download(cacheLocation); fetchErr != nil {
+		return fmt.Errorf("unable to download %s again after error: %v: %w", cacheLocation, err, fetchErr)
+	}
+
+	return decompressTarXz(defaultTarReader, cacheLocation, ep.config.binariesPath)
+}
+
 func (ep *EmbeddedPostgres) cleanDataDirectoryAndInit() error {
 	if err := os.RemoveAll(ep.config.dataPath); err != nil {
 		return fmt.Errorf("unable to clean up data directory %s with error: %s", ep.config.dataPath, err)
 	}
 
-	if err := ep.initDatabase(ep.config.binariesPath, ep.config.runtimePath, ep.config.dataPath, ep.config.username, ep.config.password, ep.config.locale, ep.syncedLogger.file); err != nil {
+	if ep.primary != nil {
+		return ep.createReplicaData()
+	}
+
+	if ep.config.standbyFrom != "" {
+		return ep.createStandbyData()
+	}
+
+	initStarted := time.Now()
+
+	// a cached data directory only matches the credentials it was created with, which generated credentials never repeat
+	if ep.config.cacheInitdb && !ep.config.generateUsername && !ep.config.generatePassword {
+		if err := ep.initFromCache(); err != nil {
+			return err
+		}
+	} else if err := ep.initDatabase(ep.config.binariesPath, ep.config.runtimePath, ep.config.dataPath, ep.config.initdbOptions(), ep.syncedLogger.file); err != nil {
 		return err
 	}
 
+	ep.emit(EventInitDbCompleted, initStarted)
+
 	return nil
 }
 
@@ -180,40 +517,198 @@ func (ep *EmbeddedPostgres) Stop() error {
 		return errors.New("server has not been started")
 	}
 
+	stopStarted := time.Now()
+
+	// after stopping, so that the shutdown is logged too
+	defer ep.stopServerLogForwarding()
+
+	ep.stopMonitor()
+
+	closeErr := ep.closeOnStop()
+
+	if err := ep.Err(); err != nil {
+		ep.started = false
+		_ = ep.syncedLogger.flush()
+
+		return err
+	}
+
+	if ep.paused {
+		if err := ep.Resume(); err != nil {
+			return err
+		}
+	}
+
+	leakErr := ep.checkConnectionLeaks()
+
+	if ep.config.terminateConnectionsOnStop {
+		ep.terminateConnections()
+	}
+
 	if err := stopPostgres(ep); err != nil {
 		return err
 	}
 
+	removeOwnerPID(ep.config.dataPath)
+	ep.removeInMemoryData()
 	ep.started = false
+	ep.markExited(nil)
+	ep.emit(EventStopped, stopStarted)
 
 	if err := ep.syncedLogger.flush(); err != nil {
 		return err
 	}
 
+	if leakErr != nil {
+		return leakErr
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("unable to close connections: %w", closeErr)
+	}
+
 	return nil
 }
 
-func startPostgres(ep *EmbeddedPostgres) error {
+// ReloadConfig asks the running server to reload its configuration files with pg_ctl reload, so that parameters edited
+// in postgresql.conf or set with ALTER SYSTEM take effect without a restart. Parameters which require a restart are not
+// applied by a reload.
+func (ep *EmbeddedPostgres) ReloadConfig() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
 	postgresBinary := filepath.Join(ep.config.binariesPath, "bin/pg_ctl")
-	postgresProcess := exec.Command(postgresBinary, "start", "-w",
+	postgresProcess := exec.Command(postgresBinary, "reload", "-D", ep.config.dataPath)
+	postgresProcess.Stdout = ep.syncedLogger.file
+	postgresProcess.Stderr = ep.syncedLogger.file
+
+	if err := postgresProcess.Run(); err != nil {
+		return fmt.Errorf("unable to reload configuration using %s: %w", postgresProcess.String(), err)
+	}
+
+	return nil
+}
+
+// terminateConnections disconnects lingering client sessions before stopping, logging each terminated session. Stop
+// continues regardless of failures, which are also logged.
+func (ep *EmbeddedPostgres) terminateConnections() {
+	terminated, err := terminateAllConnections(ep.config)
+	for _, connection := range terminated {
+		_, _ = fmt.Fprintf(ep.syncedLogger.file, "embedded-postgres: terminated connection %s\n", connection)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(ep.syncedLogger.file, "embedded-postgres: unable to terminate connections: %s\n", err)
+	}
+}
+
+// checkConnectionLeaks writes the client sessions still connected before stopping to the logger, returning an error
+// naming them when configured to fail. Failures to list the sessions are logged and do not fail Stop.
+func (ep *EmbeddedPostgres) checkConnectionLeaks() error {
+	if ep.config.connectionLeakCheck == LeakCheckOff {
+		return nil
+	}
+
+	connections, err := openConnections(ep.config)
+	if err != nil {
+		_, _ = fmt.Fprintf(ep.syncedLogger.file, "embedded-postgres: unable to check for open connections: %s\n", err)
+		return nil
+	}
+
+	for _, connection := range connections {
+		_, _ = fmt.Fprintf(ep.syncedLogger.file, "embedded-postgres: connection still open at stop %s\n", connection)
+	}
+
+	if len(connections) > 0 && ep.config.connectionLeakCheck == LeakCheckFail {
+		return fmt.Errorf("%d connections were still open at stop, close every *sql.DB and connection before stopping:\n%s",
+			len(connections), strings.Join(connections, "\n"))
+	}
+
+	return nil
+}
+
+// startPostgres starts the server with pg_ctl, which waits until the server accepts connections or the start timeout
+// has passed. Using pg_ctl rather than running postgres directly gives the same readiness and shutdown behaviour on
+// every platform, including Windows where postgres cannot be signalled.
+func startPostgres(ep *EmbeddedPostgres) error {
+	postgresProcess := exec.Command(ep.pgCtlPath(), "start", "-w",
+		"-t", pgCtlTimeout(ep.config.startTimeout),
 		"-D", ep.config.dataPath,
 		"-o", fmt.Sprintf(`"-p %d"`, ep.config.port))
 	postgresProcess.Stdout = ep.syncedLogger.file
 	postgresProcess.Stderr = ep.syncedLogger.file
 
-	if err := postgresProcess.Run(); err != nil {
+	ep.config.prepareCommand(postgresProcess)
+
+	err := postgresProcess.Start()
+	if err == nil {
+		// a server which cannot be tied to this process is still cleaned up by the next start as an orphan
+		if ep.lifeline != nil {
+			_ = ep.lifeline.adopt(postgresProcess.Process.Pid)
+		}
+
+		err = postgresProcess.Wait()
+	}
+
+	if err != nil {
 		_ = ep.syncedLogger.flush()
 		logContent, _ := readLogsOrTimeout(ep.syncedLogger.file)
 
-		return fmt.Errorf("could not start postgres using %s:\n%s", postgresProcess.String(), string(logContent))
+		return fmt.Errorf("could not start postgres using %s:\n%s%s%s", postgresProcess.String(), string(logContent), serverLogTail(ep.ServerLogPath()), rootUserHint(string(logContent)))
 	}
 
 	return nil
 }
 
+func (ep *EmbeddedPostgres) pgCtlPath() string {
+	return filepath.Join(ep.config.binariesPath, "bin/pg_ctl")
+}
+
+// releaseLifeline stops tying the server to the lifetime of this process, once it has stopped or been detached.
+func (ep *EmbeddedPostgres) releaseLifeline() {
+	if ep.lifeline != nil {
+		ep.lifeline.release()
+		ep.lifeline = nil
+	}
+}
+
+// stopPostgres stops the server with pg_ctl using the configured shutdown mode, escalating to less graceful modes when
+// the server has not stopped within the stop timeout, and finally killing the server process.
 func stopPostgres(ep *EmbeddedPostgres) error {
+	var err error
+
+	for _, mode := range stopModes(ep.config.stopMode) {
+		if err = stopPostgresWithMode(ep, mode); err == nil {
+			return nil
+		}
+	}
+
+	// only kill a server which pg_ctl still reports as running, as the process ID of a server which has already exited
+	// may have been reused
+	if !postgresRunning(ep) {
+		return err
+	}
+
+	if killErr := killPostgres(ep.config.dataPath); killErr != nil {
+		return fmt.Errorf("unable to stop postgres: %w", err)
+	}
+
+	return nil
+}
+
+func postgresRunning(ep *EmbeddedPostgres) bool {
+	postgresBinary := filepath.Join(ep.config.binariesPath, "bin/pg_ctl")
+	postgresProcess := exec.Command(postgresBinary, "status", "-D", ep.config.dataPath)
+
+	return postgresProcess.Run() == nil
+}
+
+func stopPostgresWithMode(ep *EmbeddedPostgres, mode StopMode) error {
 	postgresBinary := filepath.Join(ep.config.binariesPath, "bin/pg_ctl")
 	postgresProcess := exec.Command(postgresBinary, "stop", "-w",
+		"-t", pgCtlTimeout(ep.config.stopTimeout),
+		"-m", string(mode),
 		"-D", ep.config.dataPath)
 	postgresProcess.Stderr = ep.syncedLogger.file
 	postgresProcess.Stdout = ep.syncedLogger.file
@@ -225,6 +720,28 @@ func stopPostgres(ep *EmbeddedPostgres) error {
 	return nil
 }
 
+// stopModes returns the shutdown modes to try in order, starting with mode and escalating to immediate.
+func stopModes(mode StopMode) []StopMode {
+	switch mode {
+	case StopModeSmart:
+		return []StopMode{StopModeSmart, StopModeFast, StopModeImmediate}
+	case StopModeImmediate:
+		return []StopMode{StopModeImmediate}
+	default:
+		return []StopMode{StopModeFast, StopModeImmediate}
+	}
+}
+
+// pgCtlTimeout converts a timeout to the whole number of seconds accepted by pg_ctl, rounding up to at least a second.
+func pgCtlTimeout(timeout time.Duration) string {
+	seconds := (timeout + time.Second - 1) / time.Second
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.FormatInt(int64(seconds), 10)
+}
+
 func ensurePortAvailable(port uint32) error {
 	conn, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
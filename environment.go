@@ -0,0 +1,52 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	environmentVersion               = "EMBEDDED_POSTGRES_VERSION"
+	environmentCachePath             = "EMBEDDED_POSTGRES_CACHE_PATH"
+	environmentBinaryRepositoryURL   = "EMBEDDED_POSTGRES_BINARY_REPO_URL"
+	environmentBinaryRepositoryToken = "EMBEDDED_POSTGRES_BINARY_REPO_TOKEN"
+)
+
+// applyEnvironmentOverrides replaces configuration defaults with values from the environment so that CI systems can
+// steer the library without code changes.
+func applyEnvironmentOverrides(c Config) Config {
+	if version, ok := os.LookupEnv(environmentVersion); ok && version != "" {
+		c.version = ParseVersion(version)
+	}
+
+	if cachePath, ok := os.LookupEnv(environmentCachePath); ok && cachePath != "" {
+		c.cachePath = cachePath
+	}
+
+	if binaryRepositoryURL, ok := os.LookupEnv(environmentBinaryRepositoryURL); ok && binaryRepositoryURL != "" {
+		c.binaryRepositoryURL = strings.TrimSuffix(binaryRepositoryURL, "/")
+	}
+
+	if token, ok := os.LookupEnv(environmentBinaryRepositoryToken); ok && token != "" {
+		c = c.BinaryRepositoryBearerToken(token)
+	}
+
+	return c
+}
+
+// ParseVersion resolves a major version such as "14" to the matching predefined version, any other value is used as is.
+func ParseVersion(version string) PostgresVersion {
+	if !strings.Contains(version, ".") {
+		for _, predefined := range []PostgresVersion{V15, V14, V13, V12, V11, V10} {
+			if strings.HasPrefix(string(predefined), version+".") {
+				return predefined
+			}
+		}
+
+		if version == "9" {
+			return V9
+		}
+	}
+
+	return PostgresVersion(version)
+}
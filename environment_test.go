@@ -0,0 +1,35 @@
+package embeddedpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DefaultConfig_EnvironmentOverrides(t *testing.T) {
+	t.Setenv("EMBEDDED_POSTGRES_VERSION", "13")
+	t.Setenv("EMBEDDED_POSTGRES_CACHE_PATH", "/ci/cache")
+	t.Setenv("EMBEDDED_POSTGRES_BINARY_REPO_URL", "https://repo.local/maven2/")
+	t.Setenv("EMBEDDED_POSTGRES_BINARY_REPO_TOKEN", "s3cr3t")
+
+	config := DefaultConfig()
+
+	assert.Equal(t, V13, config.version)
+	assert.Equal(t, "/ci/cache", config.cachePath)
+	assert.Equal(t, "https://repo.local/maven2", config.binaryRepositoryURL)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer s3cr3t"}, config.binaryRepositoryHeaders)
+}
+
+func Test_DefaultConfig_EnvironmentOverridesReplacedByBuilders(t *testing.T) {
+	t.Setenv("EMBEDDED_POSTGRES_VERSION", "13.2.0")
+
+	assert.Equal(t, PostgresVersion("13.2.0"), DefaultConfig().version)
+	assert.Equal(t, V12, DefaultConfig().Version(V12).version)
+}
+
+func Test_ParseVersion(t *testing.T) {
+	assert.Equal(t, V15, ParseVersion("15"))
+	assert.Equal(t, V9, ParseVersion("9"))
+	assert.Equal(t, PostgresVersion("14.1.0"), ParseVersion("14.1.0"))
+	assert.Equal(t, PostgresVersion("42"), ParseVersion("42"))
+}
@@ -0,0 +1,71 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// createExtensions creates the configured extensions in the database, first checking that the bundled binaries include
+// each of them.
+func createExtensions(config Config) error {
+	if len(config.extensions) == 0 {
+		return nil
+	}
+
+	return withDatabaseConnection(config, config.database, func(db *sql.DB) error {
+		missing, err := unavailableExtensions(db, config.extensions)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			return fmt.Errorf("extensions %s are not available in the Postgres %s binaries at %s",
+				strings.Join(missing, ", "), config.version, config.binariesPath)
+		}
+
+		for _, extension := range config.extensions {
+			if _, err := db.Exec(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", quoteIdentifier(extension))); err != nil {
+				return fmt.Errorf("unable to create extension %s: %w", extension, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func unavailableExtensions(db *sql.DB, extensions []string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM pg_available_extensions WHERE name = ANY($1::text[])", textArray(extensions))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list available extensions: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	available := map[string]bool{}
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("unable to list available extensions: %w", err)
+		}
+
+		available[name] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to list available extensions: %w", err)
+	}
+
+	var missing []string
+
+	for _, extension := range extensions {
+		if !available[extension] {
+			missing = append(missing, extension)
+		}
+	}
+
+	return missing, nil
+}
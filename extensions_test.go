@@ -0,0 +1,67 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Extensions(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9861).
+		Extensions("uuid-ossp", "pgcrypto", "hstore"))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9861 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM pg_extension WHERE extname IN ('uuid-ossp', 'pgcrypto', 'hstore')").Scan(&count))
+	assert.Equal(t, 3, count)
+	require.NoError(t, db.Close())
+}
+
+func Test_Extensions_ErrorWhenUnavailable(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9862).
+		Extensions("pgcrypto", "not_an_extension"))
+
+	err = database.Start()
+
+	assert.EqualError(t, err, "extensions not_an_extension are not available in the Postgres 15.3.0 binaries at "+tempDir)
+	assert.False(t, database.started)
+}
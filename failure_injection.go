@@ -0,0 +1,139 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kill kills the Postgres server processes with SIGKILL, as if the machine running the database failed, so that
+// reconnect and retry logic can be tested against a genuinely dying database. The exit is reported by Err and Wait
+// like any other unexpected exit, and Stop returns the error describing it, unless the server is restarted as configured
+// with RestartOnCrash.
+func (ep *EmbeddedPostgres) Kill() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	pid, err := readPostmasterPID(ep.config.dataPath)
+	if err != nil {
+		return fmt.Errorf("unable to find postgres process: %w", err)
+	}
+
+	if err := killProcessGroup(pid); err != nil {
+		return fmt.Errorf("unable to kill postgres process %d: %w", pid, err)
+	}
+
+	// wait for the exit to be recorded, so that it is reported as soon as Kill returns
+	if ep.config.maxRestarts == 0 {
+		ep.exitMu.Lock()
+		exited := ep.exited
+		ep.exitMu.Unlock()
+
+		select {
+		case <-exited:
+		case <-time.After(ep.config.stopTimeout):
+		}
+	}
+
+	return nil
+}
+
+// Crash kills a single server process, which makes Postgres terminate every connection and run crash recovery, as after
+// a backend has been killed for running out of memory. Crash returns once the server accepts connections again.
+func (ep *EmbeddedPostgres) Crash() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	return withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		ctx, cancel := context.WithTimeout(context.Background(), ep.config.startTimeout)
+		defer cancel()
+
+		victim, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to connect to postgres: %w", err)
+		}
+
+		defer func() {
+			_ = victim.Close()
+		}()
+
+		// terminated together with every other connection once crash recovery starts
+		sentinel, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to connect to postgres: %w", err)
+		}
+
+		defer func() {
+			_ = sentinel.Close()
+		}()
+
+		var pid int
+		if err := victim.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+			return fmt.Errorf("unable to find postgres server process: %w", err)
+		}
+
+		if err := killProcess(pid); err != nil {
+			return fmt.Errorf("unable to kill postgres server process %d: %w", pid, err)
+		}
+
+		for {
+			if _, err := sentinel.ExecContext(ctx, "SELECT 1"); err != nil {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("postgres did not start crash recovery: %w", ctx.Err())
+			case <-time.After(ep.config.healthCheckInterval):
+			}
+		}
+
+		return healthCheckDatabaseOrTimeout(ep.config)
+	})
+}
+
+// Pause suspends the Postgres server processes with SIGSTOP, so that connections hang as if the database stopped
+// responding, for example to test timeouts. Resume continues them, and Stop resumes a paused server before stopping it.
+// Pausing is not supported on Windows.
+func (ep *EmbeddedPostgres) Pause() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	pid, err := readPostmasterPID(ep.config.dataPath)
+	if err != nil {
+		return fmt.Errorf("unable to find postgres process: %w", err)
+	}
+
+	if err := pauseProcessGroup(pid); err != nil {
+		return fmt.Errorf("unable to pause postgres process %d: %w", pid, err)
+	}
+
+	ep.paused = true
+
+	return nil
+}
+
+// Resume continues the Postgres server processes suspended by Pause.
+func (ep *EmbeddedPostgres) Resume() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	pid, err := readPostmasterPID(ep.config.dataPath)
+	if err != nil {
+		return fmt.Errorf("unable to find postgres process: %w", err)
+	}
+
+	if err := resumeProcessGroup(pid); err != nil {
+		return fmt.Errorf("unable to resume postgres process %d: %w", pid, err)
+	}
+
+	ep.paused = false
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Kill(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9883))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	require.NoError(t, database.Kill())
+
+	require.Error(t, database.Err())
+	assert.Contains(t, database.Err().Error(), "exited unexpectedly")
+	assert.Equal(t, database.Err(), database.Stop())
+}
+
+func Test_Crash(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9884))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9884 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, database.Crash())
+
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	assert.Error(t, err)
+	require.NoError(t, conn.Close())
+
+	_, err = db.Exec("SELECT 1")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Err())
+}
+
+func Test_Pause(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9885))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9885 user=postgres password=postgres dbname=postgres sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	require.NoError(t, database.Pause())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "SELECT 1")
+	assert.Error(t, err)
+
+	require.NoError(t, database.Resume())
+
+	_, err = db.Exec("SELECT 1")
+	assert.NoError(t, err)
+}
+
+func Test_FailureInjection_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase()
+
+	assert.EqualError(t, database.Kill(), "server has not been started")
+	assert.EqualError(t, database.Crash(), "server has not been started")
+	assert.EqualError(t, database.Pause(), "server has not been started")
+	assert.EqualError(t, database.Resume(), "server has not been started")
+}
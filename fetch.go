@@ -0,0 +1,58 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"time"
+)
+
+// Fetch downloads the binaries archive of the configured version into the cache, unless it is already cached, without
+// extracting it or starting a server, so that CI caches and Docker images can be warmed in a separate step from the
+// tests. The archive for another platform is fetched by configuring its Platform. It returns the location of the
+// cached archive.
+func (ep *EmbeddedPostgres) Fetch() (string, error) {
+	cacheLocation, cacheExists := ep.cacheLocator()
+	if cacheExists {
+		return cacheLocation, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	unlock, err := lockCache(cacheLocation)
+	if err != nil {
+		return "", err
+	}
+
+	defer unlock()
+
+	// another process may have downloaded the archive while waiting for the lock
+	if _, cacheExists := ep.cacheLocator(); cacheExists {
+		return cacheLocation, nil
+	}
+
+	if err := ep.download(cacheLocation); err != nil {
+		return "", err
+	}
+
+	return cacheLocation, nil
+}
+
+// download fetches the binaries archive into the cache location, reporting the download as lifecycle events. The cache
+// must be locked.
+func (ep *EmbeddedPostgres) download(cacheLocation string) error {
+	downloadStarted := time.Now()
+	ep.emit(EventDownloadStarted, time.Time{})
+
+	if err := ep.remoteFetchStrategy(); err != nil {
+		return err
+	}
+
+	event := LifecycleEvent{Type: EventDownloadFinished}
+	if info, err := os.Stat(cacheLocation); err == nil {
+		event.Bytes = info.Size()
+	}
+
+	ep.emitEvent(event, downloadStarted)
+
+	return nil
+}
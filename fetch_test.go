@@ -0,0 +1,53 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Fetch(t *testing.T) {
+	cacheDirectory := t.TempDir()
+	var events []LifecycleEventType
+
+	database := NewDatabase(DefaultConfig().
+		CachePath(cacheDirectory).
+		Platform("darwin", "arm64v8").
+		Version("15.4.0").
+		OnLifecycleEvent(func(event LifecycleEvent) {
+			events = append(events, event.Type)
+		}))
+
+	downloads := 0
+	database.remoteFetchStrategy = func() error {
+		downloads++
+		return os.WriteFile(filepath.Join(cacheDirectory, "embedded-postgres-binaries-darwin-arm64v8-15.4.0.txz"), []byte("archive"), 0600)
+	}
+
+	location, err := database.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDirectory, "embedded-postgres-binaries-darwin-arm64v8-15.4.0.txz"), location)
+	assert.FileExists(t, location)
+	assert.Equal(t, []LifecycleEventType{EventDownloadStarted, EventDownloadFinished}, events)
+
+	_, err = database.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, 1, downloads, "cached archives are not downloaded again")
+
+	assert.NoDirExists(t, filepath.Join(cacheDirectory, "bin"))
+}
+
+func Test_Fetch_Error(t *testing.T) {
+	database := NewDatabase(DefaultConfig().CachePath(t.TempDir()))
+	database.remoteFetchStrategy = func() error {
+		return errors.New("no version found matching 15.3.0")
+	}
+
+	_, err := database.Fetch()
+
+	assert.EqualError(t, err, "no version found matching 15.3.0")
+}
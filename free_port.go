@@ -0,0 +1,20 @@
+package embeddedpostgres
+
+import "net"
+
+// FreePort returns a TCP port which is free on localhost, for a server to be configured with. The port is only known
+// to be free when FreePort returns, so another process may still take it before the server binds it.
+func FreePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	if err := listener.Close(); err != nil {
+		return 0, err
+	}
+
+	return uint32(port), nil
+}
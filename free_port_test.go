@@ -0,0 +1,20 @@
+package embeddedpostgres
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FreePort(t *testing.T) {
+	port, err := FreePort()
+	require.NoError(t, err)
+	assert.NotZero(t, port)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(int(port))))
+	require.NoError(t, err)
+	assert.NoError(t, listener.Close())
+}
@@ -0,0 +1,61 @@
+// Package golangmigrate adapts golang-migrate to the embeddedpostgres.Migrator interface, so that migrations are
+// applied as part of Start.
+//
+//	postgres := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+//		Migrator(golangmigrate.New("file://migrations")))
+package golangmigrate
+
+import (
+	"errors"
+	"fmt"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // registers the postgres database driver
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file" // registers the file source driver
+)
+
+// New returns a Migrator applying all up migrations found at sourceURL, such as "file://migrations".
+func New(sourceURL string) embeddedpostgres.Migrator {
+	return embeddedpostgres.MigratorFunc(func(dsn string) error {
+		m, err := migrate.New(sourceURL, dsn)
+		if err != nil {
+			return fmt.Errorf("unable to create migrate instance for %s: %w", sourceURL, err)
+		}
+
+		return up(m)
+	})
+}
+
+// NewWithSourceInstance returns a Migrator applying all up migrations from an existing source driver, such as an iofs
+// driver reading migrations embedded with go:embed.
+func NewWithSourceInstance(sourceName string, sourceInstance source.Driver) embeddedpostgres.Migrator {
+	return embeddedpostgres.MigratorFunc(func(dsn string) error {
+		m, err := migrate.NewWithSourceInstance(sourceName, sourceInstance, dsn)
+		if err != nil {
+			return fmt.Errorf("unable to create migrate instance for %s: %w", sourceName, err)
+		}
+
+		return up(m)
+	})
+}
+
+func up(m *migrate.Migrate) (err error) {
+	defer func() {
+		sourceErr, databaseErr := m.Close()
+		if err == nil && sourceErr != nil {
+			err = sourceErr
+		}
+
+		if err == nil && databaseErr != nil {
+			err = databaseErr
+		}
+	}()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("unable to apply migrations: %w", err)
+	}
+
+	return nil
+}
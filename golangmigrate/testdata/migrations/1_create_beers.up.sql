@@ -0,0 +1 @@
+CREATE TABLE beers(name text);
@@ -0,0 +1 @@
+INSERT INTO beers VALUES ('Punk IPA');
@@ -0,0 +1,132 @@
+package embeddedpostgres
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// initdbCachePath returns the directory holding the cached result of initdb for the configured version, locale,
+// credentials, authentication method and data checksums.
+func (ep *EmbeddedPostgres) initdbCachePath() string {
+	cacheLocation, _ := ep.cacheLocator()
+
+	key := sha256.Sum256([]byte(strings.Join([]string{
+		string(ep.config.version),
+		ep.config.locale,
+		ep.config.username,
+		ep.config.password,
+		string(ep.config.authMethod),
+		strconv.FormatBool(ep.config.dataChecksums),
+	}, "\x00")))
+
+	return filepath.Join(filepath.Dir(cacheLocation), "initdb", hex.EncodeToString(key[:8]))
+}
+
+// initFromCache initializes the data directory by copying the cached result of an earlier initdb, running initdb and
+// caching its result when no cached copy exists yet.
+func (ep *EmbeddedPostgres) initFromCache() error {
+	cachePath := ep.initdbCachePath()
+
+	if _, err := os.Stat(filepath.Join(cachePath, "PG_VERSION")); err == nil {
+		if err := copyDirectory(cachePath, ep.config.dataPath); err != nil {
+			return fmt.Errorf("unable to copy cached data directory %s: %w", cachePath, err)
+		}
+
+		return nil
+	}
+
+	if err := ep.initDatabase(ep.config.binariesPath, ep.config.runtimePath, ep.config.dataPath, ep.config.initdbOptions(), ep.syncedLogger.file); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create initdb cache directory: %w", err)
+	}
+
+	// copy to a temporary directory first so that concurrent starts never see a partially copied cache
+	tempPath, err := os.MkdirTemp(filepath.Dir(cachePath), "temp_")
+	if err != nil {
+		return fmt.Errorf("unable to create initdb cache directory: %w", err)
+	}
+
+	defer func() {
+		_ = os.RemoveAll(tempPath)
+	}()
+
+	if err := copyDirectory(ep.config.dataPath, tempPath); err != nil {
+		return fmt.Errorf("unable to cache data directory: %w", err)
+	}
+
+	// another process caching the same data directory first is not an error
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		if _, statErr := os.Stat(cachePath); statErr != nil {
+			return fmt.Errorf("unable to cache data directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copyDirectory recursively copies the files of src into dst, preserving their permissions.
+func copyDirectory(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, relativePath)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+
+			return os.Chmod(target, info.Mode().Perm())
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) (err error) {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = source.Close()
+	}()
+
+	destination, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := destination.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = io.Copy(destination, source)
+
+	return err
+}
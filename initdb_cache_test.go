@@ -0,0 +1,61 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CacheInitdb(t *testing.T) {
+	tempDir := t.TempDir()
+
+	initCalls := 0
+	newDatabase := func(dataPath string) *EmbeddedPostgres {
+		database := NewDatabase(DefaultConfig().
+			CachePath(tempDir).
+			DataPath(dataPath).
+			CacheInitdb(true))
+		database.syncedLogger = &syncedLogger{}
+		database.config.runtimePath = tempDir
+		database.config.dataPath = dataPath
+		database.initDatabase = func(binaryExtractLocation, runtimePath, pgDataDir string, options initdbOptions, logger *os.File) error {
+			initCalls++
+			require.NoError(t, os.MkdirAll(filepath.Join(pgDataDir, "base"), 0700))
+			return os.WriteFile(filepath.Join(pgDataDir, "PG_VERSION"), []byte("15\n"), 0600)
+		}
+
+		return database
+	}
+
+	first := filepath.Join(tempDir, "first")
+	second := filepath.Join(tempDir, "second")
+
+	require.NoError(t, newDatabase(first).cleanDataDirectoryAndInit())
+	require.NoError(t, newDatabase(second).cleanDataDirectoryAndInit())
+
+	assert.Equal(t, 1, initCalls)
+	assert.FileExists(t, filepath.Join(second, "PG_VERSION"))
+	assert.DirExists(t, filepath.Join(second, "base"))
+
+	info, err := os.Stat(filepath.Join(second, "base"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func Test_initdbCachePath(t *testing.T) {
+	config := DefaultConfig().CachePath("/cache")
+
+	path := NewDatabase(config).initdbCachePath()
+	otherUser := NewDatabase(config.Username("beer")).initdbCachePath()
+	otherVersion := NewDatabase(config.Version(V14)).initdbCachePath()
+	withChecksums := NewDatabase(config.DataChecksums(true)).initdbCachePath()
+
+	assert.Equal(t, filepath.Join("/cache", "initdb"), filepath.Dir(path))
+	assert.NotEqual(t, path, otherUser)
+	assert.NotEqual(t, path, otherVersion)
+	assert.NotEqual(t, path, withChecksums)
+	assert.Equal(t, path, NewDatabase(config).initdbCachePath())
+}
@@ -0,0 +1,129 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// AfterStartHook is called with a connection to the configured database once the server has started.
+type AfterStartHook func(db *sql.DB) error
+
+// Migrator applies schema migrations to the database identified by dsn, a connection URL. Migrations are applied on
+// every start, so implementations should skip migrations which have already been applied.
+type Migrator interface {
+	Migrate(dsn string) error
+}
+
+// MigratorFunc adapts an ordinary function to the Migrator interface.
+type MigratorFunc func(dsn string) error
+
+// Migrate calls f(dsn).
+func (f MigratorFunc) Migrate(dsn string) error {
+	return f(dsn)
+}
+
+type initScript struct {
+	fsys fs.FS
+	glob string
+}
+
+// runInitScripts executes the configured SQL files against the database, in lexical order for each InitScripts call.
+func runInitScripts(config Config) error {
+	if len(config.initScripts) == 0 {
+		return nil
+	}
+
+	return withDatabaseConnection(config, config.database, func(db *sql.DB) error {
+		for _, script := range config.initScripts {
+			files, err := initScriptFiles(script)
+			if err != nil {
+				return err
+			}
+
+			for _, file := range files {
+				content, err := fs.ReadFile(script.fsys, file)
+				if err != nil {
+					return fmt.Errorf("unable to read init script %s: %w", file, err)
+				}
+
+				if _, err := db.Exec(string(content)); err != nil {
+					return fmt.Errorf("unable to execute init script %s: %w", file, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func initScriptFiles(script initScript) ([]string, error) {
+	files, err := fs.Glob(script.fsys, script.glob)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find init scripts matching %s: %w", script.glob, err)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func runMigrator(config Config) error {
+	if config.migrator == nil {
+		return nil
+	}
+
+	if err := config.migrator.Migrate(config.connectionDSN()); err != nil {
+		return fmt.Errorf("unable to migrate database %s: %w", config.database, err)
+	}
+
+	return nil
+}
+
+// afterStartStep is either a statement of AfterStartSQL, which is run on the internal connection of the library, or a
+// hook of AfterStart, which is called with a connection of the registered SQLDriver.
+type afterStartStep struct {
+	statement string
+	hook      AfterStartHook
+}
+
+// runAfterStartHooks runs the configured AfterStartSQL statements and calls the AfterStart hooks in order. The
+// connection the hooks are called with is only opened when there are any, so that statements need no SQLDriver.
+func runAfterStartHooks(config Config) error {
+	if len(config.afterStart) == 0 {
+		return nil
+	}
+
+	return withDatabaseConnection(config, config.database, func(internal *sql.DB) (err error) {
+		var db *sql.DB
+
+		defer func() {
+			if db != nil {
+				err = connectionClose(db, err)
+			}
+		}()
+
+		for i, step := range config.afterStart {
+			if step.hook == nil {
+				if _, err := internal.Exec(step.statement); err != nil {
+					return fmt.Errorf("after start hook %d failed: unable to execute %q: %w", i, step.statement, err)
+				}
+
+				continue
+			}
+
+			if db == nil {
+				if db, err = OpenDB(config.connectionDSN()); err != nil {
+					return fmt.Errorf("after start hook %d failed: %w", i, err)
+				}
+			}
+
+			if err := step.hook(db); err != nil {
+				return fmt.Errorf("after start hook %d failed: %w", i, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,207 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// InstancePool pre-warms a number of independent Postgres servers, each with its own port and runtime directory, and
+// hands them out to callers, so that many integration test packages can run in parallel without paying a cold start
+// each. Instances are returned to the pool with Release and keep their state, combine with CreateTestDatabase or
+// ResetFromTemplate where isolation is required.
+type InstancePool struct {
+	config    Config
+	size      int
+	mu        sync.Mutex
+	instances []*EmbeddedPostgres
+	available chan *EmbeddedPostgres
+	closed    chan struct{}
+	// acquired tells for each instance of the pool whether it is handed out
+	acquired map[*EmbeddedPostgres]bool
+	tempDir  string
+}
+
+// NewInstancePool creates a pool of size instances from the configuration, following the same conventions as
+// NewDatabase. The port and runtime path of the configuration are replaced for each instance.
+func NewInstancePool(size int, config ...Config) *InstancePool {
+	poolConfig := DefaultConfig()
+	if len(config) > 0 {
+		poolConfig = config[0]
+	}
+
+	return &InstancePool{
+		config: poolConfig,
+		size:   size,
+	}
+}
+
+// Start starts all instances of the pool concurrently. If any instance fails to start, the instances already started
+// are stopped again.
+func (p *InstancePool) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.available != nil {
+		return errors.New("instance pool is already started")
+	}
+
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_pool")
+	if err != nil {
+		return fmt.Errorf("unable to create instance pool directory: %w", err)
+	}
+
+	instances := make([]*EmbeddedPostgres, p.size)
+	errs := make([]error, p.size)
+
+	for i := range instances {
+		port, err := FreePort()
+		if err != nil {
+			_ = os.RemoveAll(tempDir)
+			return fmt.Errorf("unable to find a free port for instance %d: %w", i, err)
+		}
+
+		instances[i] = NewDatabase(p.config.
+			Port(port).
+			RuntimePath(filepath.Join(tempDir, fmt.Sprintf("instance_%d", i))))
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range instances {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = instances[i].Start()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			stopInstances(instances)
+			_ = os.RemoveAll(tempDir)
+
+			return fmt.Errorf("unable to start instance %d of pool: %w", i, err)
+		}
+	}
+
+	p.instances = instances
+	p.tempDir = tempDir
+	p.available = make(chan *EmbeddedPostgres, p.size)
+	p.closed = make(chan struct{})
+	p.acquired = make(map[*EmbeddedPostgres]bool, p.size)
+
+	for _, instance := range instances {
+		p.available <- instance
+		p.acquired[instance] = false
+	}
+
+	return nil
+}
+
+// Acquire hands out an instance from the pool, blocking until one is released or the context is done.
+func (p *InstancePool) Acquire(ctx context.Context) (*EmbeddedPostgres, error) {
+	p.mu.Lock()
+	available, closed := p.available, p.closed
+	p.mu.Unlock()
+
+	if available == nil {
+		return nil, errors.New("instance pool has not been started")
+	}
+
+	select {
+	case instance := <-available:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if p.acquired == nil {
+			return nil, errors.New("instance pool has been closed")
+		}
+
+		p.acquired[instance] = true
+
+		return instance, nil
+	case <-closed:
+		return nil, errors.New("instance pool has been closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release returns an instance acquired from the pool so that it can be handed out again. Instances which were not
+// acquired from the pool, or which were already released, are rejected.
+func (p *InstancePool) Release(instance *EmbeddedPostgres) error {
+	p.mu.Lock()
+
+	if p.available == nil {
+		p.mu.Unlock()
+		return errors.New("instance pool has not been started")
+	}
+
+	acquired, ok := p.acquired[instance]
+	if !ok {
+		p.mu.Unlock()
+		return errors.New("instance does not belong to the pool")
+	}
+
+	if !acquired {
+		p.mu.Unlock()
+		return errors.New("instance has already been released")
+	}
+
+	p.acquired[instance] = false
+	available := p.available
+	p.mu.Unlock()
+
+	// never blocks, as the channel has room for every instance of the pool and each is released once
+	available <- instance
+
+	return nil
+}
+
+// Close stops all instances of the pool and removes their directories.
+func (p *InstancePool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.available == nil {
+		return errors.New("instance pool has not been started")
+	}
+
+	// the available channel is left open, so that releases racing with Close cannot send on a closed channel
+	close(p.closed)
+	p.available = nil
+	p.closed = nil
+	p.acquired = nil
+
+	err := stopInstances(p.instances)
+	p.instances = nil
+
+	if removeErr := os.RemoveAll(p.tempDir); removeErr != nil && err == nil {
+		err = fmt.Errorf("unable to remove instance pool directory %s with error: %s", p.tempDir, removeErr)
+	}
+
+	return err
+}
+
+func stopInstances(instances []*EmbeddedPostgres) error {
+	var err error
+
+	for _, instance := range instances {
+		if instance == nil || !instance.started {
+			continue
+		}
+
+		if stopErr := instance.Stop(); stopErr != nil && err == nil {
+			err = stopErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InstancePool(t *testing.T) {
+	pool := NewInstancePool(2, DefaultConfig().Logger(nil))
+	require.NoError(t, pool.Start())
+
+	defer func() {
+		if err := pool.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	first, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	second, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.config.port, second.config.port)
+	assert.NotEqual(t, first.config.runtimePath, second.config.runtimePath)
+
+	db, err := OpenDB(fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", second.config.port))
+	require.NoError(t, err)
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = pool.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, pool.Release(first))
+	assert.EqualError(t, pool.Release(first), "instance has already been released")
+
+	recycled, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, first, recycled)
+}
+
+func Test_InstancePool_ErrorWhenNotStarted(t *testing.T) {
+	pool := NewInstancePool(1)
+
+	_, err := pool.Acquire(context.Background())
+
+	assert.EqualError(t, err, "instance pool has not been started")
+	assert.EqualError(t, pool.Close(), "instance pool has not been started")
+}
+
+func Test_InstancePool_ReleaseErrors(t *testing.T) {
+	pool := NewInstancePool(1)
+	instance := NewDatabase()
+
+	assert.EqualError(t, pool.Release(instance), "instance pool has not been started")
+
+	pool.available = make(chan *EmbeddedPostgres, 1)
+	pool.closed = make(chan struct{})
+	pool.acquired = map[*EmbeddedPostgres]bool{instance: false}
+
+	assert.EqualError(t, pool.Release(NewDatabase()), "instance does not belong to the pool")
+	assert.EqualError(t, pool.Release(instance), "instance has already been released")
+
+	pool.available <- instance
+
+	acquired, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, instance, acquired)
+
+	require.NoError(t, pool.Release(instance))
+	assert.Len(t, pool.available, 1)
+}
@@ -0,0 +1,61 @@
+package embeddedpostgres
+
+import "time"
+
+// LifecycleEventType identifies a phase of starting or stopping a server.
+type LifecycleEventType string
+
+// The lifecycle events, in the order they occur. The download events only occur when the binaries are not cached yet,
+// and the extracted and initdb events only when the binaries and data directory are not already in place. The crashed
+// and restarted events occur while the server is running, when its process exits unexpectedly and is restarted as
+// configured with RestartOnCrash. EventStartFailed replaces EventReady when Start returns an error.
+const (
+	EventDownloadStarted  = LifecycleEventType("download_started")
+	EventDownloadFinished = LifecycleEventType("download_finished")
+	EventExtracted        = LifecycleEventType("extracted")
+	EventInitDbCompleted  = LifecycleEventType("initdb_completed")
+	EventServerStarted    = LifecycleEventType("server_started")
+	EventReady            = LifecycleEventType("ready")
+	EventStartFailed      = LifecycleEventType("start_failed")
+	EventCrashed          = LifecycleEventType("crashed")
+	EventRestarted        = LifecycleEventType("restarted")
+	EventStopped          = LifecycleEventType("stopped")
+)
+
+// LifecycleEvent describes a lifecycle event of a server.
+type LifecycleEvent struct {
+	// Type of the event.
+	Type LifecycleEventType
+	// Time the event occurred at.
+	Time time.Time
+	// Elapsed is the time taken by the phase the event completes: downloading, extracting, initializing the data
+	// directory, starting or restarting the server process, all of Start for EventReady and EventStartFailed, and
+	// stopping the server. It is zero for EventDownloadStarted and EventCrashed.
+	Elapsed time.Duration
+	// Bytes is the size of the downloaded archive for EventDownloadFinished, and zero for other events.
+	Bytes int64
+	// Err is the error returned by Start for EventStartFailed, and nil for other events.
+	Err error
+}
+
+// LifecycleHook is called synchronously with each lifecycle event, and so should return quickly. The crashed and
+// restarted events are reported from a background goroutine.
+type LifecycleHook func(event LifecycleEvent)
+
+// emit calls the lifecycle hooks with the event, which completes a phase begun at started unless it is zero.
+func (ep *EmbeddedPostgres) emit(eventType LifecycleEventType, started time.Time) {
+	ep.emitEvent(LifecycleEvent{Type: eventType}, started)
+}
+
+func (ep *EmbeddedPostgres) emitEvent(event LifecycleEvent, started time.Time) {
+	event.Time = time.Now()
+	if !started.IsZero() {
+		event.Elapsed = event.Time.Sub(started)
+	}
+
+	ep.startReport.record(event)
+
+	for _, hook := range ep.config.lifecycleHooks {
+		hook(event)
+	}
+}
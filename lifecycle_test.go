@@ -0,0 +1,86 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OnLifecycleEvent(t *testing.T) {
+	var events []LifecycleEventType
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9890).
+		OnLifecycleEvent(func(event LifecycleEvent) {
+			events = append(events, event.Type)
+		}))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	require.NoError(t, database.Stop())
+
+	assert.Subset(t, events, []LifecycleEventType{EventInitDbCompleted, EventServerStarted, EventReady, EventStopped})
+	assert.Equal(t, EventStopped, events[len(events)-1])
+}
+
+func Test_OnLifecycleEvent_DownloadAndInitDatabase(t *testing.T) {
+	archive, cleanUp := createTempXzArchive()
+	defer cleanUp()
+
+	cacheLocation := filepath.Join(t.TempDir(), "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+	cached := false
+
+	var events []LifecycleEvent
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		StartTimeout(time.Second).
+		OnLifecycleEvent(func(event LifecycleEvent) {
+			events = append(events, event)
+		}))
+	database.cacheLocator = func() (string, bool) {
+		return cacheLocation, cached
+	}
+	database.remoteFetchStrategy = func() error {
+		cached = true
+		return copyFile(archive, cacheLocation, 0600)
+	}
+	database.initDatabase = func(binaryExtractLocation, runtimePath, pgDataDir string, options initdbOptions, logger *os.File) error {
+		return nil
+	}
+
+	err := database.Start()
+
+	require.Error(t, err)
+	require.Len(t, events, 5)
+	assert.Equal(t, EventDownloadStarted, events[0].Type)
+	assert.Equal(t, time.Duration(0), events[0].Elapsed)
+	assert.Equal(t, EventDownloadFinished, events[1].Type)
+	assert.Positive(t, events[1].Bytes)
+	assert.Equal(t, EventExtracted, events[2].Type)
+	assert.Equal(t, EventInitDbCompleted, events[3].Type)
+	assert.Equal(t, EventStartFailed, events[4].Type)
+	assert.Equal(t, err, events[4].Err)
+	assert.Positive(t, events[4].Elapsed)
+
+	for _, event := range events {
+		assert.False(t, event.Time.IsZero())
+	}
+}
+
+func Test_Config_OnLifecycleEventDoesNotShareState(t *testing.T) {
+	base := DefaultConfig().OnLifecycleEvent(func(LifecycleEvent) {})
+
+	first := base.OnLifecycleEvent(func(LifecycleEvent) {})
+	second := base.OnLifecycleEvent(func(LifecycleEvent) {})
+
+	assert.Len(t, first.lifecycleHooks, 2)
+	assert.Len(t, second.lifecycleHooks, 2)
+	assert.Len(t, base.lifecycleHooks, 1)
+}
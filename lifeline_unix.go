@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// lifelineScript stops the server once its standard input is closed without a line having been written to it, which
+// happens when the process holding the other end of the pipe exits for any reason, including being killed.
+const lifelineScript = `read _ || exec "$0" stop -m fast -D "$1"`
+
+// lifeline ties the server to the lifetime of this process. As pg_ctl daemonizes the postmaster, a parent death signal
+// cannot reach it, so a watchdog shell holds the read end of a pipe and stops the server with pg_ctl once the pipe is
+// closed by this process exiting. The watchdog is started in its own process group, so that an interrupt from the
+// terminal reaches this process but does not kill the watchdog before it has stopped the server.
+type lifeline struct {
+	watchdog *exec.Cmd
+	pipe     *os.File
+}
+
+// newLifeline starts the watchdog stopping the server in the data directory with pg_ctl once this process exits.
+func newLifeline(pgCtlPath, dataPath string) (*lifeline, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create lifeline pipe: %w", err)
+	}
+
+	watchdog := exec.Command("/bin/sh", "-c", lifelineScript, pgCtlPath, dataPath)
+	watchdog.Stdin = reader
+	watchdog.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err = watchdog.Start()
+
+	_ = reader.Close()
+
+	if err != nil {
+		_ = writer.Close()
+
+		return nil, fmt.Errorf("unable to start lifeline watchdog: %w", err)
+	}
+
+	return &lifeline{watchdog: watchdog, pipe: writer}, nil
+}
+
+// adopt is a no-op, as the watchdog finds the server through its data directory.
+func (l *lifeline) adopt(int) error {
+	return nil
+}
+
+// release stops the watchdog without stopping the server, once the server has been stopped or detached.
+func (l *lifeline) release() {
+	_, _ = l.pipe.Write([]byte("\n"))
+	_ = l.pipe.Close()
+	_ = l.watchdog.Wait()
+}
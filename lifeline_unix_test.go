@@ -0,0 +1,76 @@
+//go:build !windows
+// +build !windows
+
+package embeddedpostgres
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePgCtl writes a pg_ctl which records its arguments instead of stopping a server.
+func fakePgCtl(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	pgCtl := filepath.Join(dir, "pg_ctl")
+	calls := filepath.Join(dir, "calls")
+	require.NoError(t, os.WriteFile(pgCtl, []byte("#!/bin/sh\necho \"$@\" > "+calls+"\n"), 0700))
+
+	return pgCtl, calls
+}
+
+func Test_lifeline_StopsServerWhenPipeCloses(t *testing.T) {
+	pgCtl, calls := fakePgCtl(t)
+
+	lifeline, err := newLifeline(pgCtl, "/data")
+	require.NoError(t, err)
+
+	// closing the pipe without writing to it is what happens when this process exits
+	require.NoError(t, lifeline.pipe.Close())
+	require.NoError(t, lifeline.watchdog.Wait())
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, "stop -m fast -D /data\n", string(content))
+}
+
+func Test_lifeline_Release(t *testing.T) {
+	pgCtl, calls := fakePgCtl(t)
+
+	lifeline, err := newLifeline(pgCtl, "/data")
+	require.NoError(t, err)
+
+	lifeline.release()
+
+	assert.True(t, lifeline.watchdog.ProcessState.Exited())
+	assert.NoFileExists(t, calls)
+}
+
+func Test_ServerStopsWhenOwnerIsKilled(t *testing.T) {
+	// the helper process starts a server and is killed without any chance to stop it
+	owner := exec.Command(os.Args[0], "-test.run=^Test_helperStartAndKill$")
+	owner.Env = append(os.Environ(), "EMBEDDED_POSTGRES_HELPER_START_AND_KILL=1")
+	output, err := owner.CombinedOutput()
+	require.Error(t, err, string(output))
+
+	assert.Eventually(t, func() bool {
+		return ensurePortAvailable(9906) == nil
+	}, time.Minute, 100*time.Millisecond)
+}
+
+func Test_helperStartAndKill(t *testing.T) {
+	if os.Getenv("EMBEDDED_POSTGRES_HELPER_START_AND_KILL") == "" {
+		t.Skip("only runs as a helper process")
+	}
+
+	require.NoError(t, NewDatabase(DefaultConfig().Port(9906)).Start())
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGKILL))
+}
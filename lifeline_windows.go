@@ -0,0 +1,114 @@
+//go:build windows
+// +build windows
+
+package embeddedpostgres
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitKillOnJobClose           = 0x2000
+	processSetQuota                        = 0x0100
+)
+
+var (
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+)
+
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// lifeline ties the server to the lifetime of this process through a Job Object which kills its processes once its
+// last handle is closed, which happens when this process exits for any reason. pg_ctl is assigned to the job as soon as
+// it has started, and the postmaster and server processes it starts belong to the same job.
+type lifeline struct {
+	job syscall.Handle
+}
+
+// newLifeline creates the Job Object, the server is added to it with adopt.
+func newLifeline(string, string) (*lifeline, error) {
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return nil, fmt.Errorf("unable to create job object: %w", err)
+	}
+
+	l := &lifeline{job: syscall.Handle(job)}
+
+	if err := l.setLimitFlags(jobObjectLimitKillOnJobClose); err != nil {
+		_ = syscall.CloseHandle(l.job)
+
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// adopt assigns the process to the job, and with it any process it starts afterwards.
+func (l *lifeline) adopt(pid int) error {
+	process, err := syscall.OpenProcess(processSetQuota|syscall.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("unable to open process %d: %w", pid, err)
+	}
+
+	defer func() {
+		_ = syscall.CloseHandle(process)
+	}()
+
+	if result, _, err := procAssignProcessToJobObject.Call(uintptr(l.job), uintptr(process)); result == 0 {
+		return fmt.Errorf("unable to assign process %d to job object: %w", pid, err)
+	}
+
+	return nil
+}
+
+// release closes the job without killing its processes, once the server has been stopped or detached.
+func (l *lifeline) release() {
+	_ = l.setLimitFlags(0)
+	_ = syscall.CloseHandle(l.job)
+}
+
+func (l *lifeline) setLimitFlags(flags uint32) error {
+	var info jobObjectExtendedLimitInformation
+	info.BasicLimitInformation.LimitFlags = flags
+
+	result, _, err := procSetInformationJobObject.Call(uintptr(l.job), jobObjectExtendedLimitInformationClass,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	if result == 0 {
+		return fmt.Errorf("unable to set job object limits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CreatePublication creates a publication of the tables, which may be qualified with a schema, in the configured
+// database, or of all tables when none are given. Logical replication requires the server to be configured with
+// WalLevel(WalLevelLogical).
+func (ep *EmbeddedPostgres) CreatePublication(name string, tables ...string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	return ep.withConfiguredDatabase(func(db *sql.DB) error {
+		if _, err := db.Exec(createPublicationStatement(name, tables)); err != nil {
+			return fmt.Errorf("unable to create publication %s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+// DropPublication drops the publication from the configured database, if it exists.
+func (ep *EmbeddedPostgres) DropPublication(name string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	return ep.withConfiguredDatabase(func(db *sql.DB) error {
+		if _, err := db.Exec(fmt.Sprintf("DROP PUBLICATION IF EXISTS %s", quoteIdentifier(name))); err != nil {
+			return fmt.Errorf("unable to drop publication %s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+func (ep *EmbeddedPostgres) withConfiguredDatabase(fn func(db *sql.DB) error) error {
+	return withDatabaseConnection(ep.config, ep.config.database, fn)
+}
+
+func createPublicationStatement(name string, tables []string) string {
+	if len(tables) == 0 {
+		return fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", quoteIdentifier(name))
+	}
+
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, quoteQualifiedName(table))
+	}
+
+	return fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s", quoteIdentifier(name), strings.Join(quoted, ", "))
+}
+
+// quoteQualifiedName quotes a name which may be qualified with a schema.
+func quoteQualifiedName(name string) string {
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		return quoteIdentifier(schema) + "." + quoteIdentifier(table)
+	}
+
+	return quoteIdentifier(name)
+}
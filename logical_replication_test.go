@@ -0,0 +1,75 @@
+package embeddedpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LogicalReplication(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9865).
+		WalLevel(WalLevelLogical))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9865 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	_, err = db.Exec("CREATE TABLE outbox (id int PRIMARY KEY, payload text)")
+	require.NoError(t, err)
+
+	require.NoError(t, database.CreatePublication("outbox_publication", "public.outbox"))
+
+	slot, err := database.CreateLogicalReplicationSlot("outbox_slot", "test_decoding")
+	require.NoError(t, err)
+	assert.Equal(t, "outbox_slot", slot.Name)
+	assert.Equal(t, "logical", slot.Type)
+	assert.NotEmpty(t, slot.ConfirmedFlushLSN)
+
+	_, err = db.Exec("INSERT INTO outbox VALUES (1, 'created')")
+	require.NoError(t, err)
+
+	var change string
+	require.NoError(t, db.QueryRow("SELECT data FROM pg_logical_slot_get_changes('outbox_slot', NULL, NULL) WHERE data LIKE 'table%'").Scan(&change))
+	assert.Contains(t, change, "table public.outbox: INSERT: id[integer]:1 payload[text]:'created'")
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM pg_publication_tables WHERE pubname = 'outbox_publication'").Scan(&tables))
+	assert.Equal(t, 1, tables)
+
+	require.NoError(t, database.DropPublication("outbox_publication"))
+}
+
+func Test_LogicalReplication_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase()
+
+	assert.EqualError(t, database.CreatePublication("publication"), "server has not been started")
+	assert.EqualError(t, database.DropPublication("publication"), "server has not been started")
+
+	_, err := database.CreateLogicalReplicationSlot("slot", "pgoutput")
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_createPublicationStatement(t *testing.T) {
+	assert.Equal(t, `CREATE PUBLICATION "cdc" FOR ALL TABLES`, createPublicationStatement("cdc", nil))
+	assert.Equal(t, `CREATE PUBLICATION "cdc" FOR TABLE "orders", "sales"."Invoices"`, createPublicationStatement("cdc", []string{"orders", "sales.Invoices"}))
+}
+
+func Test_WalLevel(t *testing.T) {
+	assert.Equal(t, map[string]string{"wal_level": "logical"}, DefaultConfig().WalLevel(WalLevelLogical).startParameters)
+}
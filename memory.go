@@ -0,0 +1,40 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"runtime"
+)
+
+// sharedMemoryPath is the memory backed file system available on most Linux distributions.
+var sharedMemoryPath = "/dev/shm"
+
+// minimumSharedMemorySpace is the free space needed on the memory backed file system for a data directory, which is well
+// above the 64MB containers such as those run by Docker get by default, as Postgres also places its dynamic shared
+// memory there.
+var minimumSharedMemorySpace uint64 = 512 * 1024 * 1024
+
+// inMemoryDirectory returns a writable directory on a memory backed file system, or an empty string when none is
+// available or it has too little free space, in which case the data directory stays on disk.
+func inMemoryDirectory() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+
+	info, err := os.Stat(sharedMemoryPath)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+
+	if available, err := freeDiskSpace(sharedMemoryPath); err != nil || available < minimumSharedMemorySpace {
+		return ""
+	}
+
+	probe, err := os.MkdirTemp(sharedMemoryPath, "embedded-postgres-probe")
+	if err != nil {
+		return ""
+	}
+
+	_ = os.RemoveAll(probe)
+
+	return sharedMemoryPath
+}
@@ -0,0 +1,62 @@
+package embeddedpostgres
+
+import (
+	"math"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_inMemoryDirectory(t *testing.T) {
+	originalPath, originalSpace := sharedMemoryPath, minimumSharedMemorySpace
+	defer func() {
+		sharedMemoryPath, minimumSharedMemorySpace = originalPath, originalSpace
+	}()
+
+	sharedMemoryPath = t.TempDir()
+	minimumSharedMemorySpace = 0
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, sharedMemoryPath, inMemoryDirectory())
+	} else {
+		assert.Equal(t, "", inMemoryDirectory())
+	}
+
+	sharedMemoryPath = filepath.Join(t.TempDir(), "missing")
+	assert.Equal(t, "", inMemoryDirectory())
+}
+
+func Test_inMemoryDirectory_EmptyWhenLowOnSpace(t *testing.T) {
+	originalPath, originalSpace := sharedMemoryPath, minimumSharedMemorySpace
+	defer func() {
+		sharedMemoryPath, minimumSharedMemorySpace = originalPath, originalSpace
+	}()
+
+	sharedMemoryPath = t.TempDir()
+	minimumSharedMemorySpace = math.MaxUint64
+
+	assert.Equal(t, "", inMemoryDirectory())
+
+	database := NewDatabase(DefaultConfig().RuntimePath("/runtime").DataInMemory(true))
+	assert.Equal(t, filepath.Join("/runtime", "data"), database.DataPath())
+}
+
+func Test_DataInMemory(t *testing.T) {
+	original := sharedMemoryPath
+	defer func() {
+		sharedMemoryPath = original
+	}()
+
+	sharedMemoryPath = t.TempDir()
+
+	database := NewDatabase(DefaultConfig().Port(9874).RuntimePath("/runtime").DataInMemory(true))
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, filepath.Join(sharedMemoryPath, "embedded-postgres-go", "9874", "data"), database.DataPath())
+	} else {
+		assert.Equal(t, filepath.Join("/runtime", "data"), database.DataPath())
+	}
+
+	database = NewDatabase(DefaultConfig().DataPath("/data").DataInMemory(true))
+	assert.Equal(t, "/data", database.DataPath())
+}
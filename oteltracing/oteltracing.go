@@ -0,0 +1,133 @@
+// Package oteltracing provides OpenTelemetry spans of the startup phases of embedded Postgres servers, so that teams
+// tracing their test and CI pipelines can see where the time to start the embedded database is spent.
+package oteltracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this package.
+const instrumentationName = "github.com/RVennu/embedded-postgres/oteltracing"
+
+// The names of the spans. A start span covers all of Start, with a child span for each phase that was needed.
+const (
+	SpanStart    = "embedded-postgres.start"
+	SpanDownload = "embedded-postgres.download"
+	SpanExtract  = "embedded-postgres.extract"
+	SpanInitDb   = "embedded-postgres.initdb"
+	SpanServer   = "embedded-postgres.server_start"
+	SpanReady    = "embedded-postgres.ready"
+	SpanRestart  = "embedded-postgres.restart"
+	SpanStop     = "embedded-postgres.stop"
+)
+
+// AttributeDownloadBytes is the size of the downloaded archive, set on download spans.
+const AttributeDownloadBytes = attribute.Key("embedded_postgres.download.bytes")
+
+// Tracer creates spans from the lifecycle events of the servers it instruments.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer creates a Tracer using the provider, or the global tracer provider when the provider is nil.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// Instrument adds a lifecycle hook to the configuration which creates spans as children of the span in ctx, if any.
+// The spans of a start are created once it completes, as the lifecycle events only report phases after they end.
+func (t *Tracer) Instrument(ctx context.Context, config embeddedpostgres.Config) embeddedpostgres.Config {
+	hook := &startTrace{tracer: t.tracer, ctx: ctx}
+
+	return config.OnLifecycleEvent(hook.observe)
+}
+
+// phase is a completed phase of a start, recorded until the start completes.
+type phase struct {
+	name       string
+	start, end time.Time
+	attributes []attribute.KeyValue
+}
+
+// startTrace records the phases of each start of a server, which the crashed and restarted events may report
+// concurrently with Stop.
+type startTrace struct {
+	tracer trace.Tracer
+	ctx    context.Context
+
+	mu     sync.Mutex
+	phases []phase
+}
+
+func (s *startTrace) observe(event embeddedpostgres.LifecycleEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch event.Type {
+	case embeddedpostgres.EventDownloadFinished:
+		s.record(SpanDownload, event, AttributeDownloadBytes.Int64(event.Bytes))
+	case embeddedpostgres.EventExtracted:
+		s.record(SpanExtract, event)
+	case embeddedpostgres.EventInitDbCompleted:
+		s.record(SpanInitDb, event)
+	case embeddedpostgres.EventServerStarted:
+		s.record(SpanServer, event)
+	case embeddedpostgres.EventReady:
+		if len(s.phases) > 0 {
+			// readiness covers waiting for connections and preparing the databases once the process started
+			s.phases = append(s.phases, phase{name: SpanReady, start: s.phases[len(s.phases)-1].end, end: event.Time})
+		}
+
+		s.endStart(event)
+	case embeddedpostgres.EventStartFailed:
+		s.endStart(event)
+	case embeddedpostgres.EventRestarted:
+		s.span(s.ctx, phase{name: SpanRestart, start: event.Time.Add(-event.Elapsed), end: event.Time})
+	case embeddedpostgres.EventStopped:
+		s.span(s.ctx, phase{name: SpanStop, start: event.Time.Add(-event.Elapsed), end: event.Time})
+	}
+}
+
+func (s *startTrace) record(name string, event embeddedpostgres.LifecycleEvent, attributes ...attribute.KeyValue) {
+	s.phases = append(s.phases, phase{
+		name:       name,
+		start:      event.Time.Add(-event.Elapsed),
+		end:        event.Time,
+		attributes: attributes,
+	})
+}
+
+// endStart creates the span of a completed start and the spans of its phases.
+func (s *startTrace) endStart(event embeddedpostgres.LifecycleEvent) {
+	ctx, span := s.tracer.Start(s.ctx, SpanStart, trace.WithTimestamp(event.Time.Add(-event.Elapsed)))
+
+	for _, p := range s.phases {
+		s.span(ctx, p)
+	}
+
+	s.phases = nil
+
+	if event.Err != nil {
+		span.RecordError(event.Err, trace.WithTimestamp(event.Time))
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+
+	span.End(trace.WithTimestamp(event.Time))
+}
+
+func (s *startTrace) span(ctx context.Context, p phase) {
+	_, span := s.tracer.Start(ctx, p.name, trace.WithTimestamp(p.start), trace.WithAttributes(p.attributes...))
+	span.End(trace.WithTimestamp(p.end))
+}
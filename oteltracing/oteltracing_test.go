@@ -0,0 +1,103 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTrace(t *testing.T) (*startTrace, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return &startTrace{tracer: NewTracer(provider).tracer, ctx: context.Background()}, recorder
+}
+
+func Test_Tracer_Start(t *testing.T) {
+	hook, recorder := newTestTrace(t)
+	started := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, event := range []embeddedpostgres.LifecycleEvent{
+		{Type: embeddedpostgres.EventDownloadStarted, Time: started},
+		{Type: embeddedpostgres.EventDownloadFinished, Time: started.Add(3 * time.Second), Elapsed: 3 * time.Second, Bytes: 1024},
+		{Type: embeddedpostgres.EventExtracted, Time: started.Add(4 * time.Second), Elapsed: time.Second},
+		{Type: embeddedpostgres.EventInitDbCompleted, Time: started.Add(6 * time.Second), Elapsed: 2 * time.Second},
+		{Type: embeddedpostgres.EventServerStarted, Time: started.Add(7 * time.Second), Elapsed: time.Second},
+		{Type: embeddedpostgres.EventReady, Time: started.Add(8 * time.Second), Elapsed: 8 * time.Second},
+	} {
+		hook.observe(event)
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 6)
+
+	root := spans[len(spans)-1]
+	assert.Equal(t, SpanStart, root.Name())
+	assert.Equal(t, started, root.StartTime())
+	assert.Equal(t, started.Add(8*time.Second), root.EndTime())
+	assert.Equal(t, codes.Unset, root.Status().Code)
+
+	expected := []struct {
+		name       string
+		start, end time.Duration
+	}{
+		{SpanDownload, 0, 3 * time.Second},
+		{SpanExtract, 3 * time.Second, 4 * time.Second},
+		{SpanInitDb, 4 * time.Second, 6 * time.Second},
+		{SpanServer, 6 * time.Second, 7 * time.Second},
+		{SpanReady, 7 * time.Second, 8 * time.Second},
+	}
+
+	for i, phase := range expected {
+		span := spans[i]
+		assert.Equal(t, phase.name, span.Name())
+		assert.Equal(t, started.Add(phase.start), span.StartTime())
+		assert.Equal(t, started.Add(phase.end), span.EndTime())
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+
+	require.Len(t, spans[0].Attributes(), 1)
+	assert.Equal(t, AttributeDownloadBytes.Int64(1024), spans[0].Attributes()[0])
+}
+
+func Test_Tracer_StartFailed(t *testing.T) {
+	hook, recorder := newTestTrace(t)
+	started := time.Now()
+
+	hook.observe(embeddedpostgres.LifecycleEvent{Type: embeddedpostgres.EventInitDbCompleted, Time: started.Add(time.Second), Elapsed: time.Second})
+	hook.observe(embeddedpostgres.LifecycleEvent{Type: embeddedpostgres.EventStartFailed, Time: started.Add(2 * time.Second), Elapsed: 2 * time.Second, Err: errors.New("could not start")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, SpanInitDb, spans[0].Name())
+	assert.Equal(t, SpanStart, spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "could not start", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}
+
+func Test_Tracer_Stop(t *testing.T) {
+	hook, recorder := newTestTrace(t)
+	stopped := time.Now()
+
+	hook.observe(embeddedpostgres.LifecycleEvent{Type: embeddedpostgres.EventStopped, Time: stopped, Elapsed: time.Second})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, SpanStop, spans[0].Name())
+	assert.Equal(t, stopped.Add(-time.Second), spans[0].StartTime())
+	assert.False(t, spans[0].Parent().IsValid())
+}
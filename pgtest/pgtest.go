@@ -0,0 +1,92 @@
+// Package pgtest provides helpers to run an embedded Postgres server as part of Go tests.
+package pgtest
+
+import (
+	"database/sql"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// Option customises the configuration of the Postgres server started by New.
+type Option func(config embeddedpostgres.Config) embeddedpostgres.Config
+
+// WithConfig replaces the configuration of the Postgres server started by New.
+func WithConfig(config embeddedpostgres.Config) Option {
+	return func(_ embeddedpostgres.Config) embeddedpostgres.Config {
+		return config
+	}
+}
+
+// New starts a Postgres server on a random free port with its runtime directory inside a test temporary directory,
+// overriding any port or runtime path set by the options. Its logs are forwarded to the test log, unless the options
+// configure other loggers.
+// The server is stopped and its directories deleted when the test completes. A connected *sql.DB and the DSN used to
+// connect are returned, failing the test if the server cannot be started.
+func New(t testing.TB, opts ...Option) (*sql.DB, string) {
+	t.Helper()
+
+	port, err := embeddedpostgres.FreePort()
+	if err != nil {
+		t.Fatalf("unable to find a free port: %s", err)
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		LogToTest(t)
+
+	for _, opt := range opts {
+		config = opt(config)
+	}
+
+	config = config.
+		Port(port).
+		RuntimePath(t.TempDir())
+
+	database := embeddedpostgres.NewDatabase(config)
+	if err := database.Start(); err != nil {
+		t.Fatalf("unable to start postgres: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := database.Stop(); err != nil {
+			t.Errorf("unable to stop postgres: %s", err)
+		}
+	})
+
+	// the DSN is taken from the started server, as it includes any credentials generated by Start
+	dsn := database.ConnectionInfo().DSN
+
+	db, err := embeddedpostgres.OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("unable to connect to postgres: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("unable to close database connection: %s", err)
+		}
+	})
+
+	return db, dsn
+}
+
+// ForEachVersion runs fn as a subtest named after each of the Postgres versions, against a server of that version
+// started by New with the options. Servers are started one at a time and stopped when their subtest completes, so the
+// same test can validate compatibility across several major versions in a single go test invocation.
+func ForEachVersion(t *testing.T, versions []embeddedpostgres.PostgresVersion, fn func(t *testing.T, db *sql.DB, dsn string), opts ...Option) {
+	t.Helper()
+
+	for _, version := range versions {
+		version := version
+
+		t.Run(string(version), func(t *testing.T) {
+			versionOpts := append(append([]Option{}, opts...), func(config embeddedpostgres.Config) embeddedpostgres.Config {
+				return config.Version(version)
+			})
+
+			db, dsn := New(t, versionOpts...)
+
+			fn(t, db, dsn)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package pgxconnect connects pgx pools to embedded Postgres servers, for applications using pgx directly rather than
+// through database/sql.
+package pgxconnect
+
+import (
+	"context"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool returns a pool connected to the configured database of the started server, which is closed by Stop.
+func Pool(ctx context.Context, database *embeddedpostgres.EmbeddedPostgres) (*pgxpool.Pool, error) {
+	return PoolForDatabase(ctx, database, database.ConnectionInfo().Database)
+}
+
+// PoolForDatabase returns a pool connected to a database of the started server as the configured user, which is closed
+// by Stop.
+func PoolForDatabase(ctx context.Context, database *embeddedpostgres.EmbeddedPostgres, name string) (*pgxpool.Pool, error) {
+	// fails unless the server has been started
+	if _, err := database.PID(); err != nil {
+		return nil, err
+	}
+
+	config, err := pgxpool.ParseConfig(database.ConnectionInfo().DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ConnConfig.Database = name
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	database.CloseOnStop(poolCloser{pool: pool})
+
+	return pool, nil
+}
+
+// poolCloser adapts a pool to io.Closer.
+type poolCloser struct {
+	pool *pgxpool.Pool
+}
+
+func (c poolCloser) Close() error {
+	c.pool.Close()
+	return nil
+}
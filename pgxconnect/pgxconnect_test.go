@@ -0,0 +1,57 @@
+package pgxconnect
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Pool(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9901).
+		Databases("app", "audit").
+		ConnectionLeakCheck(embeddedpostgres.LeakCheckFail))
+	require.NoError(t, database.Start())
+
+	ctx := context.Background()
+
+	pool, err := Pool(ctx, database)
+	require.NoError(t, err)
+
+	audit, err := PoolForDatabase(ctx, database, "audit")
+	require.NoError(t, err)
+
+	var current string
+	require.NoError(t, pool.QueryRow(ctx, "SELECT current_database()").Scan(&current))
+	assert.Equal(t, "app", current)
+
+	require.NoError(t, audit.QueryRow(ctx, "SELECT current_database()").Scan(&current))
+	assert.Equal(t, "audit", current)
+
+	// the pools are closed before the leak check
+	require.NoError(t, database.Stop())
+
+	assert.Error(t, pool.Ping(ctx))
+}
+
+func Test_Pool_ErrorWhenNotStarted(t *testing.T) {
+	_, err := Pool(context.Background(), embeddedpostgres.NewDatabase())
+
+	assert.EqualError(t, err, "server has not been started")
+}
@@ -0,0 +1,65 @@
+// Package pgxdriver registers pgx as the SQL driver embedded-postgres opens the connections it hands out with, such as
+// those of OpenDB, Connect and AfterStart hooks, when imported.
+//
+//	import _ "github.com/RVennu/embedded-postgres/pgxdriver"
+package pgxdriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+//nolint:gochecknoinits
+func init() {
+	embeddedpostgres.RegisterSQLDriver(Driver{})
+}
+
+// Driver is the embeddedpostgres.SQLDriver using pgx.
+type Driver struct{}
+
+// OpenConnector returns a pgx connector for the DSN.
+func (Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return stdlib.GetConnector(*config), nil
+}
+
+// CopyFrom streams the data as CSV formatted COPY data on the underlying pgx connection, as COPY is not available
+// through database/sql.
+func (Driver) CopyFrom(ctx context.Context, conn *sql.Conn, table string, columns []string, data io.Reader) error {
+	statement := copyInStatement(table, columns)
+
+	return conn.Raw(func(driverConn interface{}) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("the database does not use pgx, but %T", driverConn)
+		}
+
+		_, err := pgxConn.Conn().PgConn().CopyFrom(ctx, data, statement)
+
+		return err
+	})
+}
+
+// copyInStatement is the COPY statement loading CSV data into the columns of the table, which may be qualified with a
+// schema.
+func copyInStatement(table string, columns []string) string {
+	sanitized := make([]string, len(columns))
+	for i, column := range columns {
+		sanitized[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN WITH (FORMAT csv)",
+		pgx.Identifier(strings.SplitN(table, ".", 2)).Sanitize(), strings.Join(sanitized, ", "))
+}
@@ -2,13 +2,16 @@ module github.com/RVennu/embedded-postgres/platform-test
 
 replace github.com/RVennu/embedded-postgres => ../
 
+replace github.com/RVennu/embedded-postgres/pqdriver => ../pqdriver
+
 go 1.18
 
-require github.com/RVennu/embedded-postgres v0.0.0
+require (
+	github.com/RVennu/embedded-postgres v0.0.0
+	github.com/RVennu/embedded-postgres/pqdriver v0.0.0
+)
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/lib/pq v1.10.4 // indirect
 	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
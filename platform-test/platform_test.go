@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	_ "github.com/RVennu/embedded-postgres/pqdriver"
 )
 
 func Test_AllMajorVersions(t *testing.T) {
@@ -80,7 +81,7 @@ func shutdownDBAndFail(t *testing.T, err error, db *embeddedpostgres.EmbeddedPos
 }
 
 func connect(port uint32) (*sql.DB, error) {
-	db, err := sql.Open("postgres", fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port))
+	db, err := embeddedpostgres.OpenDB(fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port))
 	return db, err
 }
 
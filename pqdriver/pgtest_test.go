@@ -0,0 +1,38 @@
+package pqdriver
+
+import (
+	"database/sql"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/RVennu/embedded-postgres/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the pgtest package is tested here, as its servers are connected to with the registered driver
+
+func Test_pgtest_New(t *testing.T) {
+	db, dsn := pgtest.New(t, func(config embeddedpostgres.Config) embeddedpostgres.Config {
+		return config.Database("beer")
+	})
+
+	var database string
+	require.NoError(t, db.QueryRow("SELECT current_database()").Scan(&database))
+
+	assert.Equal(t, "beer", database)
+	assert.Contains(t, dsn, "/beer?sslmode=disable")
+}
+
+func Test_pgtest_ForEachVersion(t *testing.T) {
+	var versions []string
+
+	pgtest.ForEachVersion(t, []embeddedpostgres.PostgresVersion{embeddedpostgres.V14, embeddedpostgres.V15}, func(t *testing.T, db *sql.DB, dsn string) {
+		var version string
+		require.NoError(t, db.QueryRow("SHOW server_version").Scan(&version))
+
+		versions = append(versions, version)
+	})
+
+	assert.Equal(t, []string{"14.8", "15.3"}, versions)
+}
@@ -0,0 +1,96 @@
+// Package pqdriver registers lib/pq as the SQL driver embedded-postgres opens the connections it hands out with, such
+// as those of OpenDB, Connect and AfterStart hooks, when imported.
+//
+//	import _ "github.com/RVennu/embedded-postgres/pqdriver"
+package pqdriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/lib/pq"
+)
+
+//nolint:gochecknoinits
+func init() {
+	embeddedpostgres.RegisterSQLDriver(Driver{})
+}
+
+// Driver is the embeddedpostgres.SQLDriver using lib/pq.
+type Driver struct{}
+
+// OpenConnector returns a lib/pq connector for the DSN.
+func (Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	return pq.NewConnector(dsn)
+}
+
+// CopyFrom loads the CSV records of data with a COPY statement prepared by lib/pq, in a transaction, as lib/pq takes
+// the rows of a COPY as statement arguments rather than as a stream.
+func (Driver) CopyFrom(ctx context.Context, conn *sql.Conn, table string, columns []string, data io.Reader) (err error) {
+	statement := pq.CopyIn(table, columns...)
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		statement = pq.CopyInSchema(schema, name, columns...)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	reader := csv.NewReader(data)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.ExecContext(ctx, copyValues(record)...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// copyValues converts a CSV record into the arguments of a COPY row, with empty fields as NULL.
+func copyValues(record []string) []interface{} {
+	values := make([]interface{}, len(record))
+
+	for i, field := range record {
+		if field != "" {
+			values[i] = field
+		}
+	}
+
+	return values
+}
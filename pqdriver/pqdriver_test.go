@@ -0,0 +1,68 @@
+package pqdriver
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenConnector(t *testing.T) {
+	connector, err := embeddedpostgres.SQLConnector("host=localhost port=9999 user=gin password='it\\'s wine' dbname=beer sslmode=disable")
+
+	require.NoError(t, err)
+	assert.IsType(t, &pq.Connector{}, connector)
+
+	_, err = embeddedpostgres.SQLConnector("host='unterminated")
+	assert.Error(t, err)
+}
+
+func Test_copyValues(t *testing.T) {
+	assert.Equal(t, []interface{}{"Punk IPA", nil, "5.4"}, copyValues([]string{"Punk IPA", "", "5.4"}))
+}
+
+func Test_CopyFromCSV(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9857).
+		AfterStartSQL("CREATE TABLE beers(name text, abv numeric, brewery text)"))
+	require.NoError(t, database.Start())
+
+	defer func() {
+		require.NoError(t, database.Stop())
+	}()
+
+	db, err := database.Connect()
+	require.NoError(t, err)
+
+	csv := "name,abv\nPunk IPA,5.4\n\"Elvis, Juice\",6.5\nNanny State,\n"
+	require.NoError(t, embeddedpostgres.CopyFromCSV(db, "public.beers", strings.NewReader(csv)))
+
+	var count, nulls int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*), COUNT(*) FILTER (WHERE abv IS NULL) FROM beers").Scan(&count, &nulls))
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 1, nulls)
+
+	err = embeddedpostgres.CopyFromCSV(db, "beers", strings.NewReader("name,abv\nHazy Jane,not a number\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to copy into beers")
+
+	// the failed copy loaded no rows
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM beers").Scan(&count))
+	assert.Equal(t, 3, count)
+}
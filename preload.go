@@ -0,0 +1,91 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sharedLibraryExtensions are the file extensions Postgres uses for loadable modules on each platform.
+var sharedLibraryExtensions = []string{".so", ".dll", ".dylib"}
+
+// mergePreloadLibraries combines the configured shared preload libraries with any listed in an explicit
+// shared_preload_libraries parameter, without duplicates.
+func mergePreloadLibraries(parameter string, libraries []string) string {
+	var merged []string
+	seen := map[string]bool{}
+
+	for _, library := range append(strings.Split(parameter, ","), libraries...) {
+		library = strings.TrimSpace(library)
+		if library == "" || seen[library] {
+			continue
+		}
+
+		seen[library] = true
+		merged = append(merged, library)
+	}
+
+	return strings.Join(merged, ",")
+}
+
+// checkPreloadLibraries verifies that the binaries include each shared preload library, whether configured with
+// SharedPreloadLibraries or a shared_preload_libraries start parameter, as the server otherwise fails to start with an
+// error which is only found in its log. Binaries without a lib directory are not checked.
+func checkPreloadLibraries(config Config) error {
+	libPath := filepath.Join(config.binariesPath, "lib")
+	if _, err := os.Stat(libPath); err != nil {
+		return nil
+	}
+
+	libraries := mergePreloadLibraries(config.startParameters["shared_preload_libraries"], config.sharedPreloadLibraries)
+	if libraries == "" {
+		return nil
+	}
+
+	var missing []string
+
+	for _, library := range strings.Split(libraries, ",") {
+		if !libraryAvailable(libPath, library) {
+			missing = append(missing, library)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("shared preload libraries %s are not available in the Postgres %s binaries at %s, "+
+			"configure BinariesPath or BinaryRepositoryURL with binaries which include them",
+			strings.Join(missing, ", "), config.version, config.binariesPath)
+	}
+
+	return nil
+}
+
+func libraryAvailable(libPath, library string) bool {
+	library = strings.Trim(library, `"`)
+
+	// libraries outside the binaries can only be named by an absolute path
+	if filepath.IsAbs(library) {
+		_, err := os.Stat(library)
+		return err == nil || libraryWithExtensionExists(library)
+	}
+
+	library = strings.TrimPrefix(library, "$libdir/")
+
+	for _, dir := range []string{libPath, filepath.Join(libPath, "postgresql")} {
+		if libraryWithExtensionExists(filepath.Join(dir, library)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func libraryWithExtensionExists(path string) bool {
+	for _, extension := range sharedLibraryExtensions {
+		if _, err := os.Stat(path + extension); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergePreloadLibraries(t *testing.T) {
+	assert.Equal(t, "pgaudit", mergePreloadLibraries("", []string{"pgaudit"}))
+	assert.Equal(t, "pg_stat_statements,pgaudit", mergePreloadLibraries("pg_stat_statements, pgaudit", []string{"pgaudit"}))
+	assert.Equal(t, "auto_explain,pgaudit,pg_stat_statements", mergePreloadLibraries("auto_explain", []string{"pgaudit", "pg_stat_statements"}))
+}
+
+func Test_serverParameters_SharedPreloadLibraries(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		StartParameters(map[string]string{"shared_preload_libraries": "auto_explain"}).
+		SharedPreloadLibraries("pg_stat_statements"))
+
+	assert.Equal(t, "auto_explain,pg_stat_statements", database.serverParameters()["shared_preload_libraries"])
+}
+
+func Test_AuditLogging(t *testing.T) {
+	config := DefaultConfig().AuditLogging("write", "ddl")
+
+	assert.Equal(t, []string{"pgaudit"}, config.sharedPreloadLibraries)
+	assert.Equal(t, []string{"pgaudit"}, config.extensions)
+	assert.Equal(t, map[string]string{"pgaudit.log": "write,ddl", "pgaudit.log_relation": "on"}, config.startParameters)
+	assert.Equal(t, "all", DefaultConfig().AuditLogging().startParameters["pgaudit.log"])
+}
+
+func Test_AutoExplain(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9894).
+		AutoExplain(0))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	require.NoError(t, withDatabaseConnection(DefaultConfig().Port(9894), "postgres", func(db *sql.DB) error {
+		_, err := db.Exec("SELECT count(*) FROM pg_class")
+		return err
+	}))
+
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(database.ServerLogPath())
+		return err == nil && strings.Contains(string(content), "Query Text: SELECT count(*) FROM pg_class")
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func Test_Config_AutoExplain(t *testing.T) {
+	config := DefaultConfig().AutoExplain(250 * time.Millisecond)
+
+	assert.Equal(t, []string{"auto_explain"}, config.sharedPreloadLibraries)
+	assert.Equal(t, map[string]string{"auto_explain.log_min_duration": "250ms"}, config.startParameters)
+	assert.Equal(t, "0ms", DefaultConfig().AutoExplain(0).startParameters["auto_explain.log_min_duration"])
+	assert.Equal(t, "1ms", DefaultConfig().AutoExplain(500 * time.Microsecond).startParameters["auto_explain.log_min_duration"])
+}
+
+func Test_checkPreloadLibraries(t *testing.T) {
+	binariesPath := t.TempDir()
+	config := DefaultConfig().SharedPreloadLibraries("pg_stat_statements", "pgaudit")
+	config.binariesPath = binariesPath
+
+	assert.NoError(t, checkPreloadLibraries(config), "binaries without a lib directory are not checked")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(binariesPath, "lib", "postgresql"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(binariesPath, "lib", "postgresql", "pg_stat_statements.so"), nil, 0644))
+
+	err := checkPreloadLibraries(config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "shared preload libraries pgaudit are not available in the Postgres 15.3.0 binaries at "+binariesPath)
+
+	require.NoError(t, os.WriteFile(filepath.Join(binariesPath, "lib", "pgaudit.dylib"), nil, 0644))
+	assert.NoError(t, checkPreloadLibraries(config))
+}
+
+func Test_checkPreloadLibraries_StartParameter(t *testing.T) {
+	binariesPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(binariesPath, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(binariesPath, "lib", "pg_stat_statements.so"), nil, 0644))
+
+	config := DefaultConfig().StartParameters(map[string]string{"shared_preload_libraries": "\"$libdir/pg_stat_statements\", pg_cron"})
+	config.binariesPath = binariesPath
+
+	err := checkPreloadLibraries(config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "shared preload libraries pg_cron are not available")
+
+	config = config.StartParameters(map[string]string{"shared_preload_libraries": "$libdir/pg_stat_statements," + filepath.Join(binariesPath, "lib", "pg_stat_statements")})
+	assert.NoError(t, checkPreloadLibraries(config))
+}
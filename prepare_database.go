@@ -3,14 +3,16 @@ package embeddedpostgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-
-	"github.com/lib/pq"
+	"sort"
+	"strings"
+	"time"
 )
 
 const (
@@ -18,24 +20,64 @@ const (
 	fmtAfterError  = "%v happened after error: %w"
 )
 
-type initDatabase func(binaryExtractLocation, runtimePath, pgDataDir, username, password, locale string, logger *os.File) error
-type createDatabase func(port uint32, username, password, database string) error
+type initDatabase func(binaryExtractLocation, runtimePath, pgDataDir string, options initdbOptions, logger *os.File) error
+type createDatabase func(config Config, database string, options DatabaseOptions) error
+
+// initdbOptions configure the cluster created by initDatabase.
+type initdbOptions struct {
+	username      string
+	password      string
+	locale        string
+	authMethod    AuthMethod
+	dataChecksums bool
+	noSync        bool
+	cmdHook       func(*exec.Cmd)
+}
 
-func defaultInitDatabase(binaryExtractLocation, runtimePath, pgDataDir, username, password, locale string, logger *os.File) error {
-	passwordFile, err := createPasswordFile(runtimePath, password)
-	if err != nil {
-		return err
+// initdbOptions returns the options of initdb for the configuration.
+func (c Config) initdbOptions() initdbOptions {
+	return initdbOptions{
+		username:      c.username,
+		password:      c.password,
+		locale:        c.locale,
+		authMethod:    c.authMethod,
+		dataChecksums: c.dataChecksums,
+		noSync:        c.initdbNoSync,
+		cmdHook:       c.prepareCommand,
 	}
+}
 
+func defaultInitDatabase(binaryExtractLocation, runtimePath, pgDataDir string, options initdbOptions, logger *os.File) error {
 	args := []string{
-		"-A", "password",
-		"-U", username,
+		"-A", string(options.authMethod),
+		"-U", options.username,
 		"-D", pgDataDir,
-		fmt.Sprintf("--pwfile=%s", passwordFile),
 	}
 
-	if locale != "" {
-		args = append(args, fmt.Sprintf("--locale=%s", locale))
+	// a trusted cluster has no use for the password of the superuser
+	passwordFile := ""
+	if options.authMethod != AuthMethodTrust {
+		file, err := createPasswordFile(runtimePath, options.password)
+		if err != nil {
+			return err
+		}
+
+		passwordFile = file
+
+		args = append(args, fmt.Sprintf("--pwfile=%s", passwordFile))
+	}
+
+	if options.locale != "" {
+		args = append(args, fmt.Sprintf("--locale=%s", options.locale))
+	}
+
+	if options.dataChecksums {
+		args = append(args, "--data-checksums")
+	}
+
+	// -N is spelled --nosync before Postgres 10 and --no-sync since
+	if options.noSync {
+		args = append(args, "-N")
 	}
 
 	postgresInitDBBinary := filepath.Join(binaryExtractLocation, "bin/initdb")
@@ -43,15 +85,23 @@ func defaultInitDatabase(binaryExtractLocation, runtimePath, pgDataDir, username
 	postgresInitDBProcess.Stderr = logger
 	postgresInitDBProcess.Stdout = logger
 
-	if err = postgresInitDBProcess.Run(); err != nil {
+	if options.cmdHook != nil {
+		options.cmdHook(postgresInitDBProcess)
+	}
+
+	if err := postgresInitDBProcess.Run(); err != nil {
 		logContent, readLogsErr := readLogsOrTimeout(logger) // we want to preserve the original error
 		if readLogsErr != nil {
 			logContent = []byte(string(logContent) + " - " + readLogsErr.Error())
 		}
-		return fmt.Errorf("unable to init database using '%s': %w\n%s", postgresInitDBProcess.String(), err, string(logContent))
+		return fmt.Errorf("unable to init database using '%s': %w\n%s%s", postgresInitDBProcess.String(), err, string(logContent), rootUserHint(string(logContent)))
 	}
 
-	if err = os.Remove(passwordFile); err != nil {
+	if passwordFile == "" {
+		return nil
+	}
+
+	if err := os.Remove(passwordFile); err != nil {
 		return fmt.Errorf("unable to remove password file '%v': %w", passwordFile, err)
 	}
 
@@ -67,12 +117,16 @@ func createPasswordFile(runtimePath, password string) (string, error) {
 	return passwordFileLocation, nil
 }
 
-func defaultCreateDatabase(port uint32, username, password, database string) (err error) {
+func defaultCreateDatabase(config Config, database string, options DatabaseOptions) (err error) {
 	if database == "postgres" {
 		return nil
 	}
 
-	conn, err := openDatabaseConnection(port, username, password, "postgres")
+	if err := validateIdentifier("database", database); err != nil {
+		return err
+	}
+
+	conn, err := openDatabaseConnection(config, "postgres")
 	if err != nil {
 		return errorCustomDatabase(database, err)
 	}
@@ -82,13 +136,145 @@ func defaultCreateDatabase(port uint32, username, password, database string) (er
 		err = connectionClose(db, err)
 	}()
 
-	if _, err := db.Exec(fmt.Sprintf("CREATE DATABASE %s", database)); err != nil {
+	if _, err := db.Exec(createDatabaseStatement(database, options)); err != nil {
 		return errorCustomDatabase(database, err)
 	}
 
 	return nil
 }
 
+func createDatabaseStatement(database string, options DatabaseOptions) string {
+	statement := fmt.Sprintf("CREATE DATABASE %s", quoteIdentifier(database))
+
+	if options.Owner != "" {
+		statement += " OWNER " + quoteIdentifier(options.Owner)
+	}
+
+	if options.Template != "" {
+		statement += " TEMPLATE " + quoteIdentifier(options.Template)
+	}
+
+	if options.Encoding != "" {
+		statement += " ENCODING " + quoteLiteral(options.Encoding)
+	}
+
+	if options.Collation != "" {
+		statement += " LC_COLLATE " + quoteLiteral(options.Collation)
+	}
+
+	if options.CharacterType != "" {
+		statement += " LC_CTYPE " + quoteLiteral(options.CharacterType)
+	}
+
+	if options.ConnectionLimit != 0 {
+		statement += fmt.Sprintf(" CONNECTION LIMIT %d", options.ConnectionLimit)
+	}
+
+	return statement
+}
+
+// maxIdentifierLength is the longest identifier Postgres stores, longer identifiers are silently truncated.
+const maxIdentifierLength = 63
+
+// validateIdentifier rejects names which cannot be used as an identifier even when quoted, or which Postgres would
+// truncate to a different name.
+func validateIdentifier(kind, name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%s name must not be empty", kind)
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("%s name %q must not contain NUL characters", kind, name)
+	case len(name) > maxIdentifierLength:
+		return fmt.Errorf("%s name %q is longer than %d bytes", kind, name, maxIdentifierLength)
+	}
+
+	return nil
+}
+
+func dropDatabase(config Config, database string) error {
+	return withDatabaseConnection(config, "postgres", func(db *sql.DB) error {
+		if err := terminateConnections(db, database); err != nil {
+			return err
+		}
+
+		if _, err := db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteIdentifier(database))); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// terminateAllConnections disconnects every other client session from the server, returning a description of each
+// terminated session.
+func terminateAllConnections(config Config) ([]string, error) {
+	return clientConnections(config, true)
+}
+
+// openConnections returns a description of every other client session connected to the server.
+func openConnections(config Config) ([]string, error) {
+	return clientConnections(config, false)
+}
+
+func clientConnections(config Config, terminate bool) ([]string, error) {
+	var connections []string
+
+	activityQuery := `SELECT pid, datname, usename, application_name, COALESCE(state, ''), COALESCE(query, '')
+			FROM pg_stat_activity
+			WHERE pid <> pg_backend_pid() AND datname IS NOT NULL AND usename IS NOT NULL`
+	if terminate {
+		activityQuery += " AND pg_terminate_backend(pid)"
+	}
+
+	err := withDatabaseConnection(config, config.database, func(db *sql.DB) error {
+		rows, err := db.Query(activityQuery)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = rows.Close()
+		}()
+
+		for rows.Next() {
+			var pid int
+			var database, username, applicationName, state, query string
+			if err := rows.Scan(&pid, &database, &username, &applicationName, &state, &query); err != nil {
+				return err
+			}
+
+			connections = append(connections, fmt.Sprintf("pid=%d database=%s user=%s application=%q state=%s query=%q",
+				pid, database, username, applicationName, state, query))
+		}
+
+		return rows.Err()
+	})
+
+	return connections, err
+}
+
+// terminateConnections disconnects any other session connected to the database, which would otherwise prevent it from
+// being dropped or used as a template.
+func terminateConnections(db *sql.DB, database string) error {
+	_, err := db.Exec("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", database)
+	return err
+}
+
+// withDatabaseConnection opens a connection to the database, as the user of the configuration, for the duration of fn.
+func withDatabaseConnection(config Config, database string, fn func(db *sql.DB) error) (err error) {
+	conn, err := openDatabaseConnection(config, database)
+	if err != nil {
+		return err
+	}
+
+	db := sql.OpenDB(conn)
+	defer func() {
+		err = connectionClose(db, err)
+	}()
+
+	return fn(db)
+}
+
 // connectionClose closes the database connection and handles the error of the function that used the database connection
 func connectionClose(db io.Closer, err error) error {
 	closeErr := db.Close()
@@ -106,22 +292,29 @@ func connectionClose(db io.Closer, err error) error {
 }
 
 func healthCheckDatabaseOrTimeout(config Config) error {
-	healthCheckSignal := make(chan bool)
-
-	defer close(healthCheckSignal)
+	// buffered so that the health check never blocks sending after timing out
+	healthCheckSignal := make(chan bool, 1)
 
 	timeout, cancelFunc := context.WithTimeout(context.Background(), config.startTimeout)
 
 	defer cancelFunc()
 
 	go func() {
+		interval := config.healthCheckInterval
+
 		for timeout.Err() == nil {
-			if err := healthCheckDatabase(config.port, config.database, config.username, config.password); err != nil {
-				continue
+			if err := probeReady(config); err == nil {
+				healthCheckSignal <- true
+
+				return
 			}
-			healthCheckSignal <- true
 
-			break
+			select {
+			case <-timeout.Done():
+			case <-time.After(interval):
+			}
+
+			interval = nextHealthCheckInterval(interval, config.healthCheckMaxInterval)
 		}
 	}()
 
@@ -133,30 +326,25 @@ func healthCheckDatabaseOrTimeout(config Config) error {
 	}
 }
 
-func healthCheckDatabase(port uint32, database, username, password string) (err error) {
-	conn, err := openDatabaseConnection(port, username, password, database)
-	if err != nil {
-		return err
+// nextHealthCheckInterval doubles the interval between health checks, up to maxInterval, and never below
+// minHealthCheckInterval.
+func nextHealthCheckInterval(interval, maxInterval time.Duration) time.Duration {
+	interval *= 2
+	if interval > maxInterval {
+		interval = maxInterval
 	}
 
-	db := sql.OpenDB(conn)
-	defer func() {
-		err = connectionClose(db, err)
-	}()
-
-	if _, err := db.Query("SELECT 1"); err != nil {
-		return err
+	if interval < minHealthCheckInterval {
+		return minHealthCheckInterval
 	}
 
-	return nil
+	return interval
 }
 
-func openDatabaseConnection(port uint32, username string, password string, database string) (*pq.Connector, error) {
-	conn, err := pq.NewConnector(fmt.Sprintf("host=localhost port=%d user=%s password=%s dbname=%s sslmode=disable",
-		port,
-		username,
-		password,
-		database))
+// openDatabaseConnection returns a connector for the internal connection the library runs its own statements on, which
+// needs no registered SQLDriver.
+func openDatabaseConnection(config Config, database string) (driver.Connector, error) {
+	conn, err := newWireConnector(internalDSN(config, database))
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +352,37 @@ func openDatabaseConnection(port uint32, username string, password string, datab
 	return conn, nil
 }
 
+// internalDSN returns the key/value DSN with which the library connects to the database as the user of the
+// configuration.
+func internalDSN(config Config, database string) string {
+	dsn := fmt.Sprintf("host=localhost port=%d user=%s password=%s dbname=%s",
+		config.port,
+		connectionValue(config.username),
+		connectionValue(config.password),
+		connectionValue(database))
+
+	settings := config.clientSSLSettings()
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dsn += fmt.Sprintf(" %s=%s", key, connectionValue(settings.Get(key)))
+	}
+
+	return dsn
+}
+
+// connectionValue quotes a value of a key/value connection string, so that names and passwords may contain spaces and
+// quotes.
+func connectionValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
 func errorCustomDatabase(database string, err error) error {
 	return fmt.Errorf("unable to connect to create database with custom name %s with the following error: %s", database, err)
 }
@@ -5,14 +5,19 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_defaultInitDatabase_ErrorWhenCannotCreatePasswordFile(t *testing.T) {
-	err := defaultInitDatabase("path_not_exists", "path_not_exists", "path_not_exists", "Tom", "Beer", "", os.Stderr)
+	err := defaultInitDatabase("path_not_exists", "path_not_exists", "path_not_exists", initdbOptions{username: "Tom", password: "Beer", authMethod: AuthMethodPassword}, os.Stderr)
 
 	assert.EqualError(t, err, "unable to write password file to path_not_exists/pwfile")
 }
@@ -49,7 +54,7 @@ func Test_defaultInitDatabase_ErrorWhenCannotStartInitDBProcess(t *testing.T) {
 
 	_, _ = logFile.Write([]byte("and here are the logs!"))
 
-	err = defaultInitDatabase(binTempDir, runtimeTempDir, filepath.Join(runtimeTempDir, "data"), "Tom", "Beer", "", logFile)
+	err = defaultInitDatabase(binTempDir, runtimeTempDir, filepath.Join(runtimeTempDir, "data"), initdbOptions{username: "Tom", password: "Beer", authMethod: AuthMethodPassword}, logFile)
 
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("unable to init database using '%s/bin/initdb -A password -U Tom -D %s/data --pwfile=%s/pwfile'",
@@ -60,6 +65,46 @@ func Test_defaultInitDatabase_ErrorWhenCannotStartInitDBProcess(t *testing.T) {
 	assert.FileExists(t, filepath.Join(runtimeTempDir, "pwfile"))
 }
 
+func Test_defaultInitDatabase_CmdHook(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var hooked *exec.Cmd
+
+	err := defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "Tom", password: "Beer", authMethod: AuthMethodTrust, cmdHook: func(cmd *exec.Cmd) {
+		hooked = cmd
+	}}, os.Stderr)
+
+	assert.Error(t, err)
+	require.NotNil(t, hooked)
+	assert.Equal(t, filepath.Join(tempDir, "bin/initdb"), hooked.Path)
+}
+
+func Test_defaultInitDatabase_DataChecksums(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var args []string
+
+	_ = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "Tom", password: "Beer", authMethod: AuthMethodTrust, dataChecksums: true, cmdHook: func(cmd *exec.Cmd) {
+		args = cmd.Args
+	}}, os.Stderr)
+
+	assert.Contains(t, args, "--data-checksums")
+	assert.NotContains(t, args, "-N")
+}
+
+func Test_defaultInitDatabase_NoSync(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var args []string
+
+	_ = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "Tom", password: "Beer", authMethod: AuthMethodTrust, noSync: true, cmdHook: func(cmd *exec.Cmd) {
+		args = cmd.Args
+	}}, os.Stderr)
+
+	assert.Contains(t, args, "-N")
+	assert.NotContains(t, args, "--data-checksums")
+}
+
 func Test_defaultInitDatabase_ErrorInvalidLocaleSetting(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "prepare_database_test")
 	if err != nil {
@@ -72,7 +117,7 @@ func Test_defaultInitDatabase_ErrorInvalidLocaleSetting(t *testing.T) {
 		}
 	}()
 
-	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), "postgres", "postgres", "en_XY", os.Stderr)
+	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "postgres", password: "postgres", locale: "en_XY", authMethod: AuthMethodPassword}, os.Stderr)
 
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("unable to init database using '%s/bin/initdb -A password -U postgres -D %s/data --pwfile=%s/pwfile --locale=en_XY'",
@@ -81,6 +126,100 @@ func Test_defaultInitDatabase_ErrorInvalidLocaleSetting(t *testing.T) {
 		tempDir))
 }
 
+func Test_defaultInitDatabase_AuthMethod(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logFile, err := os.Create(filepath.Join(tempDir, "initdb.log"))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = logFile.Close()
+	}()
+
+	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "postgres", password: "postgres", authMethod: AuthMethodScramSHA256}, logFile)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("unable to init database using '%s/bin/initdb -A scram-sha-256 -U postgres -D %s/data --pwfile=%s/pwfile'",
+		tempDir,
+		tempDir,
+		tempDir))
+}
+
+func Test_defaultInitDatabase_TrustSkipsPasswordFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logFile, err := os.Create(filepath.Join(tempDir, "initdb.log"))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = logFile.Close()
+	}()
+
+	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "postgres", password: "postgres", authMethod: AuthMethodTrust}, logFile)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("unable to init database using '%s/bin/initdb -A trust -U postgres -D %s/data'",
+		tempDir,
+		tempDir))
+	assert.NoFileExists(t, filepath.Join(tempDir, "pwfile"))
+}
+
+func Test_Trust(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9870).
+		Trust(true))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9870 user=postgres password=wrong dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.Close())
+}
+
+func Test_TrustConfig(t *testing.T) {
+	assert.Equal(t, AuthMethodTrust, DefaultConfig().Trust(true).authMethod)
+	assert.Equal(t, AuthMethodPassword, DefaultConfig().Trust(true).Trust(false).authMethod)
+	assert.Equal(t, AuthMethodScramSHA256, DefaultConfig().AuthMethod(AuthMethodScramSHA256).Trust(false).authMethod)
+}
+
+func Test_AuthMethodScramSHA256(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9871).
+		AuthMethod(AuthMethodScramSHA256))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9871 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	var passwordEncryption string
+	var scram bool
+	require.NoError(t, db.QueryRow("SHOW password_encryption").Scan(&passwordEncryption))
+	require.NoError(t, db.QueryRow("SELECT rolpassword LIKE 'SCRAM-SHA-256$%' FROM pg_authid WHERE rolname = 'postgres'").Scan(&scram))
+	assert.Equal(t, "scram-sha-256", passwordEncryption)
+	assert.True(t, scram)
+	require.NoError(t, db.Close())
+}
+
 func Test_defaultInitDatabase_PwFileRemoved(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "prepare_database_test")
 	if err != nil {
@@ -111,9 +250,11 @@ func Test_defaultInitDatabase_PwFileRemoved(t *testing.T) {
 }
 
 func Test_defaultCreateDatabase_ErrorWhenSQLOpenError(t *testing.T) {
-	err := defaultCreateDatabase(1234, "user client_encoding=lol", "password", "database")
+	err := defaultCreateDatabase(DefaultConfig().Port(1234).Username("user client_encoding=lol").Password("password"), "database", DatabaseOptions{})
 
-	assert.EqualError(t, err, "unable to connect to create database with custom name database with the following error: client_encoding must be absent or 'UTF8'")
+	// the username is quoted rather than parsed as a client_encoding setting, so connecting fails instead
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to connect to create database with custom name database with the following error: dial tcp")
 }
 
 func Test_defaultCreateDatabase_ErrorWhenQueryError(t *testing.T) {
@@ -130,15 +271,16 @@ func Test_defaultCreateDatabase_ErrorWhenQueryError(t *testing.T) {
 		}
 	}()
 
-	err := defaultCreateDatabase(9831, "postgres", "postgres", "b33r")
+	err := defaultCreateDatabase(DefaultConfig().Port(9831), "b33r", DatabaseOptions{})
 
-	assert.EqualError(t, err, `unable to connect to create database with custom name b33r with the following error: pq: database "b33r" already exists`)
+	assert.EqualError(t, err, `unable to connect to create database with custom name b33r with the following error: ERROR: database "b33r" already exists (SQLSTATE 42P04)`)
 }
 
-func Test_healthCheckDatabase_ErrorWhenSQLConnectingError(t *testing.T) {
-	err := healthCheckDatabase(1234, "tom client_encoding=lol", "more", "b33r")
+func Test_probeReady_ErrorWhenConnectingError(t *testing.T) {
+	err := probeReady(DefaultConfig().Port(1234).Username("more").Password("b33r").Database("tom client_encoding=lol"))
 
-	assert.EqualError(t, err, "client_encoding must be absent or 'UTF8'")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dial tcp")
 }
 
 type CloserWithoutErr struct{}
@@ -234,3 +376,140 @@ func TestConnCloserWithErr(t *testing.T) {
 		})
 	}
 }
+
+func Test_createDatabaseStatement(t *testing.T) {
+	assert.Equal(t, `CREATE DATABASE "beer"`, createDatabaseStatement("beer", DatabaseOptions{}))
+	assert.Equal(t, `CREATE DATABASE "My-DB"`, createDatabaseStatement("My-DB", DatabaseOptions{}))
+	assert.Equal(t, `CREATE DATABASE "x"" WITH OWNER ""evil"`, createDatabaseStatement(`x" WITH OWNER "evil`, DatabaseOptions{}))
+
+	assert.Equal(t,
+		`CREATE DATABASE "beer" OWNER "app" TEMPLATE "template0" ENCODING 'UTF8' LC_COLLATE 'C' LC_CTYPE 'en_US.UTF-8' CONNECTION LIMIT 10`,
+		createDatabaseStatement("beer", DatabaseOptions{
+			Owner:           "app",
+			Template:        "template0",
+			Encoding:        "UTF8",
+			Collation:       "C",
+			CharacterType:   "en_US.UTF-8",
+			ConnectionLimit: 10,
+		}))
+}
+
+func Test_healthCheckDatabaseOrTimeout_TimesOut(t *testing.T) {
+	config := DefaultConfig().
+		Port(9872).
+		StartTimeout(300*time.Millisecond).
+		HealthCheckInterval(50*time.Millisecond, 100*time.Millisecond)
+
+	err := healthCheckDatabaseOrTimeout(config)
+
+	assert.EqualError(t, err, "timed out waiting for database to become available")
+}
+
+func Test_nextHealthCheckInterval(t *testing.T) {
+	assert.Equal(t, 20*time.Millisecond, nextHealthCheckInterval(10*time.Millisecond, 250*time.Millisecond))
+	assert.Equal(t, 250*time.Millisecond, nextHealthCheckInterval(200*time.Millisecond, 250*time.Millisecond))
+	assert.Equal(t, minHealthCheckInterval, nextHealthCheckInterval(0, 0))
+	assert.Equal(t, minHealthCheckInterval, nextHealthCheckInterval(-time.Second, -time.Second))
+}
+
+func Test_validateIdentifier(t *testing.T) {
+	assert.NoError(t, validateIdentifier("database", "order"))
+	assert.NoError(t, validateIdentifier("database", "My-Database"))
+	assert.NoError(t, validateIdentifier("database", strings.Repeat("a", 63)))
+
+	assert.EqualError(t, validateIdentifier("database", ""), "database name must not be empty")
+	assert.EqualError(t, validateIdentifier("role", "a\x00b"), `role name "a\x00b" must not contain NUL characters`)
+	assert.EqualError(t, validateIdentifier("database", strings.Repeat("a", 64)), fmt.Sprintf("database name %q is longer than 63 bytes", strings.Repeat("a", 64)))
+}
+
+func Test_defaultCreateDatabase_ErrorWhenNameInvalid(t *testing.T) {
+	err := defaultCreateDatabase(DefaultConfig().Port(9999), "", DatabaseOptions{})
+
+	assert.EqualError(t, err, "database name must not be empty")
+}
+
+func Test_connectionValue(t *testing.T) {
+	assert.Equal(t, `'My DB'`, connectionValue("My DB"))
+	assert.Equal(t, `'it\'s'`, connectionValue("it's"))
+	assert.Equal(t, `'back\\slash'`, connectionValue(`back\slash`))
+	assert.Equal(t, `''`, connectionValue(""))
+}
+
+func Test_defaultInitDatabase_ExplainsRootUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of initdb")
+	}
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "bin", "initdb"), []byte("#!/bin/sh\necho 'initdb: error: cannot be run as root'\nexit 1\n"), 0755))
+
+	logFile, err := os.Create(filepath.Join(tempDir, "initdb.log"))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = logFile.Close()
+	}()
+
+	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "postgres", password: "postgres", authMethod: AuthMethodPassword}, logFile)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to init database using")
+	assert.Contains(t, err.Error(), "Run the process using embedded-postgres as an unprivileged user instead")
+}
+
+func Test_defaultInitDatabase_NoRootUserHintForOtherErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logFile, err := os.Create(filepath.Join(tempDir, "initdb.log"))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = logFile.Close()
+	}()
+
+	err = defaultInitDatabase(tempDir, tempDir, filepath.Join(tempDir, "data"), initdbOptions{username: "postgres", password: "postgres", authMethod: AuthMethodPassword}, logFile)
+
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "Postgres refuses to run as the root user")
+}
+
+func Test_Config_initdbOptions(t *testing.T) {
+	options := DefaultConfig().
+		Username("gin").
+		Password("wine").
+		Locale("C").
+		AuthMethod(AuthMethodScramSHA256).
+		DataChecksums(true).
+		InitdbNoSync(true).
+		initdbOptions()
+
+	assert.Equal(t, "gin", options.username)
+	assert.Equal(t, "wine", options.password)
+	assert.Equal(t, "C", options.locale)
+	assert.Equal(t, AuthMethodScramSHA256, options.authMethod)
+	assert.True(t, options.dataChecksums)
+	assert.True(t, options.noSync)
+}
+
+func Test_HealthCheckInterval_Minimum(t *testing.T) {
+	config := DefaultConfig().HealthCheckInterval(0, -time.Second)
+
+	assert.Equal(t, minHealthCheckInterval, config.healthCheckInterval)
+	assert.Equal(t, minHealthCheckInterval, config.healthCheckMaxInterval)
+
+	config = DefaultConfig().HealthCheckInterval(50*time.Millisecond, 20*time.Millisecond)
+
+	assert.Equal(t, 50*time.Millisecond, config.healthCheckInterval)
+	assert.Equal(t, 50*time.Millisecond, config.healthCheckMaxInterval)
+}
+
+func Test_internalDSN(t *testing.T) {
+	config := DefaultConfig().Port(9999).Username("gin").Password("it's wine")
+
+	assert.Equal(t, `host=localhost port=9999 user='gin' password='it\'s wine' dbname='beer' sslmode='disable'`, internalDSN(config, "beer"))
+
+	dsn := internalDSN(config.RuntimePath("/runtime").DataPath("/data").RequireClientCertificates(true), "beer")
+	assert.Contains(t, dsn, "sslcert=")
+	assert.Contains(t, dsn, "sslmode='verify-full'")
+}
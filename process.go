@@ -0,0 +1,285 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runningAsRoot reports whether this process runs as the root user, which Postgres refuses to run as. It is a variable
+// so that tests can simulate either user.
+var runningAsRoot = func() bool {
+	return os.Geteuid() == 0
+}
+
+// rootUserAdvice explains how to avoid running Postgres as root, which is common in Docker based CI.
+const rootUserAdvice = "Postgres refuses to run as the root user for security reasons. Run the process using " +
+	"embedded-postgres as an unprivileged user instead, for example with a USER instruction in the Dockerfile, " +
+	"docker run --user or su, making sure that user can write to the cache, runtime and data directories."
+
+// rootUserHint returns rootUserAdvice, to be appended to the error of a failed initdb, pg_ctl or pg_upgrade, when their
+// output shows they refused to run as root. It is empty when they failed for any other reason.
+func rootUserHint(output string) string {
+	if !strings.Contains(output, "cannot be run as root") {
+		return ""
+	}
+
+	return "\n" + rootUserAdvice
+}
+
+// prepareCommand applies the configured environment variables and then the command hook to the initdb or pg_ctl start
+// command.
+func (c Config) prepareCommand(cmd *exec.Cmd) {
+	if len(c.env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+
+		names := make([]string, 0, len(c.env))
+		for name := range c.env {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		// later entries take precedence over inherited ones of the same name
+		for _, name := range names {
+			cmd.Env = append(cmd.Env, name+"="+c.env[name])
+		}
+	}
+
+	if c.cmdHook != nil {
+		c.cmdHook(cmd)
+	}
+}
+
+// readPostmasterPID reads the process ID of the running server from the postmaster.pid file in the data directory.
+func readPostmasterPID(dataPath string) (int, error) {
+	lines, err := readPostmasterFile(dataPath)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return 0, fmt.Errorf("postmaster.pid in %s is invalid: %w", dataPath, err)
+	}
+
+	return pid, nil
+}
+
+// readPostmasterStartTime reads when the running server started from the postmaster.pid file in the data directory.
+func readPostmasterStartTime(dataPath string) (time.Time, error) {
+	lines, err := readPostmasterFile(dataPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(lines) < 3 {
+		return time.Time{}, fmt.Errorf("postmaster.pid in %s has no start time", dataPath)
+	}
+
+	seconds, err := strconv.ParseInt(lines[2], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("postmaster.pid in %s has an invalid start time: %w", dataPath, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+func readPostmasterFile(dataPath string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dataPath, "postmaster.pid"))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("postmaster.pid in %s is empty", dataPath)
+	}
+
+	return lines, nil
+}
+
+// postmasterStartTolerance bounds how much the start time of a process may differ from the start time recorded in
+// postmaster.pid for it to be the postmaster, which records the time in whole seconds shortly after it has started.
+const postmasterStartTolerance = 5 * time.Second
+
+// isRecordedPostmaster reports whether the process is the postmaster which wrote postmaster.pid in the data directory,
+// rather than an unrelated process which was given its process ID after a reboot or once the postmaster had exited.
+func isRecordedPostmaster(dataPath string, pid int) (bool, error) {
+	recorded, err := readPostmasterStartTime(dataPath)
+	if err != nil {
+		return false, err
+	}
+
+	started, err := processStartTime(pid)
+	if err != nil {
+		return false, err
+	}
+
+	difference := started.Sub(recorded)
+	if difference < 0 {
+		difference = -difference
+	}
+
+	return difference <= postmasterStartTolerance, nil
+}
+
+// DataDirectoryInUseError is returned by Start when the data directory is in use by a running Postgres process, for
+// example one left behind by a test run which was killed before it could stop the server.
+type DataDirectoryInUseError struct {
+	DataPath string
+	PID      int
+}
+
+func (e *DataDirectoryInUseError) Error() string {
+	return fmt.Sprintf("data directory %s is in use by running postgres process %d: stop it with 'pg_ctl stop -D %s' or kill the process before starting again",
+		e.DataPath, e.PID, e.DataPath)
+}
+
+// removeStalePostmasterPID removes a postmaster.pid file left in the data directory by a server which is no longer
+// running, returning a DataDirectoryInUseError when the recorded process is still alive.
+func removeStalePostmasterPID(dataPath string) error {
+	pid, err := readPostmasterPID(dataPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err == nil && processAlive(pid) {
+		return &DataDirectoryInUseError{DataPath: dataPath, PID: pid}
+	}
+
+	if err := os.Remove(filepath.Join(dataPath, "postmaster.pid")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove stale postmaster.pid from %s: %w", dataPath, err)
+	}
+
+	return nil
+}
+
+// ownerFileName is the file in the data directory recording the process ID of the Go process which started the server.
+const ownerFileName = "embedded-postgres.owner"
+
+// writeOwnerPID records the current process as the owner of the server running in the data directory.
+func writeOwnerPID(dataPath string) error {
+	return os.WriteFile(filepath.Join(dataPath, ownerFileName), []byte(strconv.Itoa(os.Getpid())), 0600)
+}
+
+func removeOwnerPID(dataPath string) {
+	_ = os.Remove(filepath.Join(dataPath, ownerFileName))
+}
+
+// isOrphanedServer reports whether the server running in the data directory was started by a process which has since
+// exited without stopping it, such as a test binary which was killed.
+func isOrphanedServer(dataPath string) bool {
+	content, err := os.ReadFile(filepath.Join(dataPath, ownerFileName))
+	if err != nil {
+		return false
+	}
+
+	owner, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return false
+	}
+
+	return owner != os.Getpid() && !processAlive(owner)
+}
+
+// stopOrphanedServer stops a server left running by an exited owner, asking it to shut down before killing it if it has
+// not exited within the timeout. The process is only signalled once its start time confirms it is the postmaster
+// recorded in the data directory, otherwise a DataDirectoryInUseError is returned and it is left alone.
+func stopOrphanedServer(dataPath string, pid int, timeout time.Duration) error {
+	if recorded, err := isRecordedPostmaster(dataPath, pid); err != nil || !recorded {
+		return &DataDirectoryInUseError{DataPath: dataPath, PID: pid}
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	// interrupting the postmaster requests a fast shutdown, which is not supported on Windows
+	if err := process.Signal(os.Interrupt); err != nil || !waitForExit(pid, timeout) {
+		if err := process.Kill(); err != nil {
+			return fmt.Errorf("unable to stop orphaned postgres process %d: %w", pid, err)
+		}
+
+		if !waitForExit(pid, timeout) {
+			return fmt.Errorf("orphaned postgres process %d did not exit after %s", pid, timeout)
+		}
+	}
+
+	removeOwnerPID(dataPath)
+
+	return removeStalePostmasterPID(dataPath)
+}
+
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(processMonitorInterval)
+	}
+
+	return true
+}
+
+// killPostgres kills the server process recorded in the data directory as a last resort when it cannot be stopped.
+func killPostgres(dataPath string) error {
+	pid, err := readPostmasterPID(dataPath)
+	if err != nil {
+		return err
+	}
+
+	return killProcess(pid)
+}
+
+// killProcess kills the process immediately.
+func killProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return process.Kill()
+}
+
+// processMonitorInterval is how often a running server process is checked for an unexpected exit.
+var processMonitorInterval = 250 * time.Millisecond
+
+// watchProcess polls alive until it reports false, returning true, or until stop is closed, returning false.
+func watchProcess(stop <-chan struct{}, alive func() bool) bool {
+	ticker := time.NewTicker(processMonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+			if !alive() {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readPostmasterPID(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte("4242\n/data\n1690000000\n5432\n"), 0600))
+
+	pid, err := readPostmasterPID(dataPath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4242, pid)
+}
+
+func Test_readPostmasterPID_ErrorWhenInvalid(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte("not a pid\n"), 0600))
+
+	_, err := readPostmasterPID(dataPath)
+
+	assert.EqualError(t, err, "postmaster.pid in "+dataPath+` is invalid: strconv.Atoi: parsing "not a pid": invalid syntax`)
+}
+
+func Test_readPostmasterPID_ErrorWhenEmpty(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), nil, 0600))
+
+	_, err := readPostmasterPID(dataPath)
+
+	assert.EqualError(t, err, "postmaster.pid in "+dataPath+" is empty")
+}
+
+func Test_killPostgres_ErrorWhenNotRunning(t *testing.T) {
+	err := killPostgres(t.TempDir())
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_processAlive(t *testing.T) {
+	assert.True(t, processAlive(os.Getpid()))
+
+	process := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, process.Run())
+
+	assert.False(t, processAlive(process.Process.Pid))
+}
+
+func Test_watchProcess_Exited(t *testing.T) {
+	assert.True(t, watchProcess(make(chan struct{}), func() bool { return false }))
+}
+
+func Test_watchProcess_Stop(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	assert.False(t, watchProcess(stop, func() bool { return true }))
+}
+
+func Test_removeStalePostmasterPID(t *testing.T) {
+	process := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, process.Run())
+
+	dataPath := t.TempDir()
+	pidFile := filepath.Join(dataPath, "postmaster.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(process.Process.Pid)+"\n"), 0600))
+
+	assert.NoError(t, removeStalePostmasterPID(dataPath))
+	assert.NoFileExists(t, pidFile)
+}
+
+func Test_removeStalePostmasterPID_RemovesInvalidFile(t *testing.T) {
+	dataPath := t.TempDir()
+	pidFile := filepath.Join(dataPath, "postmaster.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("garbage"), 0600))
+
+	assert.NoError(t, removeStalePostmasterPID(dataPath))
+	assert.NoFileExists(t, pidFile)
+}
+
+func Test_removeStalePostmasterPID_NoFile(t *testing.T) {
+	assert.NoError(t, removeStalePostmasterPID(t.TempDir()))
+}
+
+func Test_removeStalePostmasterPID_ErrorWhenRunning(t *testing.T) {
+	dataPath := t.TempDir()
+	pidFile := filepath.Join(dataPath, "postmaster.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600))
+
+	err := removeStalePostmasterPID(dataPath)
+
+	var inUseErr *DataDirectoryInUseError
+	require.ErrorAs(t, err, &inUseErr)
+	assert.Equal(t, os.Getpid(), inUseErr.PID)
+	assert.FileExists(t, pidFile)
+	assert.EqualError(t, err, fmt.Sprintf("data directory %s is in use by running postgres process %d: stop it with 'pg_ctl stop -D %s' or kill the process before starting again",
+		dataPath, os.Getpid(), dataPath))
+}
+
+func Test_isOrphanedServer(t *testing.T) {
+	process := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, process.Run())
+
+	dataPath := t.TempDir()
+	assert.False(t, isOrphanedServer(dataPath))
+
+	require.NoError(t, writeOwnerPID(dataPath))
+	assert.False(t, isOrphanedServer(dataPath))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, ownerFileName), []byte(strconv.Itoa(process.Process.Pid)), 0600))
+	assert.True(t, isOrphanedServer(dataPath))
+
+	removeOwnerPID(dataPath)
+	assert.NoFileExists(t, filepath.Join(dataPath, ownerFileName))
+}
+
+func Test_stopOrphanedServer(t *testing.T) {
+	orphan := exec.Command(os.Args[0], "-test.run=^Test_helperSleep$")
+	orphan.Env = append(os.Environ(), "EMBEDDED_POSTGRES_HELPER_SLEEP=1")
+	require.NoError(t, orphan.Start())
+
+	// reap the helper once it exits so that it does not linger as a zombie
+	go func() {
+		_ = orphan.Wait()
+	}()
+
+	dataPath := t.TempDir()
+	writePostmasterPID(t, dataPath, orphan.Process.Pid, time.Now())
+
+	err := stopOrphanedServer(dataPath, orphan.Process.Pid, 5*time.Second)
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dataPath, "postmaster.pid"))
+}
+
+func Test_stopOrphanedServer_LeavesReusedPID(t *testing.T) {
+	unrelated := exec.Command(os.Args[0], "-test.run=^Test_helperSleep$")
+	unrelated.Env = append(os.Environ(), "EMBEDDED_POSTGRES_HELPER_SLEEP=1")
+	require.NoError(t, unrelated.Start())
+
+	defer func() {
+		_ = unrelated.Process.Kill()
+		_ = unrelated.Wait()
+	}()
+
+	// the server recorded in the data directory started long before the process which now has its process ID
+	dataPath := t.TempDir()
+	writePostmasterPID(t, dataPath, unrelated.Process.Pid, time.Now().Add(-time.Hour))
+
+	err := stopOrphanedServer(dataPath, unrelated.Process.Pid, 5*time.Second)
+
+	var inUseErr *DataDirectoryInUseError
+	assert.ErrorAs(t, err, &inUseErr)
+	assert.True(t, processAlive(unrelated.Process.Pid))
+	assert.FileExists(t, filepath.Join(dataPath, "postmaster.pid"))
+}
+
+func Test_isRecordedPostmaster(t *testing.T) {
+	dataPath := t.TempDir()
+
+	started, err := processStartTime(os.Getpid())
+	require.NoError(t, err)
+
+	writePostmasterPID(t, dataPath, os.Getpid(), started)
+
+	recorded, err := isRecordedPostmaster(dataPath, os.Getpid())
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	writePostmasterPID(t, dataPath, os.Getpid(), started.Add(-time.Minute))
+
+	recorded, err = isRecordedPostmaster(dataPath, os.Getpid())
+	assert.NoError(t, err)
+	assert.False(t, recorded)
+}
+
+func Test_isRecordedPostmaster_ErrorWithoutStartTime(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte(strconv.Itoa(os.Getpid())+"\n"), 0600))
+
+	_, err := isRecordedPostmaster(dataPath, os.Getpid())
+
+	assert.EqualError(t, err, "postmaster.pid in "+dataPath+" has no start time")
+}
+
+func Test_readPostmasterStartTime(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte("4242\n/data\n1690000000\n5432\n"), 0600))
+
+	started, err := readPostmasterStartTime(dataPath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1690000000, 0), started)
+}
+
+func Test_processStartTime(t *testing.T) {
+	process := exec.Command(os.Args[0], "-test.run=^Test_helperSleep$")
+	process.Env = append(os.Environ(), "EMBEDDED_POSTGRES_HELPER_SLEEP=1")
+
+	before := time.Now().Truncate(time.Second)
+	require.NoError(t, process.Start())
+
+	defer func() {
+		_ = process.Process.Kill()
+		_ = process.Wait()
+	}()
+
+	started, err := processStartTime(process.Process.Pid)
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, before, started, postmasterStartTolerance)
+}
+
+// writePostmasterPID writes the lines of postmaster.pid which record the process ID and start time of the server.
+func writePostmasterPID(t *testing.T, dataPath string, pid int, started time.Time) {
+	t.Helper()
+
+	content := fmt.Sprintf("%d\n%s\n%d\n5432\n", pid, dataPath, started.Unix())
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postmaster.pid"), []byte(content), 0600))
+}
+
+func Test_helperSleep(t *testing.T) {
+	if os.Getenv("EMBEDDED_POSTGRES_HELPER_SLEEP") == "" {
+		t.Skip("only runs as a helper process")
+	}
+
+	time.Sleep(time.Minute)
+}
+
+func Test_rootUserHint(t *testing.T) {
+	assert.Empty(t, rootUserHint("initdb: error: could not create directory \"/data\": Permission denied"))
+	assert.Contains(t, rootUserHint("initdb: error: cannot be run as root"), "Postgres refuses to run as the root user")
+	assert.Contains(t, rootUserHint("pg_ctl: cannot be run as root"), "Run the process using embedded-postgres as an unprivileged user")
+}
+
+func Test_killProcess(t *testing.T) {
+	process := exec.Command(os.Args[0], "-test.run=^Test_helperSleep$")
+	process.Env = append(os.Environ(), "EMBEDDED_POSTGRES_HELPER_SLEEP=1")
+	require.NoError(t, process.Start())
+
+	require.NoError(t, killProcess(process.Process.Pid))
+
+	assert.Error(t, process.Wait())
+}
+
+func Test_prepareCommand(t *testing.T) {
+	t.Setenv("TZ", "Europe/London")
+
+	var envSeenByHook []string
+
+	config := DefaultConfig().
+		Env(map[string]string{"TZ": "UTC", "LC_ALL": "C"}).
+		Env(map[string]string{"LC_ALL": "en_US.UTF-8"}).
+		CmdHook(func(cmd *exec.Cmd) {
+			envSeenByHook = cmd.Env
+		})
+
+	cmd := exec.Command("initdb")
+	config.prepareCommand(cmd)
+
+	assert.Equal(t, cmd.Env, envSeenByHook)
+	assert.Equal(t, []string{"LC_ALL=en_US.UTF-8", "TZ=UTC"}, cmd.Env[len(cmd.Env)-2:])
+	assert.Contains(t, cmd.Env, "TZ=Europe/London")
+}
+
+func Test_prepareCommand_InheritsEnvironmentWithoutEnv(t *testing.T) {
+	cmd := exec.Command("initdb")
+	DefaultConfig().prepareCommand(cmd)
+
+	assert.Nil(t, cmd.Env)
+}
@@ -0,0 +1,98 @@
+//go:build !windows
+// +build !windows
+
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processAlive reports whether the process exists, by sending it the null signal.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// killProcessGroup kills the postmaster together with all server processes, which share its process group as pg_ctl
+// starts the postmaster as a process group leader.
+func killProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// pauseProcessGroup suspends the postmaster and all server processes until resumed with resumeProcessGroup.
+func pauseProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGSTOP)
+}
+
+func resumeProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGCONT)
+}
+
+// clockTicksPerSecond is the unit of process start times in /proc, which Linux fixes at 100 for user space.
+const clockTicksPerSecond = 100
+
+// processStartTime returns when the process started, read from /proc on Linux and from ps elsewhere.
+func processStartTime(pid int) (time.Time, error) {
+	if stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		return procStartTime(string(stat))
+	}
+
+	ps := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid))
+	ps.Env = append(os.Environ(), "LC_ALL=C")
+
+	output, err := ps.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to read start time of process %d: %w", pid, err)
+	}
+
+	return time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(strings.Fields(string(output)), " "), time.Local)
+}
+
+// procStartTime reads the start time from the contents of /proc/<pid>/stat, which counts clock ticks since boot.
+func procStartTime(stat string) (time.Time, error) {
+	// the command name in parentheses may contain spaces, the start time is the 20th field following it
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 20 {
+		return time.Time{}, errors.New("unable to parse process start time from /proc")
+	}
+
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse process start time from /proc: %w", err)
+	}
+
+	bootTime, err := procBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return bootTime.Add(time.Duration(ticks) * time.Second / clockTicksPerSecond), nil
+}
+
+func procBootTime() (time.Time, error) {
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to read boot time: %w", err)
+	}
+
+	for _, line := range strings.Split(string(stat), "\n") {
+		if value := strings.TrimPrefix(line, "btime "); value != line {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unable to parse boot time: %w", err)
+			}
+
+			return time.Unix(seconds, 0), nil
+		}
+	}
+
+	return time.Time{}, errors.New("unable to find boot time in /proc/stat")
+}
@@ -0,0 +1,66 @@
+//go:build windows
+// +build windows
+
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// stillActive is the exit code reported by GetExitCodeProcess for a process which has not exited.
+const stillActive = 259
+
+// processAlive reports whether the process exists and has not exited.
+func processAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+
+	defer func() {
+		_ = syscall.CloseHandle(handle)
+	}()
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+
+	return exitCode == stillActive
+}
+
+// processStartTime returns when the process was created.
+func processStartTime(pid int) (time.Time, error) {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to open process %d: %w", pid, err)
+	}
+
+	defer func() {
+		_ = syscall.CloseHandle(handle)
+	}()
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, fmt.Errorf("unable to read start time of process %d: %w", pid, err)
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// killProcessGroup kills the postmaster, Windows has no process groups to signal but the server processes exit once
+// the postmaster has died.
+func killProcessGroup(pid int) error {
+	return killProcess(pid)
+}
+
+func pauseProcessGroup(int) error {
+	return errors.New("pausing postgres is not supported on Windows")
+}
+
+func resumeProcessGroup(int) error {
+	return errors.New("pausing postgres is not supported on Windows")
+}
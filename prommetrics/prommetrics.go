@@ -0,0 +1,112 @@
+// Package prommetrics provides Prometheus metrics of the lifecycle of embedded Postgres servers, so that the time and
+// bandwidth spent on the embedded database in CI can be tracked.
+package prommetrics
+
+import (
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// durationBuckets range from 100 milliseconds to almost a minute, covering a cached start as well as a slow download.
+var durationBuckets = prometheus.ExponentialBuckets(0.1, 2, 10)
+
+// Collector is a prometheus.Collector of the lifecycle events of the servers it observes. A single Collector can
+// observe any number of servers.
+type Collector struct {
+	downloadDuration   prometheus.Histogram
+	downloadBytes      prometheus.Counter
+	extractionDuration prometheus.Histogram
+	initdbDuration     prometheus.Histogram
+	readyDuration      prometheus.Histogram
+	restarts           prometheus.Counter
+	crashes            prometheus.Counter
+}
+
+// NewCollector creates a Collector, to be registered with a prometheus.Registerer and added to the configuration of
+// each server with Instrument.
+func NewCollector() *Collector {
+	return &Collector{
+		downloadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "embedded_postgres_download_duration_seconds",
+			Help:    "Time taken to download the Postgres binaries.",
+			Buckets: durationBuckets,
+		}),
+		downloadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "embedded_postgres_download_bytes_total",
+			Help: "Size of the downloaded Postgres binaries.",
+		}),
+		extractionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "embedded_postgres_extraction_duration_seconds",
+			Help:    "Time taken to extract the Postgres binaries.",
+			Buckets: durationBuckets,
+		}),
+		initdbDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "embedded_postgres_initdb_duration_seconds",
+			Help:    "Time taken to initialize a data directory.",
+			Buckets: durationBuckets,
+		}),
+		readyDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "embedded_postgres_ready_duration_seconds",
+			Help:    "Time taken from starting a server until it is ready for use.",
+			Buckets: durationBuckets,
+		}),
+		restarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "embedded_postgres_restarts_total",
+			Help: "Number of times a server was restarted after its process exited unexpectedly.",
+		}),
+		crashes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "embedded_postgres_crashes_total",
+			Help: "Number of times the process of a server exited unexpectedly.",
+		}),
+	}
+}
+
+// Instrument adds the collector to the lifecycle hooks of the configuration.
+func (c *Collector) Instrument(config embeddedpostgres.Config) embeddedpostgres.Config {
+	return config.OnLifecycleEvent(c.Observe)
+}
+
+// Observe records a lifecycle event, and can be used as an embeddedpostgres.LifecycleHook.
+func (c *Collector) Observe(event embeddedpostgres.LifecycleEvent) {
+	switch event.Type {
+	case embeddedpostgres.EventDownloadFinished:
+		c.downloadDuration.Observe(event.Elapsed.Seconds())
+		c.downloadBytes.Add(float64(event.Bytes))
+	case embeddedpostgres.EventExtracted:
+		c.extractionDuration.Observe(event.Elapsed.Seconds())
+	case embeddedpostgres.EventInitDbCompleted:
+		c.initdbDuration.Observe(event.Elapsed.Seconds())
+	case embeddedpostgres.EventReady:
+		c.readyDuration.Observe(event.Elapsed.Seconds())
+	case embeddedpostgres.EventRestarted:
+		c.restarts.Inc()
+	case embeddedpostgres.EventCrashed:
+		c.crashes.Inc()
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.downloadDuration,
+		c.downloadBytes,
+		c.extractionDuration,
+		c.initdbDuration,
+		c.readyDuration,
+		c.restarts,
+		c.crashes,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(descriptions chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(descriptions)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(metrics chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(metrics)
+	}
+}
@@ -0,0 +1,51 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Collector(t *testing.T) {
+	collector := NewCollector()
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	for _, event := range []embeddedpostgres.LifecycleEvent{
+		{Type: embeddedpostgres.EventDownloadStarted},
+		{Type: embeddedpostgres.EventDownloadFinished, Elapsed: 3 * time.Second, Bytes: 1024},
+		{Type: embeddedpostgres.EventExtracted, Elapsed: time.Second},
+		{Type: embeddedpostgres.EventInitDbCompleted, Elapsed: 2 * time.Second},
+		{Type: embeddedpostgres.EventReady, Elapsed: 7 * time.Second},
+		{Type: embeddedpostgres.EventCrashed},
+		{Type: embeddedpostgres.EventRestarted, Elapsed: time.Second},
+		{Type: embeddedpostgres.EventStopped, Elapsed: time.Second},
+	} {
+		collector.Observe(event)
+	}
+
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP embedded_postgres_crashes_total Number of times the process of a server exited unexpectedly.
+# TYPE embedded_postgres_crashes_total counter
+embedded_postgres_crashes_total 1
+# HELP embedded_postgres_download_bytes_total Size of the downloaded Postgres binaries.
+# TYPE embedded_postgres_download_bytes_total counter
+embedded_postgres_download_bytes_total 1024
+# HELP embedded_postgres_restarts_total Number of times a server was restarted after its process exited unexpectedly.
+# TYPE embedded_postgres_restarts_total counter
+embedded_postgres_restarts_total 1
+`), "embedded_postgres_crashes_total", "embedded_postgres_download_bytes_total", "embedded_postgres_restarts_total"))
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.readyDuration))
+}
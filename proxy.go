@@ -0,0 +1,236 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// proxyBufferSize is the size of the buffer used to forward traffic in each direction of a proxied connection.
+const proxyBufferSize = 32 * 1024
+
+// Proxy is a TCP proxy in front of a started server, which can delay, hold or drop traffic to simulate network faults
+// between an application and its database. A Proxy is safe for concurrent use.
+type Proxy struct {
+	listener net.Listener
+	target   string
+
+	mu          sync.Mutex
+	latency     time.Duration
+	blackholed  bool
+	traffic     chan struct{}
+	reject      bool
+	connections map[*proxyConnection]struct{}
+
+	wg sync.WaitGroup
+}
+
+// proxyConnection is a client connection to the proxy together with its connection to the server.
+type proxyConnection struct {
+	client    net.Conn
+	server    net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *proxyConnection) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.client.Close()
+		_ = c.server.Close()
+	})
+}
+
+// StartProxy starts a TCP proxy listening on the port, or on a free port when the port is 0, which forwards connections
+// to the server. Clients connect to the port returned by Port instead of the server, so that latency, dropped
+// connections and unreachable networks can be simulated to test timeouts and retries. Close the proxy once done.
+func (ep *EmbeddedPostgres) StartProxy(port uint32) (*Proxy, error) {
+	if !ep.started {
+		return nil, errors.New("server has not been started")
+	}
+
+	return newProxy(port, fmt.Sprintf("localhost:%d", ep.config.port))
+}
+
+func newProxy(port uint32, target string) (*Proxy, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("unable to start proxy on port %d: %w", port, err)
+	}
+
+	traffic := make(chan struct{})
+	close(traffic)
+
+	proxy := &Proxy{
+		listener:    listener,
+		target:      target,
+		traffic:     traffic,
+		connections: map[*proxyConnection]struct{}{},
+	}
+
+	proxy.wg.Add(1)
+	go proxy.accept()
+
+	return proxy, nil
+}
+
+// Port returns the port the proxy listens on.
+func (p *Proxy) Port() uint32 {
+	return uint32(p.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// SetLatency delays all traffic through the proxy by the latency in each direction, or no longer delays it when 0.
+func (p *Proxy) SetLatency(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.latency = latency
+}
+
+// Blackhole holds all traffic through the proxy while enabled, as if the network became unreachable, so that clients
+// wait for responses until they time out. Held traffic is forwarded once disabled.
+func (p *Proxy) Blackhole(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if enabled == p.blackholed {
+		return
+	}
+
+	if enabled {
+		p.traffic = make(chan struct{})
+	} else {
+		close(p.traffic)
+	}
+
+	p.blackholed = enabled
+}
+
+// RejectConnections closes new connections to the proxy as soon as they are accepted while enabled, as if the server
+// went away. Existing connections are kept, and can be closed with DropConnections.
+func (p *Proxy) RejectConnections(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.reject = enabled
+}
+
+// DropConnections closes every connection through the proxy, as if the network connection was lost.
+func (p *Proxy) DropConnections() {
+	p.mu.Lock()
+	connections := make([]*proxyConnection, 0, len(p.connections))
+	for connection := range p.connections {
+		connections = append(connections, connection)
+	}
+	p.mu.Unlock()
+
+	for _, connection := range connections {
+		p.remove(connection)
+	}
+}
+
+// Close stops the proxy and closes every connection through it.
+func (p *Proxy) Close() error {
+	err := p.listener.Close()
+
+	p.DropConnections()
+	p.wg.Wait()
+
+	return err
+}
+
+func (p *Proxy) accept() {
+	defer p.wg.Done()
+
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		p.mu.Lock()
+		reject := p.reject
+		p.mu.Unlock()
+
+		if reject {
+			_ = client.Close()
+			continue
+		}
+
+		server, err := net.Dial("tcp", p.target)
+		if err != nil {
+			_ = client.Close()
+			continue
+		}
+
+		connection := &proxyConnection{client: client, server: server, closed: make(chan struct{})}
+
+		p.mu.Lock()
+		p.connections[connection] = struct{}{}
+		p.mu.Unlock()
+
+		p.wg.Add(2)
+		go p.forward(connection, client, server)
+		go p.forward(connection, server, client)
+	}
+}
+
+// forward copies traffic from src to dst until either side is closed, which closes the whole connection.
+func (p *Proxy) forward(connection *proxyConnection, src, dst net.Conn) {
+	defer p.wg.Done()
+	defer p.remove(connection)
+
+	buffer := make([]byte, proxyBufferSize)
+
+	for {
+		n, err := src.Read(buffer)
+		if n > 0 {
+			if !p.hold(connection) {
+				return
+			}
+
+			if _, err := dst.Write(buffer[:n]); err != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// hold delays traffic by the latency and for as long as traffic is blackholed, returning false when the connection
+// was closed meanwhile.
+func (p *Proxy) hold(connection *proxyConnection) bool {
+	p.mu.Lock()
+	latency, traffic := p.latency, p.traffic
+	p.mu.Unlock()
+
+	select {
+	case <-traffic:
+	case <-connection.closed:
+		return false
+	}
+
+	if latency == 0 {
+		return true
+	}
+
+	select {
+	case <-time.After(latency):
+		return true
+	case <-connection.closed:
+		return false
+	}
+}
+
+func (p *Proxy) remove(connection *proxyConnection) {
+	connection.close()
+
+	p.mu.Lock()
+	delete(p.connections, connection)
+	p.mu.Unlock()
+}
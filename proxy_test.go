@@ -0,0 +1,174 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StartProxy(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9886))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	proxy, err := database.StartProxy(0)
+	require.NoError(t, err)
+
+	defer func() {
+		_ = proxy.Close()
+	}()
+
+	db, err := OpenDB(fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", proxy.Port()))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	require.NoError(t, db.Ping())
+
+	proxy.DropConnections()
+
+	_, err = db.Exec("SELECT 1")
+	assert.NoError(t, err)
+}
+
+func Test_StartProxy_ErrorWhenNotStarted(t *testing.T) {
+	_, err := NewDatabase().StartProxy(0)
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_Proxy_ForwardsTraffic(t *testing.T) {
+	proxy := startEchoProxy(t)
+
+	conn := dialProxy(t, proxy)
+
+	assert.Equal(t, "beer", echo(t, conn, "beer"))
+}
+
+func Test_Proxy_SetLatency(t *testing.T) {
+	proxy := startEchoProxy(t)
+	proxy.SetLatency(50 * time.Millisecond)
+
+	conn := dialProxy(t, proxy)
+
+	start := time.Now()
+	assert.Equal(t, "beer", echo(t, conn, "beer"))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func Test_Proxy_Blackhole(t *testing.T) {
+	proxy := startEchoProxy(t)
+	conn := dialProxy(t, proxy)
+
+	proxy.Blackhole(true)
+
+	_, err := conn.Write([]byte("beer"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+	_, err = conn.Read(make([]byte, 4))
+	require.Error(t, err)
+	assert.True(t, err.(net.Error).Timeout())
+
+	proxy.Blackhole(false)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	received := make([]byte, 4)
+	_, err = io.ReadFull(conn, received)
+	require.NoError(t, err)
+	assert.Equal(t, "beer", string(received))
+}
+
+func Test_Proxy_DropConnections(t *testing.T) {
+	proxy := startEchoProxy(t)
+	conn := dialProxy(t, proxy)
+	assert.Equal(t, "beer", echo(t, conn, "beer"))
+
+	proxy.DropConnections()
+
+	_, err := conn.Read(make([]byte, 4))
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_Proxy_RejectConnections(t *testing.T) {
+	proxy := startEchoProxy(t)
+	proxy.RejectConnections(true)
+
+	conn := dialProxy(t, proxy)
+
+	_, err := conn.Read(make([]byte, 4))
+	assert.Equal(t, io.EOF, err)
+
+	proxy.RejectConnections(false)
+
+	assert.Equal(t, "beer", echo(t, dialProxy(t, proxy), "beer"))
+}
+
+func startEchoProxy(t *testing.T) *Proxy {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	proxy, err := newProxy(0, listener.Addr().String())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, proxy.Close())
+		_ = listener.Close()
+	})
+
+	return proxy
+}
+
+func dialProxy(t *testing.T, proxy *Proxy) net.Conn {
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", proxy.Port()))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func echo(t *testing.T, conn net.Conn, message string) string {
+	_, err := conn.Write([]byte(message))
+	require.NoError(t, err)
+
+	received := make([]byte, len(message))
+	_, err = io.ReadFull(conn, received)
+	require.NoError(t, err)
+
+	return string(received)
+}
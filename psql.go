@@ -0,0 +1,37 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RunPsql runs the bundled psql with the arguments against the configured database of a started server and returns
+// what it wrote to stdout, so that meta-commands such as \copy can be used from Go. psqlrc files are not read, and psql
+// exits at the first failing statement.
+func (ep *EmbeddedPostgres) RunPsql(ctx context.Context, args ...string) (string, error) {
+	if !ep.started {
+		return "", errors.New("server has not been started")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	psqlProcess := clientCommand(ctx, ep.config, "psql", append([]string{"-X", "-v", "ON_ERROR_STOP=1", "-d", ep.config.database}, args...)...)
+	psqlProcess.Stdout = stdout
+	psqlProcess.Stderr = stderr
+
+	if err := psqlProcess.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("unable to run '%s': %w\n%s", psqlProcess.String(), err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+// RunSQLFile runs a file of SQL statements and psql meta-commands, such as a fixture loading CSV files with \copy,
+// against the configured database using the bundled psql, in a single transaction. Relative paths within the file are
+// resolved against the working directory.
+func (ep *EmbeddedPostgres) RunSQLFile(ctx context.Context, path string) (string, error) {
+	return ep.RunPsql(ctx, "-q", "--single-transaction", "-f", path)
+}
@@ -0,0 +1,55 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunPsql(t *testing.T) {
+	tempDir := t.TempDir()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		Port(9902))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	csvPath := filepath.Join(tempDir, "beers.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("Punk IPA\nElvis Juice\n"), 0600))
+
+	sqlPath := filepath.Join(tempDir, "fixture.sql")
+	require.NoError(t, os.WriteFile(sqlPath, []byte("CREATE TABLE beers(name text);\n\\copy beers FROM '"+csvPath+"' WITH (FORMAT csv)\n"), 0600))
+
+	_, err := database.RunSQLFile(context.Background(), sqlPath)
+	require.NoError(t, err)
+
+	output, err := database.RunPsql(context.Background(), "-At", "-c", "SELECT count(*) FROM beers")
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", output)
+
+	_, err = database.RunPsql(context.Background(), "-c", "SELECT * FROM wines")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `relation "wines" does not exist`)
+}
+
+func Test_RunPsql_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase()
+
+	_, err := database.RunPsql(context.Background(), "-c", "SELECT 1")
+	assert.EqualError(t, err, "server has not been started")
+
+	_, err = database.RunSQLFile(context.Background(), "fixture.sql")
+	assert.EqualError(t, err, "server has not been started")
+}
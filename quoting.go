@@ -0,0 +1,36 @@
+package embeddedpostgres
+
+import "strings"
+
+// quoteIdentifier quotes a name for use as an identifier in a statement, such as a database or role name.
+func quoteIdentifier(name string) string {
+	// identifiers cannot contain NUL characters, so everything from one on is dropped as Postgres would
+	if end := strings.IndexRune(name, 0); end >= 0 {
+		name = name[:end]
+	}
+
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes a value for use as a string literal in a statement, using an escape string when the value
+// contains backslashes so that it is read the same whatever standard_conforming_strings is set to.
+func quoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, `'`, `''`)
+
+	if strings.Contains(value, `\`) {
+		return `E'` + strings.ReplaceAll(value, `\`, `\\`) + `'`
+	}
+
+	return `'` + value + `'`
+}
+
+// textArray encodes values as a text array literal, which can be passed as a parameter and cast to text[] whichever
+// driver is used.
+func textArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+
+	return "{" + strings.Join(quoted, ",") + "}"
+}
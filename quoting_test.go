@@ -0,0 +1,27 @@
+package embeddedpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_quoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"beer"`, quoteIdentifier("beer"))
+	assert.Equal(t, `"Beer Garden"`, quoteIdentifier("Beer Garden"))
+	assert.Equal(t, `"the ""best"" beer"`, quoteIdentifier(`the "best" beer`))
+	assert.Equal(t, `"bar"`, quoteIdentifier("bar\x00baz"))
+}
+
+func Test_quoteLiteral(t *testing.T) {
+	assert.Equal(t, `'beer'`, quoteLiteral("beer"))
+	assert.Equal(t, `'it''s'`, quoteLiteral("it's"))
+	assert.Equal(t, `E'back\\slash'`, quoteLiteral(`back\slash`))
+	assert.Equal(t, `E'it''s a \\ mess'`, quoteLiteral(`it's a \ mess`))
+}
+
+func Test_textArray(t *testing.T) {
+	assert.Equal(t, `{}`, textArray(nil))
+	assert.Equal(t, `{"hstore","uuid-ossp"}`, textArray([]string{"hstore", "uuid-ossp"}))
+	assert.Equal(t, `{"a,b","say \"hi\"","back\\slash"}`, textArray([]string{"a,b", `say "hi"`, `back\slash`}))
+}
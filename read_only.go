@@ -0,0 +1,94 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// standbySignalFileName is the file which makes Postgres 12 and later start as a standby, Postgres 11 and earlier
+// read standby_mode from recoveryConfFileName instead.
+const standbySignalFileName = "standby.signal"
+
+// isStandby reports whether the server runs as a standby, which is read only and receives its data from elsewhere.
+func (ep *EmbeddedPostgres) isStandby() bool {
+	return ep.primary != nil || ep.config.standbyFrom != ""
+}
+
+// createStandbyData fills the data directory with the configured base backup, configured to start as a standby.
+func (ep *EmbeddedPostgres) createStandbyData() error {
+	backupDir := ep.config.standbyFrom
+
+	if !dataDirIsValid(backupDir, ep.config.version) {
+		return fmt.Errorf("%s does not contain a base backup of Postgres %s in plain format", backupDir, ep.config.version)
+	}
+
+	if err := copyDirectory(backupDir, ep.config.dataPath); err != nil {
+		return fmt.Errorf("unable to copy base backup %s: %w", backupDir, err)
+	}
+
+	pgVersion, err := os.ReadFile(filepath.Join(ep.config.dataPath, "PG_VERSION"))
+	if err != nil {
+		return fmt.Errorf("unable to read version of base backup %s: %w", backupDir, err)
+	}
+
+	// versions before 10 are numbered like 9.6, and are all configured with recovery.conf
+	if major, err := strconv.Atoi(strings.TrimSpace(string(pgVersion))); err == nil && major >= 12 {
+		if err := os.WriteFile(filepath.Join(ep.config.dataPath, standbySignalFileName), nil, 0600); err != nil {
+			return fmt.Errorf("unable to configure standby in %s: %w", ep.config.dataPath, err)
+		}
+
+		return nil
+	}
+
+	recoveryConf := filepath.Join(ep.config.dataPath, recoveryConfFileName)
+	if _, err := os.Stat(recoveryConf); err == nil {
+		return nil
+	}
+
+	if err := os.WriteFile(recoveryConf, []byte(formatParameters(map[string]string{"standby_mode": "on"})), 0600); err != nil {
+		return fmt.Errorf("unable to configure standby in %s: %w", ep.config.dataPath, err)
+	}
+
+	return nil
+}
+
+// makeReadOnly makes new transactions read only once the databases have been prepared, waiting until the reloaded
+// configuration has taken effect.
+func (ep *EmbeddedPostgres) makeReadOnly() error {
+	parameters := ep.serverParameters()
+	parameters["default_transaction_read_only"] = "on"
+
+	if err := writeServerParameters(ep.config.dataPath, parameters); err != nil {
+		return err
+	}
+
+	if err := ep.ReloadConfig(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(ep.config.startTimeout)
+
+	return withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		for {
+			var readOnly string
+			if err := db.QueryRow("SELECT current_setting('default_transaction_read_only')").Scan(&readOnly); err != nil {
+				return fmt.Errorf("unable to make database read only: %w", err)
+			}
+
+			if readOnly == "on" {
+				return nil
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("database is not read only after %s", ep.config.startTimeout)
+			}
+
+			time.Sleep(ep.config.healthCheckInterval)
+		}
+	})
+}
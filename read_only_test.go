@@ -0,0 +1,136 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadOnly(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9889).
+		AfterStartSQL("CREATE TABLE beers(name text)", "INSERT INTO beers VALUES ('Punk IPA')").
+		ReadOnly(true))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9889 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM beers").Scan(&name))
+	assert.Equal(t, "Punk IPA", name)
+
+	_, err = db.Exec("INSERT INTO beers VALUES ('Dead Pony Club')")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only transaction")
+}
+
+func Test_StandbyFrom(t *testing.T) {
+	tempDir := t.TempDir()
+
+	primary := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "primary")).
+		Port(9888).
+		AfterStartSQL("CREATE TABLE beers(name text)", "INSERT INTO beers VALUES ('Punk IPA')"))
+	if err := primary.Start(); err != nil {
+		shutdownDBAndFail(t, err, primary)
+	}
+
+	backupDir := filepath.Join(tempDir, "backup")
+	require.NoError(t, primary.BaseBackup(context.Background(), backupDir, BaseBackupOptions{}))
+	require.NoError(t, primary.Stop())
+
+	standby := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "standby")).
+		Port(9888).
+		StandbyFrom(backupDir))
+	if err := standby.Start(); err != nil {
+		shutdownDBAndFail(t, err, standby)
+	}
+
+	defer func() {
+		if err := standby.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9888 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM beers").Scan(&name))
+	assert.Equal(t, "Punk IPA", name)
+
+	_, err = db.Exec("SET default_transaction_read_only = off; INSERT INTO beers VALUES ('Dead Pony Club')")
+	assert.Error(t, err)
+}
+
+func Test_createStandbyData(t *testing.T) {
+	backupDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, "PG_VERSION"), []byte("15\n"), 0600))
+
+	dataPath := filepath.Join(t.TempDir(), "data")
+	database := NewDatabase(DefaultConfig().Version(V15).DataPath(dataPath).StandbyFrom(backupDir))
+	database.config.dataPath = dataPath
+
+	require.NoError(t, database.createStandbyData())
+
+	assert.FileExists(t, filepath.Join(dataPath, "PG_VERSION"))
+	assert.FileExists(t, filepath.Join(dataPath, standbySignalFileName))
+	assert.NoFileExists(t, filepath.Join(dataPath, recoveryConfFileName))
+}
+
+func Test_createStandbyData_RecoveryConfBeforePostgres12(t *testing.T) {
+	backupDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, "PG_VERSION"), []byte("11\n"), 0600))
+
+	dataPath := filepath.Join(t.TempDir(), "data")
+	database := NewDatabase(DefaultConfig().Version(V11).DataPath(dataPath).StandbyFrom(backupDir))
+	database.config.dataPath = dataPath
+
+	require.NoError(t, database.createStandbyData())
+
+	recoveryConf, err := os.ReadFile(filepath.Join(dataPath, recoveryConfFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "standby_mode = 'on'\n", string(recoveryConf))
+	assert.NoFileExists(t, filepath.Join(dataPath, standbySignalFileName))
+}
+
+func Test_createStandbyData_ErrorWhenNotBackupOfVersion(t *testing.T) {
+	backupDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, "PG_VERSION"), []byte("14\n"), 0600))
+
+	database := NewDatabase(DefaultConfig().Version(V15).StandbyFrom(backupDir))
+
+	err := database.createStandbyData()
+
+	assert.EqualError(t, err, backupDir+" does not contain a base backup of Postgres "+string(V15)+" in plain format")
+}
+
+func Test_StandbyFrom_ServerParameters(t *testing.T) {
+	database := NewDatabase(DefaultConfig().StandbyFrom("/backup"))
+
+	assert.Equal(t, "on", database.serverParameters()["hot_standby"])
+	assert.True(t, database.isStandby())
+}
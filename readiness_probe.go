@@ -0,0 +1,43 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"context"
+	"time"
+)
+
+// probeTimeout bounds a single readiness probe, so that a server which accepted the connection but never answers does
+// not stall the health check.
+const probeTimeout = 5 * time.Second
+
+// probeReady checks that the server accepts sessions for the user and database of the configuration, by logging in with
+// the startup and authentication messages of the wire protocol until the server reports it is ready for queries. It
+// connects with the SSL settings of the library, so that it presents the client certificate of the superuser when
+// client certificates are required, and supports the trust, password, md5 and scram-sha-256 authentication methods.
+func probeReady(config Config) (err error) {
+	wireConfig, err := parseWireDSN(internalDSN(config, config.database))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	conn, err := (&wireConnector{config: wireConfig}).dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = connectionClose(conn, err)
+	}()
+
+	session := &wireSession{reader: bufio.NewReader(conn), writer: conn, username: wireConfig.user, password: wireConfig.password}
+
+	if err := session.login(wireConfig.database); err != nil {
+		return err
+	}
+
+	// ends the session cleanly rather than leaving the server to log an unexpected EOF
+	return session.send('X', nil)
+}
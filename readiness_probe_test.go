@@ -0,0 +1,139 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_probeReady_Trust(t *testing.T) {
+	terminated := make(chan byte, 1)
+
+	port := fakeServer(t, func(t *testing.T, session *wireSession, startup []byte) {
+		assert.Equal(t, uint32(protocolVersion), binary.BigEndian.Uint32(startup))
+		assert.Equal(t, "user\x00gin\x00database\x00beer\x00\x00", string(startup[4:]))
+
+		sendReady(session)
+
+		messageType, _, _ := session.receive()
+		terminated <- messageType
+	})
+
+	require.NoError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")))
+	assert.Equal(t, byte('X'), <-terminated)
+}
+
+func Test_probeReady_CleartextPassword(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('R', authentication(authenticationCleartextPassword))
+
+		messageType, payload, err := session.receive()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, byte('p'), messageType)
+		assert.Equal(t, "wine\x00", string(payload))
+
+		sendReady(session)
+	})
+
+	assert.NoError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")))
+}
+
+func Test_probeReady_MD5Password(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('R', authentication(authenticationMD5Password, 1, 2, 3, 4))
+
+		_, payload, err := session.receive()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "md5c7abe45b24f1e349bba3e5658e79943a\x00", string(payload))
+
+		sendReady(session)
+	})
+
+	assert.NoError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")))
+}
+
+func Test_probeReady_ScramSHA256(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('R', authentication(authenticationSASL, []byte("SCRAM-SHA-256-PLUS\x00SCRAM-SHA-256\x00\x00")...))
+
+		_, payload, err := session.receive()
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.True(t, bytes.HasPrefix(payload, []byte("SCRAM-SHA-256\x00"))) {
+			return
+		}
+
+		clientFirst := string(payload[len("SCRAM-SHA-256\x00")+4:])
+		if !assert.True(t, strings.HasPrefix(clientFirst, "n,,n=,r=")) {
+			return
+		}
+
+		salt := []byte("sea salt")
+		serverFirst := "r=" + strings.TrimPrefix(clientFirst, "n,,n=,r=") + "server,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+		_ = session.send('R', authentication(authenticationSASLContinue, []byte(serverFirst)...))
+
+		_, payload, err = session.receive()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		withoutProof, proof, found := strings.Cut(string(payload), ",p=")
+		if !assert.True(t, found) {
+			return
+		}
+
+		assert.Equal(t, "c=biws,r="+strings.TrimPrefix(clientFirst, "n,,n=,r=")+"server", withoutProof)
+
+		saltedPassword := pbkdf2SHA256([]byte("wine"), salt, 4096)
+		authMessage := strings.TrimPrefix(clientFirst, "n,,") + "," + serverFirst + "," + withoutProof
+		assert.Equal(t, base64.StdEncoding.EncodeToString(scramClientProof(saltedPassword, authMessage)), proof)
+
+		serverFinal := "v=" + base64.StdEncoding.EncodeToString(scramServerSignature(saltedPassword, authMessage))
+		_ = session.send('R', authentication(authenticationSASLFinal, []byte(serverFinal)...))
+		sendReady(session)
+	})
+
+	assert.NoError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")))
+}
+
+func Test_probeReady_ErrorWhenServerSkipsScramSignature(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('R', authentication(authenticationSASL, []byte("SCRAM-SHA-256\x00\x00")...))
+
+		if _, _, err := session.receive(); !assert.NoError(t, err) {
+			return
+		}
+
+		sendReady(session)
+	})
+
+	assert.EqualError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")), "the server did not complete the SCRAM exchange")
+}
+
+func Test_probeReady_ErrorResponse(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('E', []byte("SFATAL\x00VFATAL\x00C3D000\x00Mdatabase \"beer\" does not exist\x00\x00"))
+	})
+
+	assert.EqualError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")), `FATAL: database "beer" does not exist (SQLSTATE 3D000)`)
+}
+
+func Test_probeReady_UnsupportedAuthentication(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		_ = session.send('R', authentication(7))
+	})
+
+	assert.EqualError(t, probeReady(DefaultConfig().Port(port).Username("gin").Password("wine").Database("beer")), "unsupported authentication request 7")
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,20 +20,17 @@ import (
 type RemoteFetchStrategy func() error
 
 //nolint:funlen
-func defaultRemoteFetchStrategy(remoteFetchHost string, versionStrategy VersionStrategy, cacheLocator CacheLocator) RemoteFetchStrategy {
+func defaultRemoteFetchStrategy(remoteFetchHost string, client *http.Client, versionStrategy VersionStrategy, cacheLocator CacheLocator) RemoteFetchStrategy {
 	return func() error {
 		operatingSystem, architecture, version := versionStrategy()
 
-		jarDownloadURL := fmt.Sprintf("%s/io/zonky/test/postgres/embedded-postgres-binaries-%s-%s/%s/embedded-postgres-binaries-%s-%s-%s.jar",
-			remoteFetchHost,
-			operatingSystem,
-			architecture,
-			version,
-			operatingSystem,
-			architecture,
-			version)
+		jarDownloadURL := artifactURL(remoteFetchHost, operatingSystem, architecture, version)
 
-		jarDownloadResponse, err := http.Get(jarDownloadURL)
+		if err := checkArtifactAvailable(client, jarDownloadURL, remoteFetchHost, operatingSystem, architecture, version); err != nil {
+			return err
+		}
+
+		jarDownloadResponse, err := client.Get(jarDownloadURL)
 		if err != nil {
 			return fmt.Errorf("unable to connect to %s", remoteFetchHost)
 		}
@@ -43,13 +41,17 @@ func defaultRemoteFetchStrategy(remoteFetchHost string, versionStrategy VersionS
 			return fmt.Errorf("no version found matching %s", version)
 		}
 
+		if isHTMLResponse(jarDownloadResponse) {
+			return errorUnexpectedHTML(jarDownloadURL)
+		}
+
 		jarBodyBytes, err := io.ReadAll(jarDownloadResponse.Body)
 		if err != nil {
 			return errorFetchingPostgres(err)
 		}
 
 		shaDownloadURL := fmt.Sprintf("%s.sha256", jarDownloadURL)
-		shaDownloadResponse, err := http.Get(shaDownloadURL)
+		shaDownloadResponse, err := client.Get(shaDownloadURL)
 
 		defer closeBody(shaDownloadResponse)()
 
@@ -66,6 +68,89 @@ func defaultRemoteFetchStrategy(remoteFetchHost string, versionStrategy VersionS
 	}
 }
 
+// artifactURL returns the URL of the jar containing the binaries of a version for a platform in the repository.
+func artifactURL(remoteFetchHost, operatingSystem, architecture string, version PostgresVersion) string {
+	return fmt.Sprintf("%s/io/zonky/test/postgres/embedded-postgres-binaries-%s-%s/%s/embedded-postgres-binaries-%s-%s-%s.jar",
+		remoteFetchHost,
+		operatingSystem,
+		architecture,
+		version,
+		operatingSystem,
+		architecture,
+		version)
+}
+
+// checkArtifactAvailable issues a HEAD request for the artifact before downloading it, so that a version which is not
+// published for the current platform results in a clear error rather than an attempt to unzip an error page.
+// Repositories that do not support HEAD requests are tolerated and the download is attempted as usual.
+func checkArtifactAvailable(client *http.Client, downloadURL, remoteFetchHost, operatingSystem, architecture string, version PostgresVersion) error {
+	headResponse, err := client.Head(downloadURL)
+	if err != nil {
+		return fmt.Errorf("unable to connect to %s", remoteFetchHost)
+	}
+
+	defer closeBody(headResponse)()
+
+	switch {
+	case headResponse.StatusCode == http.StatusNotFound || headResponse.StatusCode == http.StatusGone:
+		return fmt.Errorf("version %s is not published for %s/%s at %s", version, operatingSystem, architecture, remoteFetchHost)
+	case headResponse.StatusCode == http.StatusOK && isHTMLResponse(headResponse):
+		return errorUnexpectedHTML(downloadURL)
+	}
+
+	return nil
+}
+
+// binaryRepositoryClient returns the client used to download binaries, using the configured TLS settings and headers
+// when set.
+func binaryRepositoryClient(config Config) *http.Client {
+	if config.binaryRepositoryTLS == nil && len(config.binaryRepositoryHeaders) == 0 {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.binaryRepositoryTLS != nil {
+		transport.TLSClientConfig = config.binaryRepositoryTLS.Clone()
+	}
+
+	if len(config.binaryRepositoryHeaders) == 0 {
+		return &http.Client{Transport: transport}
+	}
+
+	host := ""
+	if repositoryURL, err := url.Parse(config.binaryRepositoryURL); err == nil {
+		host = repositoryURL.Host
+	}
+
+	return &http.Client{Transport: headerTransport{host: host, headers: config.binaryRepositoryHeaders, next: transport}}
+}
+
+// headerTransport adds headers, such as credentials, to every request to the repository host. Requests redirected to
+// other hosts, such as a storage bucket serving the artifacts, are sent without them.
+type headerTransport struct {
+	host    string
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host != t.host {
+		return t.next.RoundTrip(request)
+	}
+
+	request = request.Clone(request.Context())
+
+	for name, value := range t.headers {
+		request.Header.Set(name, value)
+	}
+
+	return t.next.RoundTrip(request)
+}
+
+func isHTMLResponse(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
+
 func closeBody(resp *http.Response) func() {
 	return func() {
 		if err := resp.Body.Close(); err != nil {
@@ -134,6 +219,12 @@ func decompressSingleFile(file *zip.File, cacheLocation string) error {
 		return errorExtractingPostgres(err)
 	}
 
+	// flush the archive to disk before it is renamed into place, so that a crash cannot leave a truncated archive in
+	// the cache
+	if err := tmp.Sync(); err != nil {
+		return errorExtractingPostgres(err)
+	}
+
 	// Windows cannot rename a file if is it still open.
 	// The file needs to be manually closed to allow the rename to happen
 	if err := tmp.Close(); err != nil {
@@ -148,8 +239,12 @@ func decompressSingleFile(file *zip.File, cacheLocation string) error {
 	return nil
 }
 
+func errorUnexpectedHTML(downloadURL string) error {
+	return fmt.Errorf("error fetching postgres: %s returned an HTML page instead of a binary archive, check BinaryRepositoryURL", downloadURL)
+}
+
 func errorExtractingPostgres(err error) error {
-	return fmt.Errorf("unable to extract postgres archive: %s", err)
+	return fmt.Errorf("unable to extract postgres archive: %w", err)
 }
 
 func errorFetchingPostgres(err error) error {
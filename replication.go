@@ -0,0 +1,157 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// replicationPollInterval is the interval between checks of whether a replica has replayed the WAL of its primary.
+const replicationPollInterval = 10 * time.Millisecond
+
+// StartReplica starts a hot standby of the started server, streaming from a physical replication slot. The replica is
+// created with pg_basebackup from the binaries of this server, and uses the port, paths, timeouts and logger of the
+// given configuration, while the version, credentials and database are those of this server. Init scripts, roles and
+// other setup are not applied to the replica, which receives them from this server instead. Stop the replica before
+// this server. Replicas require Postgres 10 or later.
+func (ep *EmbeddedPostgres) StartReplica(config Config) (*EmbeddedPostgres, error) {
+	if !ep.started {
+		return nil, errors.New("server has not been started")
+	}
+
+	if config.port == ep.config.port {
+		return nil, fmt.Errorf("replica must use a different port than its primary, both use %d", config.port)
+	}
+
+	replicaConfig := DefaultConfig().
+		Version(ep.config.version).
+		Port(config.port).
+		Username(ep.config.username).
+		Password(ep.config.password).
+		Database(ep.config.database).
+		AuthMethod(ep.config.authMethod).
+		BinariesPath(ep.config.binariesPath).
+		RuntimePath(config.runtimePath).
+		DataPath(config.dataPath).
+		CachePath(config.cachePath).
+		StartTimeout(config.startTimeout).
+		StopTimeout(config.stopTimeout).
+		StartParameters(config.startParameters)
+
+	if config.logger != nil {
+		replicaConfig = replicaConfig.Logger(config.logger)
+	}
+
+	replica := NewDatabase(replicaConfig)
+	replica.cacheLocator = ep.cacheLocator
+	replica.primary = ep
+
+	if err := replica.Start(); err != nil {
+		return nil, err
+	}
+
+	return replica, nil
+}
+
+// replicationSlotName is the name of the physical replication slot of the replica listening on the port.
+func replicationSlotName(port uint32) string {
+	return "embedded_postgres_replica_" + strconv.FormatUint(uint64(port), 10)
+}
+
+// createReplicaData fills the data directory of a replica with a base backup of its primary, configured to stream from
+// a newly created replication slot.
+func (ep *EmbeddedPostgres) createReplicaData() error {
+	primary := ep.primary.config
+	slot := replicationSlotName(ep.config.port)
+
+	// the slot of an earlier replica on the same port is left behind when its primary reuses its data directory
+	if err := withDatabaseConnection(primary, primary.database, func(db *sql.DB) error {
+		_, err := db.Exec("SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = $1", slot)
+		return err
+	}); err != nil {
+		return fmt.Errorf("unable to remove replication slot %s: %w", slot, err)
+	}
+
+	baseBackup := clientCommand(context.Background(), primary, "pg_basebackup", baseBackupArgs(ep.config.dataPath, BaseBackupOptions{
+		Slot:              slot,
+		CreateSlot:        true,
+		WriteRecoveryConf: true,
+	})...)
+	baseBackup.Stdout = ep.syncedLogger.file
+	baseBackup.Stderr = ep.syncedLogger.file
+
+	if err := baseBackup.Run(); err != nil {
+		_ = ep.syncedLogger.flush()
+		logContent, _ := readLogsOrTimeout(ep.syncedLogger.file)
+
+		return fmt.Errorf("unable to create replica using %s: %w\n%s", baseBackup.String(), err, string(logContent))
+	}
+
+	return nil
+}
+
+// WaitForReplica waits until the replica has replayed all WAL written by this server so far, so that changes made on
+// this server are visible on the replica.
+func (ep *EmbeddedPostgres) WaitForReplica(ctx context.Context, replica *EmbeddedPostgres) error {
+	var target string
+
+	if err := withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&target)
+	}); err != nil {
+		return fmt.Errorf("unable to read WAL position of primary: %w", err)
+	}
+
+	config := replica.config
+
+	return withDatabaseConnection(config, config.database, func(db *sql.DB) error {
+		for {
+			var replayed bool
+			if err := db.QueryRowContext(ctx, "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", target).Scan(&replayed); err != nil {
+				return fmt.Errorf("unable to read WAL position of replica: %w", err)
+			}
+
+			if replayed {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("replica has not replayed WAL position %s: %w", target, ctx.Err())
+			case <-time.After(replicationPollInterval):
+			}
+		}
+	})
+}
+
+// Promote promotes a standby, such as a replica started with StartReplica, to a primary with pg_ctl promote, so that
+// failover can be tested deterministically. It returns once the server accepts writes. The physical replication slot of
+// a replica is kept on its former primary, and can be removed with DropReplicationSlot.
+func (ep *EmbeddedPostgres) Promote() error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	postgresBinary := filepath.Join(ep.config.binariesPath, "bin/pg_ctl")
+	postgresProcess := exec.Command(postgresBinary, "promote", "-w",
+		"-t", pgCtlTimeout(ep.config.startTimeout),
+		"-D", ep.config.dataPath)
+	postgresProcess.Stdout = ep.syncedLogger.file
+	postgresProcess.Stderr = ep.syncedLogger.file
+
+	if err := postgresProcess.Run(); err != nil {
+		_ = ep.syncedLogger.flush()
+		logContent, _ := readLogsOrTimeout(ep.syncedLogger.file)
+
+		return fmt.Errorf("unable to promote postgres using %s: %w\n%s", postgresProcess.String(), err, string(logContent))
+	}
+
+	// a promoted replica no longer depends on its primary, which can now be stopped first
+	ep.primary = nil
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ReplicationSlot describes a replication slot of the server.
+type ReplicationSlot struct {
+	// Name of the slot.
+	Name string
+	// Type is "physical" or "logical".
+	Type string
+	// Plugin is the output plugin of a logical slot, such as pgoutput or wal2json, and empty for physical slots.
+	Plugin string
+	// Database of a logical slot, empty for physical slots.
+	Database string
+	// Active reports whether a consumer is streaming from the slot.
+	Active bool
+	// RestartLSN is the oldest WAL position retained for the slot, empty when no WAL is retained yet.
+	RestartLSN string
+	// ConfirmedFlushLSN is the position up to which the consumer of a logical slot has confirmed receiving changes.
+	ConfirmedFlushLSN string
+}
+
+// CreateLogicalReplicationSlot creates a logical replication slot in the configured database using the output plugin,
+// such as pgoutput or wal2json, for a change data capture consumer to stream from. Plugins other than pgoutput and
+// test_decoding must be included in the binaries.
+func (ep *EmbeddedPostgres) CreateLogicalReplicationSlot(name, plugin string) (ReplicationSlot, error) {
+	if !ep.started {
+		return ReplicationSlot{}, errors.New("server has not been started")
+	}
+
+	slot := ReplicationSlot{Name: name, Type: "logical", Plugin: plugin, Database: ep.config.database}
+
+	err := ep.withConfiguredDatabase(func(db *sql.DB) error {
+		return db.QueryRow("SELECT lsn::text FROM pg_create_logical_replication_slot($1, $2)", name, plugin).
+			Scan(&slot.ConfirmedFlushLSN)
+	})
+	if err != nil {
+		return ReplicationSlot{}, fmt.Errorf("unable to create logical replication slot %s: %w", name, err)
+	}
+
+	slot.RestartLSN = slot.ConfirmedFlushLSN
+
+	return slot, nil
+}
+
+// CreatePhysicalReplicationSlot creates a physical replication slot which immediately retains WAL, for a standby or
+// pg_receivewal to stream from.
+func (ep *EmbeddedPostgres) CreatePhysicalReplicationSlot(name string) (ReplicationSlot, error) {
+	if !ep.started {
+		return ReplicationSlot{}, errors.New("server has not been started")
+	}
+
+	slot := ReplicationSlot{Name: name, Type: "physical"}
+
+	err := ep.withConfiguredDatabase(func(db *sql.DB) error {
+		return db.QueryRow("SELECT lsn::text FROM pg_create_physical_replication_slot($1, true)", name).Scan(&slot.RestartLSN)
+	})
+	if err != nil {
+		return ReplicationSlot{}, fmt.Errorf("unable to create physical replication slot %s: %w", name, err)
+	}
+
+	return slot, nil
+}
+
+// ReplicationSlots lists the replication slots of the server, ordered by name.
+func (ep *EmbeddedPostgres) ReplicationSlots() ([]ReplicationSlot, error) {
+	if !ep.started {
+		return nil, errors.New("server has not been started")
+	}
+
+	var slots []ReplicationSlot
+
+	err := ep.withConfiguredDatabase(func(db *sql.DB) error {
+		rows, err := db.Query(`SELECT slot_name, slot_type, COALESCE(plugin, ''), COALESCE(database, ''), active,
+				COALESCE(restart_lsn::text, ''), COALESCE(confirmed_flush_lsn::text, '')
+			FROM pg_replication_slots
+			ORDER BY slot_name`)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = rows.Close()
+		}()
+
+		for rows.Next() {
+			var slot ReplicationSlot
+			if err := rows.Scan(&slot.Name, &slot.Type, &slot.Plugin, &slot.Database, &slot.Active, &slot.RestartLSN, &slot.ConfirmedFlushLSN); err != nil {
+				return err
+			}
+
+			slots = append(slots, slot)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list replication slots: %w", err)
+	}
+
+	return slots, nil
+}
+
+// DropReplicationSlot drops the replication slot, releasing the WAL it retains. Slots with an active consumer cannot be
+// dropped.
+func (ep *EmbeddedPostgres) DropReplicationSlot(name string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	err := ep.withConfiguredDatabase(func(db *sql.DB) error {
+		_, err := db.Exec("SELECT pg_drop_replication_slot($1)", name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to drop replication slot %s: %w", name, err)
+	}
+
+	return nil
+}
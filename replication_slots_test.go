@@ -0,0 +1,68 @@
+package embeddedpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReplicationSlots(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9864).
+		WalLevel(WalLevelLogical))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	physical, err := database.CreatePhysicalReplicationSlot("standby")
+	require.NoError(t, err)
+	assert.NotEmpty(t, physical.RestartLSN)
+
+	_, err = database.CreateLogicalReplicationSlot("cdc", "pgoutput")
+	require.NoError(t, err)
+
+	slots, err := database.ReplicationSlots()
+	require.NoError(t, err)
+	require.Len(t, slots, 2)
+
+	assert.Equal(t, "cdc", slots[0].Name)
+	assert.Equal(t, "logical", slots[0].Type)
+	assert.Equal(t, "pgoutput", slots[0].Plugin)
+	assert.Equal(t, "postgres", slots[0].Database)
+	assert.False(t, slots[0].Active)
+	assert.NotEmpty(t, slots[0].ConfirmedFlushLSN)
+
+	assert.Equal(t, ReplicationSlot{Name: "standby", Type: "physical", RestartLSN: physical.RestartLSN}, slots[1])
+
+	require.NoError(t, database.DropReplicationSlot("cdc"))
+	require.NoError(t, database.DropReplicationSlot("standby"))
+
+	slots, err = database.ReplicationSlots()
+	require.NoError(t, err)
+	assert.Empty(t, slots)
+
+	err = database.DropReplicationSlot("standby")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to drop replication slot standby")
+}
+
+func Test_ReplicationSlots_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase()
+
+	_, err := database.CreatePhysicalReplicationSlot("standby")
+	assert.EqualError(t, err, "server has not been started")
+
+	_, err = database.ReplicationSlots()
+	assert.EqualError(t, err, "server has not been started")
+
+	assert.EqualError(t, database.DropReplicationSlot("standby"), "server has not been started")
+}
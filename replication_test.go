@@ -0,0 +1,128 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StartReplica(t *testing.T) {
+	primary := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9867))
+
+	if err := primary.Start(); err != nil {
+		shutdownDBAndFail(t, err, primary)
+	}
+
+	defer func() {
+		if err := primary.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	replica, err := primary.StartReplica(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9866))
+	require.NoError(t, err)
+
+	defer func() {
+		if err := replica.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	primaryDB, err := OpenDB("host=localhost port=9867 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	_, err = primaryDB.Exec("CREATE TABLE replicated (id int); INSERT INTO replicated VALUES (42)")
+	require.NoError(t, err)
+	require.NoError(t, primaryDB.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	require.NoError(t, primary.WaitForReplica(ctx, replica))
+
+	replicaDB, err := OpenDB("host=localhost port=9866 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	var id int
+	var inRecovery bool
+	require.NoError(t, replicaDB.QueryRow("SELECT id FROM replicated").Scan(&id))
+	require.NoError(t, replicaDB.QueryRow("SELECT pg_is_in_recovery()").Scan(&inRecovery))
+	assert.Equal(t, 42, id)
+	assert.True(t, inRecovery)
+	require.NoError(t, replicaDB.Close())
+}
+
+func Test_StartReplica_ErrorWhenNotStarted(t *testing.T) {
+	_, err := NewDatabase().StartReplica(DefaultConfig().Port(9866))
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_StartReplica_ErrorWhenSamePort(t *testing.T) {
+	database := NewDatabase(DefaultConfig().Port(9867))
+	database.started = true
+
+	_, err := database.StartReplica(DefaultConfig().Port(9867))
+
+	assert.EqualError(t, err, "replica must use a different port than its primary, both use 9867")
+}
+
+func Test_Promote(t *testing.T) {
+	primary := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9881))
+
+	if err := primary.Start(); err != nil {
+		shutdownDBAndFail(t, err, primary)
+	}
+
+	defer func() {
+		if err := primary.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	replica, err := primary.StartReplica(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9882))
+	require.NoError(t, err)
+
+	defer func() {
+		if err := replica.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	require.NoError(t, replica.Promote())
+
+	replicaDB, err := OpenDB("host=localhost port=9882 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = replicaDB.Close()
+	}()
+
+	var inRecovery bool
+	require.NoError(t, replicaDB.QueryRow("SELECT pg_is_in_recovery()").Scan(&inRecovery))
+	assert.False(t, inRecovery)
+
+	_, err = replicaDB.Exec("CREATE TABLE promoted (id int)")
+	assert.NoError(t, err)
+}
+
+func Test_Promote_ErrorWhenNotStarted(t *testing.T) {
+	err := NewDatabase().Promote()
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_replicationSlotName(t *testing.T) {
+	assert.Equal(t, "embedded_postgres_replica_9866", replicationSlotName(9866))
+}
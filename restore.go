@@ -0,0 +1,104 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// customDumpSignature is the header of dumps written by pg_dump using the custom format.
+var customDumpSignature = []byte("PGDMP")
+
+// restoreDump restores the configured dump into the database using the bundled pg_restore for custom format dumps and
+// psql for plain SQL dumps.
+func restoreDump(config Config, logger *os.File) error {
+	if config.restoreFrom == "" {
+		return nil
+	}
+
+	custom, err := isCustomFormatDump(config.restoreFrom)
+	if err != nil {
+		return err
+	}
+
+	var restoreProcess *exec.Cmd
+	if custom {
+		restoreProcess = clientCommand(context.Background(), config, "pg_restore",
+			"--no-owner",
+			"--exit-on-error",
+			"-d", config.database,
+			config.restoreFrom)
+	} else {
+		restoreProcess = clientCommand(context.Background(), config, "psql",
+			"-v", "ON_ERROR_STOP=1",
+			"-q",
+			"-d", config.database,
+			"-f", config.restoreFrom)
+	}
+
+	restoreProcess.Stdout = logger
+	restoreProcess.Stderr = logger
+
+	if err := restoreProcess.Run(); err != nil {
+		logContent, readLogsErr := readLogsOrTimeout(logger) // we want to preserve the original error
+		if readLogsErr != nil {
+			logContent = []byte(string(logContent) + " - " + readLogsErr.Error())
+		}
+		return fmt.Errorf("unable to restore %s using '%s': %w\n%s", config.restoreFrom, restoreProcess.String(), err, string(logContent))
+	}
+
+	return nil
+}
+
+// Restore restores a dump read from r into an existing database on a started server, using the bundled pg_restore for
+// dumps written with pg_dump --format=custom and psql for plain SQL dumps, such as those written by Dump. Ownership is
+// not restored, and the restore stops at the first error.
+func (ep *EmbeddedPostgres) Restore(ctx context.Context, database string, r io.Reader) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	dump := bufio.NewReader(r)
+	header, _ := dump.Peek(len(customDumpSignature))
+
+	var restoreProcess *exec.Cmd
+	if bytes.Equal(header, customDumpSignature) {
+		restoreProcess = clientCommand(ctx, ep.config, "pg_restore", "--no-owner", "--exit-on-error", "-d", database)
+	} else {
+		restoreProcess = clientCommand(ctx, ep.config, "psql", "-v", "ON_ERROR_STOP=1", "-q", "-d", database)
+	}
+
+	stderr := &bytes.Buffer{}
+	restoreProcess.Stdin = dump
+	restoreProcess.Stdout = stderr
+	restoreProcess.Stderr = stderr
+
+	if err := restoreProcess.Run(); err != nil {
+		return fmt.Errorf("unable to restore database %s using '%s': %w\n%s", database, restoreProcess.String(), err, stderr.String())
+	}
+
+	return nil
+}
+
+func isCustomFormatDump(dumpPath string) (bool, error) {
+	file, err := os.Open(dumpPath)
+	if err != nil {
+		return false, fmt.Errorf("unable to open dump %s: %w", dumpPath, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	header := make([]byte, len(customDumpSignature))
+	if _, err := io.ReadFull(file, header); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, fmt.Errorf("unable to read dump %s: %w", dumpPath, err)
+	}
+
+	return bytes.Equal(header, customDumpSignature), nil
+}
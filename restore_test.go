@@ -0,0 +1,176 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RestoreFrom(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	dumpPath := filepath.Join(tempDir, "dump.sql")
+	require.NoError(t, os.WriteFile(dumpPath, []byte("CREATE TABLE beers(name text);\nINSERT INTO beers VALUES ('Punk IPA');\n"), 0600))
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		Port(9854).
+		Database("beer").
+		RestoreFrom(dumpPath))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9854 user=postgres password=postgres dbname=beer sslmode=disable")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM beers").Scan(&count))
+	assert.Equal(t, 1, count)
+	require.NoError(t, db.Close())
+}
+
+func Test_isCustomFormatDump(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"custom": "PGDMP\x01\x0e\x00",
+		"plain":  "--\n-- PostgreSQL database dump\n--\n",
+		"short":  "PG",
+	} {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+		custom, err := isCustomFormatDump(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, name == "custom", custom, name)
+	}
+}
+
+func Test_isCustomFormatDump_ErrorWhenMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.dump")
+
+	_, err := isCustomFormatDump(path)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to open dump "+path)
+}
+
+func Test_restoreDump_ErrorWhenRestoreFails(t *testing.T) {
+	tempDir := t.TempDir()
+	dumpPath := filepath.Join(tempDir, "dump.sql")
+	require.NoError(t, os.WriteFile(dumpPath, []byte("SELECT 1;"), 0600))
+
+	logFile, err := os.CreateTemp(tempDir, "log")
+	require.NoError(t, err)
+
+	config := DefaultConfig().BinariesPath(tempDir).RestoreFrom(dumpPath)
+
+	err = restoreDump(config, logFile)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to restore "+dumpPath+" using '"+filepath.Join(tempDir, "bin", "psql"))
+}
+
+func Test_Restore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9899).
+		AfterStartSQL("CREATE TABLE beers(name text)", "INSERT INTO beers VALUES ('Punk IPA')"))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, format := range []DumpFormat{DumpFormatPlain, DumpFormatCustom} {
+		dump := &bytes.Buffer{}
+		require.NoError(t, database.Dump(context.Background(), "postgres", dump, DumpOptions{Format: format}))
+
+		restored := "restored_" + string(format)
+		require.NoError(t, database.CreateDatabase(restored))
+		require.NoError(t, database.Restore(context.Background(), restored, dump))
+
+		err := withDatabaseConnection(DefaultConfig().Port(9899), restored, func(db *sql.DB) error {
+			var name string
+			if err := db.QueryRow("SELECT name FROM beers").Scan(&name); err != nil {
+				return err
+			}
+
+			assert.Equal(t, "Punk IPA", name)
+
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
+
+func Test_Restore_ChoosesClientByFormat(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts in place of the client binaries")
+	}
+
+	binaries := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(binaries, "bin"), 0755))
+
+	for _, binary := range []string{"psql", "pg_restore"} {
+		script := "#!/bin/sh\necho " + binary + " failed\nexit 1\n"
+		require.NoError(t, os.WriteFile(filepath.Join(binaries, "bin", binary), []byte(script), 0755))
+	}
+
+	database := NewDatabase(DefaultConfig().BinariesPath(binaries))
+	database.started = true
+
+	err := database.Restore(context.Background(), "gin", bytes.NewBufferString("PGDMP custom dump"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to restore database gin using '"+filepath.Join(binaries, "bin", "pg_restore"))
+	assert.Contains(t, err.Error(), "pg_restore failed")
+
+	err = database.Restore(context.Background(), "gin", bytes.NewBufferString("CREATE TABLE beers(name text);"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "psql failed")
+}
+
+func Test_Restore_ErrorWhenNotStarted(t *testing.T) {
+	err := NewDatabase().Restore(context.Background(), "postgres", &bytes.Buffer{})
+
+	assert.EqualError(t, err, "server has not been started")
+}
@@ -0,0 +1,81 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Role describes an additional login role created during startup, so that tests can connect as a least privileged
+// application user rather than as the superuser.
+type Role struct {
+	// Name of the role.
+	Name string
+	// Password of the role, which may be empty for roles which do not connect with a password.
+	Password string
+	// Attributes such as CREATEDB or REPLICATION. LOGIN is always granted.
+	Attributes []string
+	// Databases owned by the role, which are created when they do not exist.
+	Databases []string
+	// Grants are privileges granted to the role within the configured database, such as
+	// "SELECT, INSERT ON ALL TABLES IN SCHEMA public" or another role to become a member of.
+	Grants []string
+}
+
+// createRoles creates the configured roles along with the databases they own, then applies their grants.
+func createRoles(config Config) error {
+	if len(config.roles) == 0 {
+		return nil
+	}
+
+	return withDatabaseConnection(config, config.database, func(db *sql.DB) error {
+		for _, role := range config.roles {
+			if err := validateIdentifier("role", role.Name); err != nil {
+				return err
+			}
+
+			if _, err := db.Exec(createRoleStatement(role)); err != nil {
+				return fmt.Errorf("unable to create role %s: %w", role.Name, err)
+			}
+
+			for _, database := range role.Databases {
+				if err := ensureDatabaseOwner(db, database, role.Name); err != nil {
+					return fmt.Errorf("unable to create database %s owned by role %s: %w", database, role.Name, err)
+				}
+			}
+
+			for _, grant := range role.Grants {
+				if _, err := db.Exec(fmt.Sprintf("GRANT %s TO %s", grant, quoteIdentifier(role.Name))); err != nil {
+					return fmt.Errorf("unable to grant %s to role %s: %w", grant, role.Name, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func createRoleStatement(role Role) string {
+	options := append([]string{"LOGIN"}, role.Attributes...)
+	if role.Password != "" {
+		options = append(options, "PASSWORD "+quoteLiteral(role.Password))
+	}
+
+	return fmt.Sprintf("CREATE ROLE %s WITH %s", quoteIdentifier(role.Name), strings.Join(options, " "))
+}
+
+func ensureDatabaseOwner(db *sql.DB, database, owner string) error {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", database).Scan(&exists); err != nil {
+		return err
+	}
+
+	statement := "CREATE DATABASE %s OWNER %s"
+	if exists {
+		statement = "ALTER DATABASE %s OWNER TO %s"
+	}
+
+	_, err := db.Exec(fmt.Sprintf(statement, quoteIdentifier(database), quoteIdentifier(owner)))
+
+	return err
+}
@@ -0,0 +1,79 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Roles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9860).
+		Roles(Role{
+			Name:      "app",
+			Password:  "secret",
+			Databases: []string{"app", "postgres"},
+		}, Role{
+			Name:     "reader",
+			Password: "secret",
+			Grants:   []string{"pg_read_all_data"},
+		}))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9860 user=app password=secret dbname=app sslmode=disable")
+	require.NoError(t, err)
+
+	var owner string
+	require.NoError(t, db.QueryRow("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = 'postgres'").Scan(&owner))
+	assert.Equal(t, "app", owner)
+
+	var superuser bool
+	require.NoError(t, db.QueryRow("SELECT rolsuper FROM pg_roles WHERE rolname = current_user").Scan(&superuser))
+	assert.False(t, superuser)
+	require.NoError(t, db.Close())
+}
+
+func Test_createRoleStatement(t *testing.T) {
+	assert.Equal(t,
+		`CREATE ROLE "app" WITH LOGIN`,
+		createRoleStatement(Role{Name: "app"}))
+
+	assert.Equal(t,
+		`CREATE ROLE "app user" WITH LOGIN CREATEDB REPLICATION PASSWORD 'it''s secret'`,
+		createRoleStatement(Role{Name: "app user", Password: "it's secret", Attributes: []string{"CREATEDB", "REPLICATION"}}))
+
+	assert.Equal(t,
+		`CREATE ROLE "app" WITH LOGIN PASSWORD E'back\\slash'`,
+		createRoleStatement(Role{Name: "app", Password: `back\slash`}))
+}
+
+func Test_Config_RolesDoesNotShareState(t *testing.T) {
+	base := DefaultConfig().Roles(Role{Name: "a"})
+	first := base.Roles(Role{Name: "b"})
+	second := base.Roles(Role{Name: "c"})
+
+	assert.Equal(t, "b", first.roles[1].Name)
+	assert.Equal(t, "c", second.roles[1].Name)
+}
@@ -0,0 +1,139 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// serverLogFileName is the file the logging collector writes the server log to.
+	serverLogFileName = "postgresql.log"
+	// serverLogTailLines is the number of lines at the end of the server log included in errors.
+	serverLogTailLines = 50
+)
+
+// ServerLogPath returns the file the server log is written to, within the runtime directory. Only the output of initdb
+// and pg_ctl is written to the configured Logger, the server log is forwarded to the ServerLogger when configured.
+func (ep *EmbeddedPostgres) ServerLogPath() string {
+	return filepath.Join(ep.RuntimePath(), "log", serverLogFileName)
+}
+
+// ServerLog opens the server log of the server started last, which can be read while the server is running and after
+// it has stopped, until it is started again.
+func (ep *EmbeddedPostgres) ServerLog() (io.ReadCloser, error) {
+	file, err := os.Open(ep.ServerLogPath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open server log: %w", err)
+	}
+
+	return file, nil
+}
+
+// serverLogPollInterval is how often the server log is checked for output to forward to the server logger.
+var serverLogPollInterval = 100 * time.Millisecond
+
+// startServerLogForwarding forwards the server log to the configured server logger in the background while the server
+// is running.
+func (ep *EmbeddedPostgres) startServerLogForwarding() {
+	if ep.config.serverLogger == nil {
+		return
+	}
+
+	ep.serverLogStop = make(chan struct{})
+	ep.serverLogDone = make(chan struct{})
+
+	go forwardServerLog(ep.ServerLogPath(), ep.config.serverLogger, ep.serverLogStop, ep.serverLogDone)
+}
+
+func (ep *EmbeddedPostgres) stopServerLogForwarding() {
+	if ep.serverLogStop == nil {
+		return
+	}
+
+	close(ep.serverLogStop)
+	<-ep.serverLogDone
+
+	ep.serverLogStop = nil
+	ep.serverLogDone = nil
+}
+
+// forwardServerLog copies the server log to the writer as it grows until stop is closed, after which the remainder is
+// copied.
+func forwardServerLog(logPath string, writer io.Writer, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(serverLogPollInterval)
+	defer ticker.Stop()
+
+	var offset int64
+
+	for {
+		offset = copyServerLog(logPath, writer, offset)
+
+		select {
+		case <-stop:
+			copyServerLog(logPath, writer, offset)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyServerLog copies the server log from the offset to the writer, returning the offset copied up to.
+func copyServerLog(logPath string, writer io.Writer, offset int64) int64 {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return offset
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+
+	copied, _ := io.Copy(writer, file)
+
+	return offset + copied
+}
+
+// serverLogParameters returns the parameters which write the server log to a single file, which is never rotated.
+func serverLogParameters(logPath string) map[string]string {
+	return map[string]string{
+		"logging_collector": "on",
+		"log_directory":     filepath.Dir(logPath),
+		"log_filename":      filepath.Base(logPath),
+		"log_rotation_age":  "0",
+		"log_rotation_size": "0",
+	}
+}
+
+// statementLogParameters returns the parameters which log every statement, and its duration once it completed.
+func statementLogParameters() map[string]string {
+	return map[string]string{
+		"log_statement":              "all",
+		"log_min_duration_statement": "0",
+	}
+}
+
+// serverLogTail returns the last lines of the server log to be appended to an error, or nothing when there is no server
+// log to read.
+func serverLogTail(logPath string) string {
+	content, err := os.ReadFile(logPath)
+	if err != nil || len(content) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) > serverLogTailLines {
+		lines = lines[len(lines)-serverLogTailLines:]
+	}
+
+	return fmt.Sprintf("\nserver log %s:\n%s\n", logPath, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,87 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServerLog(t *testing.T) {
+	runtimePath := t.TempDir()
+	database := NewDatabase(DefaultConfig().RuntimePath(runtimePath))
+
+	assert.Equal(t, filepath.Join(runtimePath, "log", "postgresql.log"), database.ServerLogPath())
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(database.ServerLogPath()), 0700))
+	require.NoError(t, os.WriteFile(database.ServerLogPath(), []byte("LOG:  database system is ready to accept connections\n"), 0600))
+
+	serverLog, err := database.ServerLog()
+	require.NoError(t, err)
+
+	defer func() {
+		_ = serverLog.Close()
+	}()
+
+	content, err := io.ReadAll(serverLog)
+	require.NoError(t, err)
+	assert.Equal(t, "LOG:  database system is ready to accept connections\n", string(content))
+}
+
+func Test_ServerLog_ErrorWhenMissing(t *testing.T) {
+	database := NewDatabase(DefaultConfig().RuntimePath(t.TempDir()))
+
+	_, err := database.ServerLog()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to open server log")
+}
+
+func Test_serverLogParameters(t *testing.T) {
+	logPath := filepath.Join("/runtime", "log", "postgresql.log")
+
+	assert.Equal(t, map[string]string{
+		"logging_collector": "on",
+		"log_directory":     filepath.Join("/runtime", "log"),
+		"log_filename":      "postgresql.log",
+		"log_rotation_age":  "0",
+		"log_rotation_size": "0",
+	}, serverLogParameters(logPath))
+}
+
+func Test_serverParameters_LogAllStatements(t *testing.T) {
+	parameters := NewDatabase(DefaultConfig().LogAllStatements(true)).serverParameters()
+
+	assert.Equal(t, "all", parameters["log_statement"])
+	assert.Equal(t, "0", parameters["log_min_duration_statement"])
+
+	parameters = NewDatabase(DefaultConfig().
+		LogAllStatements(true).
+		StartParameters(map[string]string{"log_statement": "mod"})).serverParameters()
+
+	assert.Equal(t, "mod", parameters["log_statement"])
+
+	assert.NotContains(t, NewDatabase().serverParameters(), "log_statement")
+}
+
+func Test_serverLogTail(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "postgresql.log")
+
+	lines := make([]string, 0, 60)
+	for i := 1; i <= 60; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	require.NoError(t, os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	assert.Equal(t, "\nserver log "+logPath+":\n"+strings.Join(lines[10:], "\n")+"\n", serverLogTail(logPath))
+}
+
+func Test_serverLogTail_EmptyWhenMissing(t *testing.T) {
+	assert.Empty(t, serverLogTail(filepath.Join(t.TempDir(), "postgresql.log")))
+}
@@ -0,0 +1,158 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// parametersFileName is the file in the data directory holding the configured start parameters. It is included at the
+// end of postgresql.conf, so that its parameters override postgresql.conf while ALTER SYSTEM still overrides them.
+const parametersFileName = "embedded-postgres.conf"
+
+// testParameters trade durability for speed, which is safe for throwaway test databases only.
+var testParameters = map[string]string{
+	"fsync":              "off",
+	"synchronous_commit": "off",
+	"full_page_writes":   "off",
+	"shared_buffers":     "32MB",
+	"autovacuum":         "off",
+}
+
+// deterministicParameters make error messages and the formatting of timestamps independent of the machine running the
+// server.
+var deterministicParameters = map[string]string{
+	"lc_messages": "C",
+	"TimeZone":    "UTC",
+	"DateStyle":   "ISO, MDY",
+	"autovacuum":  "off",
+}
+
+// writeServerParameters writes the parameters to the parameters file of the data directory, making sure it is included
+// by postgresql.conf.
+func writeServerParameters(dataPath string, parameters map[string]string) error {
+	// without a postgresql.conf the server fails to start with a clearer error than ours
+	if _, err := os.Stat(filepath.Join(dataPath, "postgresql.conf")); os.IsNotExist(err) {
+		return nil
+	}
+
+	content := "# Written by embedded-postgres on every start, changes are overwritten.\n" + formatParameters(parameters)
+
+	if err := os.WriteFile(filepath.Join(dataPath, parametersFileName), []byte(content), 0600); err != nil {
+		return fmt.Errorf("unable to write server parameters to %s: %w", dataPath, err)
+	}
+
+	return includeParametersFile(dataPath)
+}
+
+// formatParameters formats the parameters as configuration file lines, sorted by name.
+func formatParameters(parameters map[string]string) string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	content := &strings.Builder{}
+
+	for _, name := range names {
+		fmt.Fprintf(content, "%s = '%s'\n", name, strings.ReplaceAll(parameters[name], "'", "''"))
+	}
+
+	return content.String()
+}
+
+func includeParametersFile(dataPath string) error {
+	configPath := filepath.Join(dataPath, "postgresql.conf")
+	include := fmt.Sprintf("include_if_exists = '%s'", parametersFileName)
+
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", configPath, err)
+	}
+
+	if strings.Contains(string(config), include) {
+		return nil
+	}
+
+	file, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to include server parameters in %s: %w", configPath, err)
+	}
+
+	if _, err := fmt.Fprintf(file, "\n%s\n", include); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to include server parameters in %s: %w", configPath, err)
+	}
+
+	return file.Close()
+}
+
+// SetServerParameter sets a server parameter, such as work_mem or log_statement, with ALTER SYSTEM. When apply is true
+// the parameter takes effect immediately, by reloading the configuration or, for parameters which can only be set at
+// server start such as shared_buffers, by restarting the server. Otherwise it takes effect on the next reload or
+// start. Parameters which are unknown or cannot be changed are rejected.
+func (ep *EmbeddedPostgres) SetServerParameter(name, value string, apply bool) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	var context string
+
+	err := withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		if err := db.QueryRow("SELECT context FROM pg_settings WHERE name = $1", name).Scan(&context); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("unknown server parameter %s", name)
+			}
+
+			return err
+		}
+
+		if context == "internal" {
+			return fmt.Errorf("server parameter %s cannot be changed", name)
+		}
+
+		if _, err := db.Exec(fmt.Sprintf("ALTER SYSTEM SET %s = %s", quoteIdentifier(name), quoteLiteral(value))); err != nil {
+			return fmt.Errorf("unable to set server parameter %s: %w", name, err)
+		}
+
+		return nil
+	})
+	if err != nil || !apply {
+		return err
+	}
+
+	if context == "postmaster" {
+		return ep.restart()
+	}
+
+	return ep.ReloadConfig()
+}
+
+// restart stops and starts the server process, keeping the data directory and any supervision in place.
+func (ep *EmbeddedPostgres) restart() error {
+	ep.stopMonitor()
+
+	if err := stopPostgres(ep); err != nil {
+		return fmt.Errorf("unable to restart postgres: %w", err)
+	}
+
+	if err := startPostgres(ep); err != nil {
+		// Stop returns early once no longer started, so the log forwarding is stopped here instead
+		ep.started = false
+		ep.markExited(err)
+		ep.stopServerLogForwarding()
+		_ = ep.syncedLogger.flush()
+
+		return err
+	}
+
+	ep.startMonitor()
+
+	return healthCheckDatabaseOrTimeout(ep.config)
+}
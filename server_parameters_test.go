@@ -0,0 +1,201 @@
+package embeddedpostgres
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetServerParameter(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9871))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	require.NoError(t, database.SetServerParameter("work_mem", "8MB", true))
+	require.NoError(t, database.SetServerParameter("max_connections", "42", true))
+
+	assert.EqualError(t, database.SetServerParameter("not_a_parameter", "1", true), "unknown server parameter not_a_parameter")
+	assert.EqualError(t, database.SetServerParameter("block_size", "16384", true), "server parameter block_size cannot be changed")
+
+	db, err := OpenDB("host=localhost port=9871 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	var workMem, maxConnections string
+	require.NoError(t, db.QueryRow("SHOW work_mem").Scan(&workMem))
+	require.NoError(t, db.QueryRow("SHOW max_connections").Scan(&maxConnections))
+	assert.Equal(t, "8MB", workMem)
+	assert.Equal(t, "42", maxConnections)
+	require.NoError(t, db.Close())
+}
+
+func Test_SetServerParameter_ErrorWhenNotStarted(t *testing.T) {
+	assert.EqualError(t, NewDatabase().SetServerParameter("work_mem", "8MB", true), "server has not been started")
+}
+
+func Test_StartParameters(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9873).
+		TuneForTests().
+		StartParameters(map[string]string{"max_connections": "42"}))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9873 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	var fsync, maxConnections string
+	require.NoError(t, db.QueryRow("SHOW fsync").Scan(&fsync))
+	require.NoError(t, db.QueryRow("SHOW max_connections").Scan(&maxConnections))
+	assert.Equal(t, "off", fsync)
+	assert.Equal(t, "42", maxConnections)
+	require.NoError(t, db.Close())
+}
+
+func Test_writeServerParameters(t *testing.T) {
+	dataPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataPath, "postgresql.conf"), []byte("max_connections = 100\n"), 0600))
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, writeServerParameters(dataPath, map[string]string{
+			"log_statement":    "all",
+			"fsync":            "off",
+			"application_name": "it's",
+		}))
+	}
+
+	parameters, err := os.ReadFile(filepath.Join(dataPath, parametersFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "# Written by embedded-postgres on every start, changes are overwritten.\n"+
+		"application_name = 'it''s'\n"+
+		"fsync = 'off'\n"+
+		"log_statement = 'all'\n", string(parameters))
+
+	config, err := os.ReadFile(filepath.Join(dataPath, "postgresql.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "max_connections = 100\n\ninclude_if_exists = 'embedded-postgres.conf'\n", string(config))
+}
+
+func Test_Config_StartParametersDoesNotShareState(t *testing.T) {
+	base := DefaultConfig().StartParameters(map[string]string{"fsync": "off"})
+	tuned := base.TuneForTests().StartParameters(map[string]string{"shared_buffers": "64MB"})
+
+	assert.Equal(t, map[string]string{"fsync": "off"}, base.startParameters)
+	assert.Equal(t, "64MB", tuned.startParameters["shared_buffers"])
+	assert.Equal(t, "off", tuned.startParameters["autovacuum"])
+	assert.Equal(t, "32MB", testParameters["shared_buffers"])
+	assert.True(t, tuned.initdbNoSync)
+	assert.False(t, base.initdbNoSync)
+}
+
+func Test_Config_ResourceSizing(t *testing.T) {
+	config := DefaultConfig().
+		TuneForTests().
+		MaxConnections(500).
+		SharedBuffers("16MB").
+		WorkMem("64MB")
+
+	assert.Equal(t, "500", config.startParameters["max_connections"])
+	assert.Equal(t, "16MB", config.startParameters["shared_buffers"])
+	assert.Equal(t, "64MB", config.startParameters["work_mem"])
+	assert.Equal(t, "off", config.startParameters["fsync"])
+}
+
+func Test_Config_DeterministicOutput(t *testing.T) {
+	config := DefaultConfig().
+		StartParameters(map[string]string{"fsync": "off"}).
+		DeterministicOutput()
+
+	assert.Equal(t, map[string]string{
+		"fsync":       "off",
+		"lc_messages": "C",
+		"TimeZone":    "UTC",
+		"DateStyle":   "ISO, MDY",
+		"autovacuum":  "off",
+	}, config.startParameters)
+}
+
+func Test_Config_Timeouts(t *testing.T) {
+	config := DefaultConfig().
+		StatementTimeout(30 * time.Second).
+		LockTimeout(1500 * time.Millisecond)
+
+	assert.Equal(t, "30000ms", config.startParameters["statement_timeout"])
+	assert.Equal(t, "1500ms", config.startParameters["lock_timeout"])
+	assert.Equal(t, "0ms", DefaultConfig().StatementTimeout(0).startParameters["statement_timeout"])
+	assert.Equal(t, "1ms", DefaultConfig().StatementTimeout(time.Microsecond).startParameters["statement_timeout"])
+	assert.Equal(t, "2ms", DefaultConfig().LockTimeout(1500 * time.Microsecond).startParameters["lock_timeout"])
+	assert.Equal(t, "0ms", DefaultConfig().LockTimeout(-time.Second).startParameters["lock_timeout"])
+}
+
+func Test_SetServerParameter_StopsServerLogForwardingWhenRestartFails(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		ServerLogger(io.Discard).
+		Port(9904))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	require.Error(t, database.SetServerParameter("shared_preload_libraries", "not_a_library", true))
+
+	assert.Nil(t, database.serverLogStop)
+	assert.Error(t, database.Stop())
+}
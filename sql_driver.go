@@ -0,0 +1,79 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// SQLDriver is a database/sql driver for Postgres, which the library opens the connections it hands out with, such as
+// those of OpenDB, Connect, CreateTestDatabase and AfterStart hooks. The library does not depend on a driver itself:
+// import the pgxdriver or pqdriver module, which registers one when imported.
+//
+//	import _ "github.com/RVennu/embedded-postgres/pqdriver"
+//
+// The statements the library runs itself, such as creating databases and roles, do not need a driver.
+type SQLDriver interface {
+	// OpenConnector returns a connector for a key/value DSN or postgres:// URL.
+	OpenConnector(dsn string) (driver.Connector, error)
+
+	// CopyFrom loads data, CSV records without a header, into the columns of the table on the connection. The table may
+	// be qualified with a schema, and as with COPY, empty fields are loaded as NULL.
+	CopyFrom(ctx context.Context, conn *sql.Conn, table string, columns []string, data io.Reader) error
+}
+
+var (
+	sqlDriverMu sync.RWMutex
+	sqlDriver   SQLDriver
+)
+
+// RegisterSQLDriver sets the driver the library opens connections with, replacing any registered before. It is called
+// by the init function of the driver modules.
+func RegisterSQLDriver(d SQLDriver) {
+	if d == nil {
+		panic("embeddedpostgres: RegisterSQLDriver driver is nil")
+	}
+
+	sqlDriverMu.Lock()
+	defer sqlDriverMu.Unlock()
+
+	sqlDriver = d
+}
+
+// registeredSQLDriver returns the registered driver, or an error telling how to register one.
+func registeredSQLDriver() (SQLDriver, error) {
+	sqlDriverMu.RLock()
+	defer sqlDriverMu.RUnlock()
+
+	if sqlDriver == nil {
+		return nil, errors.New("no SQL driver is registered, import github.com/RVennu/embedded-postgres/pgxdriver " +
+			"or github.com/RVennu/embedded-postgres/pqdriver")
+	}
+
+	return sqlDriver, nil
+}
+
+// SQLConnector returns a connector for the DSN using the registered driver. Key/value DSNs and postgres:// URLs are
+// accepted, as produced by ConnectionInfo.
+func SQLConnector(dsn string) (driver.Connector, error) {
+	d, err := registeredSQLDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.OpenConnector(dsn)
+}
+
+// OpenDB opens a *sql.DB for the DSN using the registered driver. Unlike sql.Open it does not need the name the driver
+// is registered with database/sql under.
+func OpenDB(dsn string) (*sql.DB, error) {
+	connector, err := SQLConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(connector), nil
+}
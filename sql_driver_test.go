@@ -0,0 +1,75 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoinits
+func init() {
+	// the driver modules depend on this one, so its tests connect through the internal connection instead
+	RegisterSQLDriver(internalSQLDriver{})
+}
+
+type internalSQLDriver struct{}
+
+func (internalSQLDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	return newWireConnector(dsn)
+}
+
+func (internalSQLDriver) CopyFrom(context.Context, *sql.Conn, string, []string, io.Reader) error {
+	return errors.New("COPY is not supported by the internal connection")
+}
+
+func withSQLDriver(t *testing.T, d SQLDriver) {
+	sqlDriverMu.Lock()
+	previous := sqlDriver
+	sqlDriver = d
+	sqlDriverMu.Unlock()
+
+	t.Cleanup(func() {
+		sqlDriverMu.Lock()
+		sqlDriver = previous
+		sqlDriverMu.Unlock()
+	})
+}
+
+func Test_SQLConnector(t *testing.T) {
+	connector, err := SQLConnector("host=localhost port=9999 user=gin password=wine dbname=beer sslmode=disable")
+
+	require.NoError(t, err)
+	assert.IsType(t, &wireConnector{}, connector)
+
+	_, err = SQLConnector("host='unterminated")
+	assert.Error(t, err)
+}
+
+func Test_SQLConnector_ErrorWhenNoDriverIsRegistered(t *testing.T) {
+	withSQLDriver(t, nil)
+
+	_, err := OpenDB("host=localhost port=9999 user=gin password=wine dbname=beer sslmode=disable")
+
+	assert.EqualError(t, err, "no SQL driver is registered, import github.com/RVennu/embedded-postgres/pgxdriver "+
+		"or github.com/RVennu/embedded-postgres/pqdriver")
+}
+
+func Test_RegisterSQLDriver(t *testing.T) {
+	withSQLDriver(t, nil)
+
+	RegisterSQLDriver(internalSQLDriver{})
+
+	registered, err := registeredSQLDriver()
+	require.NoError(t, err)
+	assert.Equal(t, internalSQLDriver{}, registered)
+
+	assert.Panics(t, func() {
+		RegisterSQLDriver(nil)
+	})
+}
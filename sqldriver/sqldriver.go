@@ -0,0 +1,21 @@
+// Package sqldriver registers the embedded Postgres database/sql driver under the name "embeddedpostgres", so that
+// the server is started on the first connection of sql.Open and stopped when the *sql.DB is closed.
+//
+//	import _ "github.com/RVennu/embedded-postgres/sqldriver"
+//
+//	db, err := sql.Open("embeddedpostgres", "port=5432 user=postgres password=postgres dbname=postgres")
+package sqldriver
+
+import (
+	"database/sql"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// DriverName is the name the driver is registered under.
+const DriverName = "embeddedpostgres"
+
+//nolint:gochecknoinits
+func init() {
+	sql.Register(DriverName, embeddedpostgres.Driver{})
+}
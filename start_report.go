@@ -0,0 +1,36 @@
+package embeddedpostgres
+
+import "time"
+
+// StartReport is the timing breakdown of a start of the server, to quantify the impact of caching options and of slow
+// file systems on the time to start. Phases that were not needed, such as downloading cached binaries, take zero time.
+type StartReport struct {
+	// DownloadTime is the time taken to download the binaries.
+	DownloadTime time.Duration
+	// ExtractTime is the time taken to extract the binaries.
+	ExtractTime time.Duration
+	// InitDbTime is the time taken to initialize the data directory.
+	InitDbTime time.Duration
+	// ReadyTime is the time taken by all of Start, until the server was ready for use.
+	ReadyTime time.Duration
+}
+
+// StartReport returns the timing breakdown of the last call to Start, which is complete once Start returned without
+// an error.
+func (ep *EmbeddedPostgres) StartReport() StartReport {
+	return ep.startReport
+}
+
+// record adds the phase completed by the lifecycle event to the report.
+func (r *StartReport) record(event LifecycleEvent) {
+	switch event.Type {
+	case EventDownloadFinished:
+		r.DownloadTime = event.Elapsed
+	case EventExtracted:
+		r.ExtractTime = event.Elapsed
+	case EventInitDbCompleted:
+		r.InitDbTime = event.Elapsed
+	case EventReady:
+		r.ReadyTime = event.Elapsed
+	}
+}
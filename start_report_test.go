@@ -0,0 +1,76 @@
+package embeddedpostgres
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StartReport(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9891))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	report := database.StartReport()
+	assert.Positive(t, report.InitDbTime)
+	assert.Greater(t, report.ReadyTime, report.InitDbTime)
+}
+
+func Test_StartReport_DownloadAndInitDatabase(t *testing.T) {
+	archive, cleanUp := createTempXzArchive()
+	defer cleanUp()
+
+	cacheLocation := filepath.Join(t.TempDir(), "embedded-postgres-binaries-linux-amd64-15.3.0.txz")
+	cached := false
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		StartTimeout(time.Second))
+	database.cacheLocator = func() (string, bool) {
+		return cacheLocation, cached
+	}
+	database.remoteFetchStrategy = func() error {
+		cached = true
+		return copyFile(archive, cacheLocation, 0600)
+	}
+	database.initDatabase = func(binaryExtractLocation, runtimePath, pgDataDir string, options initdbOptions, logger *os.File) error {
+		return nil
+	}
+
+	require.Error(t, database.Start())
+
+	report := database.StartReport()
+	assert.Positive(t, report.DownloadTime)
+	assert.Positive(t, report.ExtractTime)
+	assert.Positive(t, report.InitDbTime)
+	assert.Zero(t, report.ReadyTime)
+}
+
+func Test_StartReport_ResetByStart(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:9892")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	database := NewDatabase(DefaultConfig().Port(9892))
+	database.startReport = StartReport{DownloadTime: time.Second, ReadyTime: time.Minute}
+
+	require.Error(t, database.Start())
+	assert.Equal(t, StartReport{}, database.StartReport())
+}
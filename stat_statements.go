@@ -0,0 +1,106 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StatementStatistics are the statistics pg_stat_statements tracked for a normalized statement, such as
+// SELECT * FROM users WHERE id = $1, across all its executions.
+type StatementStatistics struct {
+	// Query is the normalized text of the statement.
+	Query string
+	// Calls is the number of times the statement was executed.
+	Calls int64
+	// Rows is the total number of rows retrieved or affected by the statement.
+	Rows int64
+	// TotalTime is the total time spent executing the statement.
+	TotalTime time.Duration
+	// MeanTime is the mean time spent executing the statement.
+	MeanTime time.Duration
+}
+
+// TopStatements returns the statistics of at most limit statements executed in the database, those which took the
+// most time in total first, so that performance tests can assert on query counts and timings. The server must be
+// configured with StatStatements.
+func (ep *EmbeddedPostgres) TopStatements(ctx context.Context, limit int) ([]StatementStatistics, error) {
+	if !ep.started {
+		return nil, errors.New("server has not been started")
+	}
+
+	var statements []StatementStatistics
+
+	if err := withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		var serverVersion int
+		if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&serverVersion); err != nil {
+			return err
+		}
+
+		rows, err := db.QueryContext(ctx, topStatementsQuery(serverVersion), limit)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = rows.Close()
+		}()
+
+		for rows.Next() {
+			var (
+				statement       StatementStatistics
+				totalMs, meanMs float64
+			)
+
+			if err := rows.Scan(&statement.Query, &statement.Calls, &statement.Rows, &totalMs, &meanMs); err != nil {
+				return err
+			}
+
+			statement.TotalTime = millisecondsToDuration(totalMs)
+			statement.MeanTime = millisecondsToDuration(meanMs)
+			statements = append(statements, statement)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("unable to read statement statistics: %w", err)
+	}
+
+	return statements, nil
+}
+
+// ResetStatementStatistics discards the statistics tracked by pg_stat_statements so far, for example so that each test
+// only asserts on its own statements.
+func (ep *EmbeddedPostgres) ResetStatementStatistics(ctx context.Context) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	if err := withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, "SELECT pg_stat_statements_reset()")
+		return err
+	}); err != nil {
+		return fmt.Errorf("unable to reset statement statistics: %w", err)
+	}
+
+	return nil
+}
+
+// topStatementsQuery returns the query for the top statements of the current database, whose timing columns were
+// renamed in Postgres 13.
+func topStatementsQuery(serverVersion int) string {
+	totalTime, meanTime := "total_exec_time", "mean_exec_time"
+	if serverVersion < 130000 {
+		totalTime, meanTime = "total_time", "mean_time"
+	}
+
+	return fmt.Sprintf("SELECT query, calls, rows, %[1]s, %[2]s FROM pg_stat_statements "+
+		"WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database()) "+
+		"ORDER BY %[1]s DESC LIMIT $1", totalTime, meanTime)
+}
+
+func millisecondsToDuration(milliseconds float64) time.Duration {
+	return time.Duration(milliseconds * float64(time.Millisecond))
+}
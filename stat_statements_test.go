@@ -0,0 +1,83 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StatStatements(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9893).
+		StatStatements())
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	require.NoError(t, database.ResetStatementStatistics(ctx))
+
+	require.NoError(t, withDatabaseConnection(DefaultConfig().Port(9893), "postgres", func(db *sql.DB) error {
+		for i := 0; i < 3; i++ {
+			if _, err := db.Exec("SELECT count(*) FROM pg_class WHERE relpages > $1", i); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	statements, err := database.TopStatements(ctx, 100)
+	require.NoError(t, err)
+
+	var found bool
+
+	for _, statement := range statements {
+		if strings.Contains(statement.Query, "FROM pg_class WHERE relpages >") {
+			found = true
+
+			assert.Equal(t, int64(3), statement.Calls)
+			assert.Equal(t, int64(3), statement.Rows)
+			assert.Positive(t, statement.TotalTime)
+		}
+	}
+
+	assert.True(t, found, "statement not found in %v", statements)
+}
+
+func Test_Config_StatStatements(t *testing.T) {
+	config := DefaultConfig().StatStatements()
+
+	assert.Equal(t, []string{"pg_stat_statements"}, config.sharedPreloadLibraries)
+	assert.Equal(t, []string{"pg_stat_statements"}, config.extensions)
+}
+
+func Test_topStatementsQuery(t *testing.T) {
+	assert.Equal(t, "SELECT query, calls, rows, total_exec_time, mean_exec_time FROM pg_stat_statements "+
+		"WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database()) "+
+		"ORDER BY total_exec_time DESC LIMIT $1", topStatementsQuery(150003))
+	assert.Contains(t, topStatementsQuery(120011), "total_time, mean_time FROM pg_stat_statements")
+}
+
+func Test_TopStatements_ErrorWhenNotStarted(t *testing.T) {
+	_, err := NewDatabase().TopStatements(context.Background(), 10)
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_ResetStatementStatistics_ErrorWhenNotStarted(t *testing.T) {
+	err := NewDatabase().ResetStatementStatistics(context.Background())
+
+	assert.EqualError(t, err, "server has not been started")
+}
@@ -0,0 +1,175 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Err returns the error describing an unexpected exit of the Postgres process since it was last started, such as a
+// crash or the process being killed, or nil when the process has not exited unexpectedly or has been restarted. Once
+// the process has exited, Stop also returns this error.
+func (ep *EmbeddedPostgres) Err() error {
+	ep.exitMu.Lock()
+	defer ep.exitMu.Unlock()
+
+	return ep.exitStatus.Err
+}
+
+// ExitStatus describes whether and how the Postgres process exited since it was last started. As the process is
+// daemonized by pg_ctl, its exit code and signal are not available; an unexpected exit is described by Err instead.
+type ExitStatus struct {
+	// Exited reports whether the process has exited, either stopped by Stop or unexpectedly.
+	Exited bool
+	// Err describes why the process exited unexpectedly, or why Start failed, and is nil for a clean shutdown.
+	Err error
+}
+
+// Clean reports whether the process exited because it was stopped by Stop.
+func (s ExitStatus) Clean() bool {
+	return s.Exited && s.Err == nil
+}
+
+// Wait blocks until the Postgres process exits, returning nil when it was stopped by Stop or the error describing an
+// unexpected exit.
+func (ep *EmbeddedPostgres) Wait() error {
+	ep.exitMu.Lock()
+	exited := ep.exited
+	ep.exitMu.Unlock()
+
+	if exited == nil {
+		return errors.New("server has not been started")
+	}
+
+	<-exited
+
+	return ep.ExitStatus().Err
+}
+
+// ExitStatus returns whether and how the Postgres process exited since it was last started, without blocking.
+func (ep *EmbeddedPostgres) ExitStatus() ExitStatus {
+	ep.exitMu.Lock()
+	defer ep.exitMu.Unlock()
+
+	return ep.exitStatus
+}
+
+// startMonitor supervises the started Postgres process in the background to detect it exiting unexpectedly.
+func (ep *EmbeddedPostgres) startMonitor() {
+	ep.exitMu.Lock()
+	ep.exitStatus = ExitStatus{}
+	ep.exited = make(chan struct{})
+	ep.exitMu.Unlock()
+
+	pid, err := readPostmasterPID(ep.config.dataPath)
+	if err != nil {
+		return
+	}
+
+	ep.monitorStop = make(chan struct{})
+	ep.monitorDone = make(chan struct{})
+
+	go ep.superviseProcess(pid, ep.monitorStop, ep.monitorDone)
+}
+
+func (ep *EmbeddedPostgres) stopMonitor() {
+	if ep.monitorStop == nil {
+		return
+	}
+
+	close(ep.monitorStop)
+	<-ep.monitorDone
+
+	ep.monitorStop = nil
+	ep.monitorDone = nil
+}
+
+// superviseProcess watches the process until stop is closed. When the process exits unexpectedly it is restarted, as
+// long as restarts remain, otherwise the exit is recorded as the error returned by Err.
+func (ep *EmbeddedPostgres) superviseProcess(pid int, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for restarts := 0; ; restarts++ {
+		if !watchProcess(stop, func() bool { return processAlive(pid) }) {
+			return
+		}
+
+		logContent, _ := readLogsOrTimeout(ep.syncedLogger.file)
+		exitErr := fmt.Errorf("postgres process %d exited unexpectedly:\n%s%s", pid, string(logContent), serverLogTail(ep.ServerLogPath()))
+
+		ep.emit(EventCrashed, time.Time{})
+
+		if ep.config.onCrash != nil {
+			ep.config.onCrash(exitErr)
+		}
+
+		if restarts >= ep.config.maxRestarts {
+			ep.markExited(exitErr)
+			return
+		}
+
+		select {
+		case <-stop:
+			ep.markExited(exitErr)
+			return
+		case <-time.After(restartBackoff(ep.config.restartBackoff, restarts)):
+		}
+
+		restartStarted := time.Now()
+
+		if err := startPostgres(ep); err != nil {
+			ep.markExited(fmt.Errorf("%s\nrestart failed: %w", exitErr, err))
+			return
+		}
+
+		restartedPID, err := readPostmasterPID(ep.config.dataPath)
+		if err != nil {
+			ep.markExited(fmt.Errorf("%s\nrestart failed: %w", exitErr, err))
+			return
+		}
+
+		pid = restartedPID
+
+		ep.emit(EventRestarted, restartStarted)
+	}
+}
+
+// markExited records that the process has exited, cleanly when err is nil, and releases any callers of Wait. As there
+// is no longer a server for this process to own, its lifeline is released too.
+func (ep *EmbeddedPostgres) markExited(err error) {
+	ep.exitMu.Lock()
+	defer ep.exitMu.Unlock()
+
+	if ep.exitStatus.Exited {
+		return
+	}
+
+	ep.releaseLifeline()
+
+	ep.exitStatus = ExitStatus{Exited: true, Err: err}
+
+	if ep.exited != nil {
+		close(ep.exited)
+	}
+}
+
+// maxRestartBackoff caps the doubled backoff between restarts.
+const maxRestartBackoff = time.Minute
+
+// restartBackoff doubles the backoff for each restart already attempted, stopping at maxRestartBackoff, or at backoff
+// itself when larger, so that large restart counts cannot overflow.
+func restartBackoff(backoff time.Duration, restarts int) time.Duration {
+	if backoff <= 0 || backoff >= maxRestartBackoff {
+		return backoff
+	}
+
+	for i := 0; i < restarts && backoff < maxRestartBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxRestartBackoff {
+		return maxRestartBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,118 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RestartOnCrash(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	crashes := make(chan error, 2)
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9867).
+		RestartOnCrash(1, 100*time.Millisecond).
+		OnCrash(func(err error) {
+			crashes <- err
+		}))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if database.started {
+			_ = database.Stop()
+		}
+	}()
+
+	require.NoError(t, killPostgres(database.DataPath()))
+	<-crashes
+
+	db, err := OpenDB("host=localhost port=9867 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return db.Ping() == nil }, 20*time.Second, 100*time.Millisecond)
+	assert.NoError(t, database.Err())
+	require.NoError(t, db.Close())
+
+	require.NoError(t, killPostgres(database.DataPath()))
+	<-crashes
+
+	assert.Eventually(t, func() bool { return database.Err() != nil }, 10*time.Second, 100*time.Millisecond)
+}
+
+func Test_restartBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, restartBackoff(time.Second, 0))
+	assert.Equal(t, 2*time.Second, restartBackoff(time.Second, 1))
+	assert.Equal(t, 8*time.Second, restartBackoff(time.Second, 3))
+	assert.Equal(t, time.Minute, restartBackoff(time.Second, 6))
+	assert.Equal(t, time.Minute, restartBackoff(time.Second, 1000))
+	assert.Equal(t, time.Minute, restartBackoff(time.Second, math.MaxInt))
+	assert.Equal(t, 2*time.Minute, restartBackoff(2*time.Minute, 3))
+	assert.Equal(t, time.Duration(0), restartBackoff(0, math.MaxInt))
+}
+
+func Test_Wait(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "embedded_postgres_test")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(tempDir).
+		Port(9869))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	assert.False(t, database.ExitStatus().Exited)
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- database.Wait()
+	}()
+
+	require.NoError(t, database.Stop())
+	assert.NoError(t, <-waited)
+	assert.True(t, database.ExitStatus().Clean())
+}
+
+func Test_Wait_ErrorWhenNotStarted(t *testing.T) {
+	assert.EqualError(t, NewDatabase().Wait(), "server has not been started")
+}
+
+func Test_markExited(t *testing.T) {
+	database := NewDatabase()
+	database.exited = make(chan struct{})
+
+	database.markExited(errors.New("postgres process 42 exited unexpectedly"))
+	database.markExited(nil)
+
+	assert.EqualError(t, database.Wait(), "postgres process 42 exited unexpectedly")
+	assert.Equal(t, ExitStatus{Exited: true, Err: database.Err()}, database.ExitStatus())
+	assert.False(t, database.ExitStatus().Clean())
+}
@@ -0,0 +1,80 @@
+// Package tccompat adapts embedded Postgres servers to the Host, MappedPort, Endpoint and Terminate methods of
+// testcontainers-go containers, so that test harnesses abstracting over containers can run against an embedded server
+// instead of a Postgres container.
+package tccompat
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/docker/go-connections/nat"
+)
+
+// ContainerPort is the port exposed by the official Postgres images, which MappedPort maps to the port of the server.
+const ContainerPort = nat.Port("5432/tcp")
+
+// Container presents an embedded Postgres server as a Postgres container.
+type Container struct {
+	database *embeddedpostgres.EmbeddedPostgres
+}
+
+// New adapts a server, which the caller starts unless it already has.
+func New(database *embeddedpostgres.EmbeddedPostgres) *Container {
+	return &Container{database: database}
+}
+
+// Start starts a server with the configuration, as creating a container with testcontainers-go starts it.
+func Start(config embeddedpostgres.Config) (*Container, error) {
+	database := embeddedpostgres.NewDatabase(config)
+	if err := database.Start(); err != nil {
+		return nil, err
+	}
+
+	return New(database), nil
+}
+
+// Database returns the adapted server.
+func (c *Container) Database() *embeddedpostgres.EmbeddedPostgres {
+	return c.database
+}
+
+// Host returns the host the server listens on.
+func (c *Container) Host(_ context.Context) (string, error) {
+	return c.database.ConnectionInfo().Host, nil
+}
+
+// MappedPort returns the port of the server for ContainerPort, the only port a Postgres container exposes.
+func (c *Container) MappedPort(_ context.Context, port nat.Port) (nat.Port, error) {
+	if port.Port() != ContainerPort.Port() || port.Proto() != ContainerPort.Proto() {
+		return "", fmt.Errorf("port %s is not exposed, only %s is", port, ContainerPort)
+	}
+
+	return nat.NewPort(ContainerPort.Proto(), strconv.FormatUint(uint64(c.database.ConnectionInfo().Port), 10))
+}
+
+// Endpoint returns the host and port of the server, prefixed with the protocol followed by :// unless it is empty.
+func (c *Container) Endpoint(ctx context.Context, proto string) (string, error) {
+	host, err := c.Host(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	port, err := c.MappedPort(ctx, ContainerPort)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s:%s", host, port.Port())
+	if proto == "" {
+		return endpoint, nil
+	}
+
+	return proto + "://" + endpoint, nil
+}
+
+// Terminate stops the server.
+func (c *Container) Terminate(_ context.Context) error {
+	return c.database.Stop()
+}
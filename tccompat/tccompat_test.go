@@ -0,0 +1,49 @@
+package tccompat
+
+import (
+	"context"
+	"testing"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Container(t *testing.T) {
+	container := New(embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().Port(9999)))
+	ctx := context.Background()
+
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", host)
+
+	for _, port := range []nat.Port{"5432/tcp", "5432"} {
+		mapped, err := container.MappedPort(ctx, port)
+		require.NoError(t, err)
+		assert.Equal(t, nat.Port("9999/tcp"), mapped)
+	}
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:9999", endpoint)
+
+	endpoint, err = container.Endpoint(ctx, "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://localhost:9999", endpoint)
+}
+
+func Test_Container_MappedPortErrorWhenNotExposed(t *testing.T) {
+	container := New(embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().Port(9999)))
+
+	for _, port := range []nat.Port{"8080/tcp", "5432/udp"} {
+		_, err := container.MappedPort(context.Background(), port)
+		assert.EqualError(t, err, "port "+string(port)+" is not exposed, only 5432/tcp is")
+	}
+}
+
+func Test_Container_TerminateErrorWhenNotStarted(t *testing.T) {
+	container := New(embeddedpostgres.NewDatabase())
+
+	assert.EqualError(t, container.Terminate(context.Background()), "server has not been started")
+}
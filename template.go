@@ -0,0 +1,112 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SnapshotTemplate copies the current state of a seeded database into a template database, from which
+// ResetFromTemplate can later recreate it. Any sessions connected to the database are terminated, as Postgres requires
+// the source of a copy to be unused. A previous snapshot of the same database is replaced.
+func (ep *EmbeddedPostgres) SnapshotTemplate(database string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	template := templateDatabaseName(database)
+
+	err := withDatabaseConnection(ep.config, "postgres", func(db *sql.DB) error {
+		if err := dropTemplateDatabase(db, template); err != nil {
+			return err
+		}
+
+		if err := terminateConnections(db, database); err != nil {
+			return err
+		}
+
+		statements := []string{
+			fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quoteIdentifier(template), quoteIdentifier(database)),
+			fmt.Sprintf("ALTER DATABASE %s IS_TEMPLATE true", quoteIdentifier(template)),
+		}
+
+		for _, statement := range statements {
+			if _, err := db.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to snapshot database %s as template: %w", database, err)
+	}
+
+	return nil
+}
+
+// ResetFromTemplate drops the database and recreates it from the template taken by SnapshotTemplate, which is
+// considerably faster than re-running migrations and seeds between tests. Any sessions connected to the database are
+// terminated.
+func (ep *EmbeddedPostgres) ResetFromTemplate(database string) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	template := templateDatabaseName(database)
+
+	err := withDatabaseConnection(ep.config, "postgres", func(db *sql.DB) error {
+		if err := terminateConnections(db, database); err != nil {
+			return err
+		}
+
+		statements := []string{
+			fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteIdentifier(database)),
+			fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quoteIdentifier(database), quoteIdentifier(template)),
+		}
+
+		for _, statement := range statements {
+			if _, err := db.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to reset database %s from template: %w", database, err)
+	}
+
+	return nil
+}
+
+func dropTemplateDatabase(db *sql.DB, template string) error {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", template).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	// template databases cannot be dropped until they are unmarked
+	if _, err := db.Exec(fmt.Sprintf("ALTER DATABASE %s IS_TEMPLATE false", quoteIdentifier(template))); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(fmt.Sprintf("DROP DATABASE %s", quoteIdentifier(template)))
+
+	return err
+}
+
+func templateDatabaseName(database string) string {
+	const suffix = "_template"
+
+	// keep within the 63 byte identifier limit
+	if len(database)+len(suffix) > 63 {
+		database = database[:63-len(suffix)]
+	}
+
+	return database + suffix
+}
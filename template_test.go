@@ -0,0 +1,62 @@
+package embeddedpostgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResetFromTemplate(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		Port(9846).
+		Database("beer"))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9846 user=postgres password=postgres dbname=beer sslmode=disable")
+	require.NoError(t, err)
+
+	_, err = db.Exec("CREATE TABLE beers(name text); INSERT INTO beers VALUES ('Punk IPA')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, database.SnapshotTemplate("beer"))
+
+	db, err = OpenDB("host=localhost port=9846 user=postgres password=postgres dbname=beer sslmode=disable")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO beers VALUES ('Elvis Juice')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, database.ResetFromTemplate("beer"))
+
+	db, err = OpenDB("host=localhost port=9846 user=postgres password=postgres dbname=beer sslmode=disable")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM beers").Scan(&count))
+	assert.Equal(t, 1, count)
+	require.NoError(t, db.Close())
+}
+
+func Test_ErrorWhenSnapshotTemplateBeforeStart(t *testing.T) {
+	database := NewDatabase()
+
+	assert.EqualError(t, database.SnapshotTemplate("beer"), "server has not been started")
+	assert.EqualError(t, database.ResetFromTemplate("beer"), "server has not been started")
+}
+
+func Test_templateDatabaseName(t *testing.T) {
+	assert.Equal(t, "beer_template", templateDatabaseName("beer"))
+	assert.Equal(t, strings.Repeat("a", 54)+"_template", templateDatabaseName(strings.Repeat("a", 70)))
+}
@@ -0,0 +1,79 @@
+package embeddedpostgres
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+)
+
+// TestingT is the subset of testing.TB used by the test helpers, satisfied by *testing.T and *testing.B.
+type TestingT interface {
+	Helper()
+	Name() string
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// CreateTestDatabase creates a uniquely named database for the calling test on the running server, returning a
+// connection to it along with its DSN. The database is dropped when the test completes, so parallel tests sharing one
+// server are isolated from each other.
+func (ep *EmbeddedPostgres) CreateTestDatabase(t TestingT) (*sql.DB, string) {
+	t.Helper()
+
+	if !ep.started {
+		t.Fatalf("unable to create test database: server has not been started")
+	}
+
+	database := testDatabaseName(t.Name())
+
+	if err := ep.createDatabase(ep.config, database, ep.config.databaseOptions); err != nil {
+		t.Fatalf("unable to create test database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := dropDatabase(ep.config, database); err != nil {
+			t.Errorf("unable to drop test database: %s", err)
+		}
+	})
+
+	dsn := ep.config.Database(database).connectionDSN()
+
+	db, err := OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("unable to connect to test database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("unable to close test database connection: %s", err)
+		}
+	})
+
+	return db, dsn
+}
+
+// testDatabaseName derives a valid, unique database name from a test name.
+func testDatabaseName(testName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, testName)
+
+	// leave room for the prefix and random suffix within the 63 byte identifier limit
+	if len(name) > 45 {
+		name = name[:45]
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return "test_" + name + "_" + hex.EncodeToString(suffix)
+}
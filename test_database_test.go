@@ -0,0 +1,48 @@
+package embeddedpostgres
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateTestDatabase(t *testing.T) {
+	database := NewDatabase(DefaultConfig().Port(9845))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	var name string
+
+	t.Run("Isolated", func(t *testing.T) {
+		db, dsn := database.CreateTestDatabase(t)
+
+		require.NoError(t, db.QueryRow("SELECT current_database()").Scan(&name))
+		assert.True(t, strings.HasPrefix(name, "test_test_createtestdatabase_isolated_"))
+		assert.Contains(t, dsn, "/"+name+"?sslmode=disable")
+	})
+
+	conn, err := openDatabaseConnection(DefaultConfig().Port(9845), "postgres")
+	require.NoError(t, err)
+
+	var exists bool
+	require.NoError(t, sql.OpenDB(conn).QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists))
+	assert.False(t, exists)
+}
+
+func Test_testDatabaseName(t *testing.T) {
+	name := testDatabaseName("Test_Something/With Spaces-And-A-Very-Long-Name-That-Goes-On-And-On")
+
+	assert.Regexp(t, "^test_test_something_with_spaces_and_a_very_long_na_[0-9a-f]{8}$", name)
+	assert.LessOrEqual(t, len(name), 63)
+	assert.NotEqual(t, name, testDatabaseName("Test_Something/With Spaces-And-A-Very-Long-Name-That-Goes-On-And-On"))
+}
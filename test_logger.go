@@ -0,0 +1,51 @@
+package embeddedpostgres
+
+import (
+	"bytes"
+	"sync"
+)
+
+// TestLogger is the subset of testing.TB used to forward logs to a test, satisfied by *testing.T and *testing.B.
+type TestLogger interface {
+	Helper()
+	Logf(format string, args ...interface{})
+}
+
+// TestLogWriter forwards each line written to it to the Logf method of a test. It is safe for concurrent use.
+type TestLogWriter struct {
+	t      TestLogger
+	prefix string
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// NewTestLogWriter returns a writer which forwards each line written to it to t.Logf with the prefix, to be used as
+// the Logger or ServerLogger of a configuration. An incomplete line is held until the rest of it is written.
+func NewTestLogWriter(t TestLogger, prefix string) *TestLogWriter {
+	return &TestLogWriter{t: t, prefix: prefix}
+}
+
+func (w *TestLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.t.Helper()
+
+	w.partial = append(w.partial, p...)
+
+	for {
+		end := bytes.IndexByte(w.partial, '\n')
+		if end < 0 {
+			break
+		}
+
+		w.t.Logf("%s%s", w.prefix, bytes.TrimSuffix(w.partial[:end], []byte("\r")))
+		w.partial = w.partial[end+1:]
+	}
+
+	// so that the lines already logged are not retained by the buffer
+	w.partial = append([]byte(nil), w.partial...)
+
+	return len(p), nil
+}
@@ -0,0 +1,97 @@
+package embeddedpostgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTestLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingTestLogger) Helper() {}
+
+func (l *recordingTestLogger) Logf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingTestLogger) recorded() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]string(nil), l.lines...)
+}
+
+func Test_TestLogWriter(t *testing.T) {
+	logger := &recordingTestLogger{}
+	writer := NewTestLogWriter(logger, "postgres: ")
+
+	n, err := writer.Write([]byte("waiting for server to start...."))
+	require.NoError(t, err)
+	assert.Equal(t, 31, n)
+	assert.Empty(t, logger.recorded())
+
+	_, err = writer.Write([]byte(" done\r\nserver started\nLOG:  database system is"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"postgres: waiting for server to start.... done",
+		"postgres: server started",
+	}, logger.recorded())
+}
+
+func Test_LogToTest(t *testing.T) {
+	logger := &recordingTestLogger{}
+
+	config := DefaultConfig().LogToTest(logger)
+
+	assert.IsType(t, &TestLogWriter{}, config.logger)
+	assert.Same(t, config.logger, config.serverLogger)
+}
+
+func Test_forwardServerLog(t *testing.T) {
+	original := serverLogPollInterval
+	serverLogPollInterval = time.Millisecond
+
+	defer func() {
+		serverLogPollInterval = original
+	}()
+
+	logPath := filepath.Join(t.TempDir(), "postgresql.log")
+	logger := &recordingTestLogger{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go forwardServerLog(logPath, NewTestLogWriter(logger, ""), stop, done)
+
+	require.NoError(t, os.WriteFile(logPath, []byte("LOG:  database system is ready to accept connections\n"), 0600))
+
+	assert.Eventually(t, func() bool {
+		return len(logger.recorded()) == 1
+	}, 5*time.Second, time.Millisecond)
+
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString("LOG:  database system is shut down\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	close(stop)
+	<-done
+
+	assert.Equal(t, []string{
+		"LOG:  database system is ready to accept connections",
+		"LOG:  database system is shut down",
+	}, logger.recorded())
+}
@@ -0,0 +1,323 @@
+package embeddedpostgres
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	serverCertificateFileName = "server.crt"
+	serverKeyFileName         = "server.key"
+	rootCertificateFileName   = "root.crt"
+	clientCertificatesDir     = "client-certificates"
+	clientCertificateRule     = "# client certificates required by embedded-postgres"
+	certificateValidity       = 10 * 365 * 24 * time.Hour
+)
+
+// certificateAuthority signs the certificates generated for a server, so that clients can verify them with
+// sslmode=verify-full.
+type certificateAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+func newCertificateAuthority() (*certificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := certificateTemplate("embedded-postgres CA")
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificateAuthority{certificate: certificate, key: key}, nil
+}
+
+// sign issues a certificate for the common name, valid for the given host names and IP addresses when used by a server.
+func (ca *certificateAuthority) sign(commonName string, usage x509.ExtKeyUsage, hosts ...string) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := certificateTemplate(commonName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return der, key, nil
+}
+
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValidity),
+	}, nil
+}
+
+// writeServerCertificates generates a certificate authority and a server certificate for localhost signed by it, and
+// writes them to the data directory.
+func writeServerCertificates(dataPath string) (*certificateAuthority, error) {
+	ca, err := newCertificateAuthority()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate certificate authority: %w", err)
+	}
+
+	der, key, err := ca.sign("localhost", x509.ExtKeyUsageServerAuth, "localhost", "127.0.0.1", "::1")
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate server certificate: %w", err)
+	}
+
+	if err := writeCertificate(filepath.Join(dataPath, rootCertificateFileName), ca.certificate.Raw); err != nil {
+		return nil, err
+	}
+
+	if err := writeCertificate(filepath.Join(dataPath, serverCertificateFileName), der); err != nil {
+		return nil, err
+	}
+
+	if err := writePrivateKey(filepath.Join(dataPath, serverKeyFileName), key); err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+func writeCertificate(path string, der []byte) error {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		return fmt.Errorf("unable to write certificate %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// writePrivateKey writes the key readable by its owner only, as Postgres and libpq refuse to use keys with wider
+// permissions.
+func writePrivateKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("unable to encode private key %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return fmt.Errorf("unable to write private key %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// writePKCS8PrivateKey writes the key as unencrypted PKCS#8 DER, the format the PostgreSQL JDBC driver reads client
+// keys in, readable by its owner only.
+func writePKCS8PrivateKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("unable to encode private key %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, der, 0600); err != nil {
+		return fmt.Errorf("unable to write private key %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// sslParameters enable SSL with the certificates written by writeServerCertificates, verifying client certificates
+// against the same certificate authority when they are required.
+func sslParameters(requireClientCertificates bool) map[string]string {
+	parameters := map[string]string{
+		"ssl":           "on",
+		"ssl_cert_file": serverCertificateFileName,
+		"ssl_key_file":  serverKeyFileName,
+	}
+
+	if requireClientCertificates {
+		parameters["ssl_ca_file"] = rootCertificateFileName
+	}
+
+	return parameters
+}
+
+// writeClientCertificateRule adds rules to the top of pg_hba.conf requiring TCP connections to use SSL and to present a
+// client certificate matching the user, or removes them again. Connections through the Unix socket, such as those of
+// pg_ctl, are matched by the rules written by initdb, which are left in place.
+func writeClientCertificateRule(dataPath string, authMethod AuthMethod, required bool) error {
+	hbaPath := filepath.Join(dataPath, "pg_hba.conf")
+
+	content, err := os.ReadFile(hbaPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", hbaPath, err)
+	}
+
+	var lines []string
+	if required {
+		lines = append(lines,
+			fmt.Sprintf("hostssl all all all %s clientcert=verify-full %s", authMethod, clientCertificateRule),
+			fmt.Sprintf("hostnossl all all all reject %s", clientCertificateRule))
+	}
+
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if !strings.HasSuffix(strings.TrimSpace(line), clientCertificateRule) {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	if err := os.WriteFile(hbaPath, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", hbaPath, err)
+	}
+
+	return nil
+}
+
+// ClientCertificate issues a client certificate for the user, signed by the certificate authority of the started
+// server, and returns the paths of the certificate and its key for use as sslcert and sslkey. It requires a server
+// started with RequireClientCertificates, and user names containing path separators are rejected.
+func (ep *EmbeddedPostgres) ClientCertificate(user string) (string, string, error) {
+	if !ep.started || ep.certificateAuthority == nil || !ep.config.requireClientCertificates {
+		return "", "", errors.New("client certificates require a server started with RequireClientCertificates")
+	}
+
+	return issueClientCertificate(ep.certificateAuthority, ep.config.runtimePath, user)
+}
+
+// issueClientCertificate writes a client certificate for the user signed by the certificate authority, and its key, to
+// the runtime directory. The key is also written in PKCS#8 DER for JDBCURL.
+func issueClientCertificate(ca *certificateAuthority, runtimePath, user string) (string, string, error) {
+	if err := validateCertificateUser(user); err != nil {
+		return "", "", err
+	}
+
+	der, key, err := ca.sign(user, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to generate client certificate for %s: %w", user, err)
+	}
+
+	dir := filepath.Join(runtimePath, clientCertificatesDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("unable to create client certificate directory %s: %w", dir, err)
+	}
+
+	certificatePath, keyPath := clientCertificatePaths(runtimePath, user)
+
+	if err := writeCertificate(certificatePath, der); err != nil {
+		return "", "", err
+	}
+
+	if err := writePrivateKey(keyPath, key); err != nil {
+		return "", "", err
+	}
+
+	if err := writePKCS8PrivateKey(jdbcClientKeyPath(runtimePath, user), key); err != nil {
+		return "", "", err
+	}
+
+	return certificatePath, keyPath, nil
+}
+
+// validateCertificateUser rejects user names which cannot be used as the file names of client certificates, as they
+// would refer to files outside of the client certificate directory.
+func validateCertificateUser(user string) error {
+	if err := validateIdentifier("user", user); err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(user, `/\`) || user == "." || user == ".." {
+		return fmt.Errorf("user name %q cannot be used for a client certificate", user)
+	}
+
+	return nil
+}
+
+func clientCertificatePaths(runtimePath, user string) (string, string) {
+	dir := filepath.Join(runtimePath, clientCertificatesDir)
+
+	return filepath.Join(dir, user+".crt"), filepath.Join(dir, user+".key")
+}
+
+func jdbcClientKeyPath(runtimePath, user string) string {
+	return filepath.Join(runtimePath, clientCertificatesDir, user+".pk8")
+}
+
+// clientSSLSettings returns the SSL settings of the connections made by the library, and published for others to use.
+// SSL is disabled, unless client certificates are required, in which case the server is verified and the certificate
+// issued to the superuser on start is presented.
+func (c Config) clientSSLSettings() url.Values {
+	settings := url.Values{}
+
+	if !c.requireClientCertificates {
+		settings.Set("sslmode", "disable")
+		return settings
+	}
+
+	certificatePath, keyPath := clientCertificatePaths(c.runtimePath, c.username)
+
+	settings.Set("sslmode", "verify-full")
+	settings.Set("sslrootcert", filepath.Join(c.dataPath, rootCertificateFileName))
+	settings.Set("sslcert", certificatePath)
+	settings.Set("sslkey", keyPath)
+
+	return settings
+}
+
+// SSLRootCertPath returns the path of the certificate authority which signed the server certificate when SSL is
+// enabled, for clients connecting with sslmode=verify-full and sslrootcert.
+func (ep *EmbeddedPostgres) SSLRootCertPath() string {
+	return filepath.Join(ep.DataPath(), rootCertificateFileName)
+}
@@ -0,0 +1,233 @@
+package embeddedpostgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SSL(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9872).
+		SSL(true))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB(fmt.Sprintf("host=localhost port=9872 user=postgres password=postgres dbname=postgres sslmode=verify-full sslrootcert=%s", database.SSLRootCertPath()))
+	require.NoError(t, err)
+
+	var ssl bool
+	require.NoError(t, db.QueryRow("SELECT ssl FROM pg_stat_ssl WHERE pid = pg_backend_pid()").Scan(&ssl))
+	assert.True(t, ssl)
+	require.NoError(t, db.Close())
+}
+
+func Test_writeServerCertificates(t *testing.T) {
+	dataPath := t.TempDir()
+
+	ca, err := writeServerCertificates(dataPath)
+	require.NoError(t, err)
+
+	rootCertificate, err := os.ReadFile(filepath.Join(dataPath, rootCertificateFileName))
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(rootCertificate))
+	assert.True(t, ca.certificate.IsCA)
+
+	serverCertificate, err := tls.LoadX509KeyPair(filepath.Join(dataPath, serverCertificateFileName), filepath.Join(dataPath, serverKeyFileName))
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(serverCertificate.Certificate[0])
+	require.NoError(t, err)
+
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		_, err = leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.NoError(t, err, host)
+	}
+
+	keyInfo, err := os.Stat(filepath.Join(dataPath, serverKeyFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), keyInfo.Mode().Perm())
+}
+
+func Test_writeServerCertificates_ErrorWhenDataPathMissing(t *testing.T) {
+	_, err := writeServerCertificates(filepath.Join(t.TempDir(), "missing"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to write certificate")
+}
+
+func Test_serverParameters_SSL(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		SSL(true).
+		StartParameters(map[string]string{"ssl_ciphers": "HIGH", "ssl_cert_file": "custom.crt"}))
+
+	expected := serverLogParameters(database.ServerLogPath())
+	expected["ssl"] = "on"
+	expected["ssl_cert_file"] = "custom.crt"
+	expected["ssl_key_file"] = serverKeyFileName
+	expected["ssl_ciphers"] = "HIGH"
+
+	assert.Equal(t, expected, database.serverParameters())
+
+	plain := NewDatabase()
+	assert.Equal(t, serverLogParameters(plain.ServerLogPath()), plain.serverParameters())
+	assert.Equal(t, filepath.Join(database.DataPath(), "root.crt"), database.SSLRootCertPath())
+}
+
+func Test_RequireClientCertificates(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(t.TempDir()).
+		Port(9869).
+		RequireClientCertificates(true))
+
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	dsn := fmt.Sprintf("host=localhost port=9869 user=postgres password=postgres dbname=postgres sslmode=verify-full sslrootcert=%s", database.SSLRootCertPath())
+
+	db, err := OpenDB(dsn)
+	require.NoError(t, err)
+	assert.Error(t, db.Ping())
+	require.NoError(t, db.Close())
+
+	db, err = OpenDB("host=localhost port=9869 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+	assert.Error(t, db.Ping())
+	require.NoError(t, db.Close())
+
+	// the library connects with the certificate issued to the superuser on start
+	connected, err := database.Connect()
+	require.NoError(t, err)
+	require.NoError(t, connected.Ping())
+	require.NoError(t, database.CreateDatabase("orders"))
+
+	certificatePath, keyPath, err := database.ClientCertificate("postgres")
+	require.NoError(t, err)
+
+	db, err = OpenDB(fmt.Sprintf("%s sslcert=%s sslkey=%s", dsn, certificatePath, keyPath))
+	require.NoError(t, err)
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.Close())
+}
+
+func Test_Config_clientSSLSettings(t *testing.T) {
+	assert.Equal(t, "sslmode=disable", DefaultConfig().clientSSLSettings().Encode())
+
+	config := DefaultConfig().
+		RuntimePath("/runtime").
+		DataPath("/data").
+		Username("gin").
+		RequireClientCertificates(true)
+
+	settings := config.clientSSLSettings()
+	assert.Equal(t, "verify-full", settings.Get("sslmode"))
+	assert.Equal(t, filepath.Join("/data", "root.crt"), settings.Get("sslrootcert"))
+	assert.Equal(t, filepath.Join("/runtime", clientCertificatesDir, "gin.crt"), settings.Get("sslcert"))
+	assert.Equal(t, filepath.Join("/runtime", clientCertificatesDir, "gin.key"), settings.Get("sslkey"))
+	assert.Contains(t, config.connectionDSN(), "?sslcert=")
+}
+
+func Test_ClientCertificate_ErrorWhenNotRequired(t *testing.T) {
+	_, _, err := NewDatabase(DefaultConfig().SSL(true)).ClientCertificate("postgres")
+
+	assert.EqualError(t, err, "client certificates require a server started with RequireClientCertificates")
+}
+
+func Test_certificateAuthority_SignsClientCertificates(t *testing.T) {
+	ca, err := newCertificateAuthority()
+	require.NoError(t, err)
+
+	der, _, err := ca.sign("app_user", x509.ExtKeyUsageClientAuth)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+
+	_, err = certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.NoError(t, err)
+	assert.Equal(t, "app_user", certificate.Subject.CommonName)
+}
+
+func Test_issueClientCertificate(t *testing.T) {
+	ca, err := newCertificateAuthority()
+	require.NoError(t, err)
+
+	runtimePath := t.TempDir()
+
+	certificatePath, keyPath, err := issueClientCertificate(ca, runtimePath, "app_user")
+	require.NoError(t, err)
+	assert.FileExists(t, certificatePath)
+	assert.FileExists(t, keyPath)
+
+	der, err := os.ReadFile(jdbcClientKeyPath(runtimePath, "app_user"))
+	require.NoError(t, err)
+
+	_, err = x509.ParsePKCS8PrivateKey(der)
+	assert.NoError(t, err)
+}
+
+func Test_issueClientCertificate_ErrorWhenUserIsNotAFileName(t *testing.T) {
+	ca, err := newCertificateAuthority()
+	require.NoError(t, err)
+
+	runtimePath := t.TempDir()
+
+	for _, user := range []string{"../escape", `..\escape`, "a/b", "..", ""} {
+		_, _, err := issueClientCertificate(ca, runtimePath, user)
+		assert.Error(t, err, user)
+	}
+
+	_, _, err = issueClientCertificate(ca, runtimePath, "../escape")
+	assert.EqualError(t, err, `user name "../escape" cannot be used for a client certificate`)
+	assert.NoFileExists(t, filepath.Join(runtimePath, "escape.crt"))
+}
+
+func Test_writeClientCertificateRule(t *testing.T) {
+	dataPath := t.TempDir()
+	hbaPath := filepath.Join(dataPath, "pg_hba.conf")
+	initdbRules := "# TYPE  DATABASE        USER            ADDRESS                 METHOD\nhost    all             all             127.0.0.1/32            password\n"
+	require.NoError(t, os.WriteFile(hbaPath, []byte(initdbRules), 0600))
+
+	require.NoError(t, writeClientCertificateRule(dataPath, AuthMethodScramSHA256, true))
+	require.NoError(t, writeClientCertificateRule(dataPath, AuthMethodScramSHA256, true))
+
+	content, err := os.ReadFile(hbaPath)
+	require.NoError(t, err)
+	assert.Equal(t, "hostssl all all all scram-sha-256 clientcert=verify-full "+clientCertificateRule+"\n"+
+		"hostnossl all all all reject "+clientCertificateRule+"\n"+initdbRules, string(content))
+
+	require.NoError(t, writeClientCertificateRule(dataPath, AuthMethodScramSHA256, false))
+
+	content, err = os.ReadFile(hbaPath)
+	require.NoError(t, err)
+	assert.Equal(t, initdbRules, string(content))
+
+	assert.NoError(t, writeClientCertificateRule(filepath.Join(dataPath, "missing"), AuthMethodPassword, true))
+}
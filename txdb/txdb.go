@@ -0,0 +1,193 @@
+// Package txdb provides a database/sql connector which runs all work inside a single transaction that is rolled back
+// when the *sql.DB is closed, so tests against the embedded server need no cleanup SQL at all.
+package txdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	embeddedpostgres "github.com/RVennu/embedded-postgres"
+)
+
+// Open returns a *sql.DB connected to the Postgres server at the DSN, whose work is rolled back when it is closed. It
+// connects using the SQLDriver registered with embedded-postgres.
+func Open(dsn string) (*sql.DB, error) {
+	connector, err := embeddedpostgres.SQLConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenConnector(connector), nil
+}
+
+// OpenConnector returns a *sql.DB using a single connection from the connector, inside a transaction which is rolled
+// back when the *sql.DB is closed. Transactions started through the *sql.DB are emulated with savepoints.
+//
+// The pool is limited to one open connection, as all work shares the same underlying transaction.
+func OpenConnector(connector driver.Connector) *sql.DB {
+	db := sql.OpenDB(&txConnector{connector: connector})
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+type txConnector struct {
+	connector driver.Connector
+	mu        sync.Mutex
+	conn      driver.Conn
+	tx        driver.Tx
+	savepoint int
+}
+
+func (c *txConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := c.connector.Connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := beginTx(ctx, conn)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("unable to begin transaction: %w", err)
+		}
+
+		c.conn = conn
+		c.tx = tx
+	}
+
+	return &txConn{connector: c}, nil
+}
+
+func (c *txConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// Close rolls back all work and closes the underlying connection, it is called when the *sql.DB is closed.
+func (c *txConnector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	rollbackErr := c.tx.Rollback()
+	closeErr := c.conn.Close()
+	c.conn, c.tx = nil, nil
+
+	if rollbackErr != nil {
+		return fmt.Errorf("unable to rollback transaction: %w", rollbackErr)
+	}
+
+	return closeErr
+}
+
+func (c *txConnector) nextSavepoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.savepoint++
+
+	return fmt.Sprintf("txdb_savepoint_%d", c.savepoint)
+}
+
+func beginTx(ctx context.Context, conn driver.Conn) (driver.Tx, error) {
+	if beginner, ok := conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, driver.TxOptions{})
+	}
+
+	//nolint:staticcheck
+	return conn.Begin()
+}
+
+// txConn shares the connection of its connector, closing it leaves the underlying connection and transaction open.
+type txConn struct {
+	connector *txConnector
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return c.connector.conn.Prepare(query)
+}
+
+func (c *txConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.connector.conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+
+	return c.Prepare(query)
+}
+
+func (c *txConn) Close() error {
+	return nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	savepoint := c.connector.nextSavepoint()
+
+	if err := c.exec(ctx, fmt.Sprintf("SAVEPOINT %s", savepoint)); err != nil {
+		return nil, err
+	}
+
+	return &savepointTx{conn: c, savepoint: savepoint}, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.connector.conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.connector.conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *txConn) exec(ctx context.Context, query string) error {
+	_, err := c.ExecContext(ctx, query, nil)
+	if !errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+
+	stmt, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	//nolint:staticcheck
+	_, err = stmt.Exec(nil)
+
+	return err
+}
+
+// savepointTx emulates a transaction within the connector's transaction.
+type savepointTx struct {
+	conn      *txConn
+	savepoint string
+}
+
+func (t *savepointTx) Commit() error {
+	return t.conn.exec(context.Background(), fmt.Sprintf("RELEASE SAVEPOINT %s", t.savepoint))
+}
+
+func (t *savepointTx) Rollback() error {
+	return t.conn.exec(context.Background(), fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", t.savepoint))
+}
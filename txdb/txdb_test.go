@@ -0,0 +1,95 @@
+package txdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConnector struct {
+	connects int
+	conn     *fakeConn
+}
+
+func (f *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	f.connects++
+	return f.conn, nil
+}
+
+func (f *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	statements []string
+	closed     bool
+}
+
+func (f *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeConn) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeConn) Begin() (driver.Tx, error) {
+	f.statements = append(f.statements, "BEGIN")
+	return f, nil
+}
+
+func (f *fakeConn) Commit() error {
+	f.statements = append(f.statements, "COMMIT")
+	return nil
+}
+
+func (f *fakeConn) Rollback() error {
+	f.statements = append(f.statements, "ROLLBACK")
+	return nil
+}
+
+func (f *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	f.statements = append(f.statements, query)
+	return driver.RowsAffected(1), nil
+}
+
+func Test_OpenConnector_RollsBackOnClose(t *testing.T) {
+	conn := &fakeConn{}
+	connector := &fakeConnector{conn: conn}
+
+	db := OpenConnector(connector)
+
+	_, err := db.Exec("INSERT INTO beers VALUES ('Punk IPA')")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	_, err = tx.Exec("INSERT INTO beers VALUES ('Elvis Juice')")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, db.Close())
+
+	assert.Equal(t, 1, connector.connects)
+	assert.True(t, conn.closed)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO beers VALUES ('Punk IPA')",
+		"SAVEPOINT txdb_savepoint_1",
+		"INSERT INTO beers VALUES ('Elvis Juice')",
+		"ROLLBACK TO SAVEPOINT txdb_savepoint_1",
+		"SAVEPOINT txdb_savepoint_2",
+		"RELEASE SAVEPOINT txdb_savepoint_2",
+		"ROLLBACK",
+	}, conn.statements)
+}
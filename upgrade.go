@@ -0,0 +1,124 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// UpgradeDataDir upgrades the data directory oldDataDir, created by Postgres fromVersion, to toVersion with pg_upgrade,
+// so that a persistent data directory survives a bump of the configured major version rather than being initialized
+// again by Start. The binaries of both versions are downloaded as for Start, and the credentials, locale, port and
+// authentication method of this configuration are used. The data directory is replaced with the upgraded one once
+// pg_upgrade succeeds, postgresql.conf and pg_hba.conf are those of a new data directory and any changes made to them
+// are not carried over. The server must not be started.
+func (ep *EmbeddedPostgres) UpgradeDataDir(oldDataDir string, fromVersion, toVersion PostgresVersion) error {
+	if ep.started {
+		return errors.New("server is already started")
+	}
+
+	if !dataDirIsValid(oldDataDir, fromVersion) {
+		return fmt.Errorf("%s does not contain a data directory of Postgres %s", oldDataDir, fromVersion)
+	}
+
+	oldBinaries, err := ep.binariesForVersion(fromVersion)
+	if err != nil {
+		return err
+	}
+
+	newBinaries, err := ep.binariesForVersion(toVersion)
+	if err != nil {
+		return err
+	}
+
+	logger, err := newSyncedLogger("", ep.config.logger)
+	if err != nil {
+		return errors.New("unable to create logger")
+	}
+
+	defer func() {
+		_ = logger.flush()
+	}()
+
+	// pg_upgrade writes its logs and sockets to the working directory, which is next to the data directory so that the
+	// upgraded data directory can be renamed into place
+	workDir, err := os.MkdirTemp(filepath.Dir(oldDataDir), "upgrade_")
+	if err != nil {
+		return fmt.Errorf("unable to create upgrade directory next to %s: %w", oldDataDir, err)
+	}
+
+	defer func() {
+		// the original data directory is left in the upgrade directory when it could not be moved back into place
+		if _, err := os.Stat(oldDataDir); err == nil {
+			_ = os.RemoveAll(workDir)
+		}
+	}()
+
+	newDataDir := filepath.Join(workDir, "data")
+
+	if err := ep.initDatabase(newBinaries, workDir, newDataDir, ep.config.initdbOptions(), logger.file); err != nil {
+		return err
+	}
+
+	upgradeProcess := exec.Command(filepath.Join(newBinaries, "bin/pg_upgrade"), upgradeArgs(oldBinaries, newBinaries, oldDataDir, newDataDir, ep.config.username, ep.config.port)...)
+	upgradeProcess.Dir = workDir
+	upgradeProcess.Env = append(os.Environ(), "PGPASSWORD="+ep.config.password)
+	upgradeProcess.Stdout = logger.file
+	upgradeProcess.Stderr = logger.file
+
+	if err := upgradeProcess.Run(); err != nil {
+		_ = logger.flush()
+		logContent, _ := readLogsOrTimeout(logger.file)
+
+		return fmt.Errorf("unable to upgrade %s using %s: %w\n%s%s", oldDataDir, upgradeProcess.String(), err, string(logContent), rootUserHint(string(logContent)))
+	}
+
+	return replaceDataDir(oldDataDir, newDataDir, filepath.Join(workDir, "old"))
+}
+
+// binariesForVersion downloads and extracts the binaries of the version to the shared binaries directory, returning
+// the directory they were extracted to.
+func (ep *EmbeddedPostgres) binariesForVersion(version PostgresVersion) (string, error) {
+	binaries := NewDatabase(ep.config.Version(version).BinariesPath(""))
+	binaries.config.binariesPath = binaries.BinariesPath()
+
+	cacheLocation, cacheExists := binaries.cacheLocator()
+	if err := binaries.downloadAndExtractBinary(cacheExists, cacheLocation); err != nil {
+		return "", err
+	}
+
+	return binaries.config.binariesPath, nil
+}
+
+func upgradeArgs(oldBinaries, newBinaries, oldDataDir, newDataDir, username string, port uint32) []string {
+	return []string{
+		"-b", filepath.Join(oldBinaries, "bin"),
+		"-B", filepath.Join(newBinaries, "bin"),
+		"-d", oldDataDir,
+		"-D", newDataDir,
+		"-U", username,
+		"-p", strconv.FormatUint(uint64(port), 10),
+		"-P", strconv.FormatUint(uint64(port), 10),
+	}
+}
+
+// replaceDataDir moves the upgraded data directory into place, keeping the old data directory aside until then so that
+// a failed rename leaves it intact.
+func replaceDataDir(dataDir, upgradedDataDir, oldDataDir string) error {
+	if err := os.Rename(dataDir, oldDataDir); err != nil {
+		return fmt.Errorf("unable to replace data directory %s: %w", dataDir, err)
+	}
+
+	if err := os.Rename(upgradedDataDir, dataDir); err != nil {
+		if restoreErr := os.Rename(oldDataDir, dataDir); restoreErr != nil {
+			return fmt.Errorf("unable to replace data directory %s: %v, the original data directory was left at %s", dataDir, err, oldDataDir)
+		}
+
+		return fmt.Errorf("unable to replace data directory %s: %w", dataDir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package embeddedpostgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UpgradeDataDir(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "data")
+
+	oldDatabase := NewDatabase(DefaultConfig().
+		Version(V14).
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		DataPath(dataDir).
+		Port(9880).
+		AfterStartSQL("CREATE TABLE beers(name text)", "INSERT INTO beers VALUES ('Punk IPA')"))
+	if err := oldDatabase.Start(); err != nil {
+		shutdownDBAndFail(t, err, oldDatabase)
+	}
+
+	require.NoError(t, oldDatabase.Stop())
+
+	config := DefaultConfig().
+		Version(V15).
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		DataPath(dataDir).
+		Port(9880)
+
+	newDatabase := NewDatabase(config)
+	require.NoError(t, newDatabase.UpgradeDataDir(dataDir, V14, V15))
+	assert.True(t, dataDirIsValid(dataDir, V15))
+
+	if err := newDatabase.Start(); err != nil {
+		shutdownDBAndFail(t, err, newDatabase)
+	}
+
+	defer func() {
+		if err := newDatabase.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	db, err := OpenDB("host=localhost port=9880 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM beers").Scan(&name))
+	assert.Equal(t, "Punk IPA", name)
+}
+
+func Test_UpgradeDataDir_ErrorWhenNotDataDirOfVersion(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "PG_VERSION"), []byte("13\n"), 0600))
+
+	err := NewDatabase().UpgradeDataDir(dataDir, V14, V15)
+
+	assert.EqualError(t, err, dataDir+" does not contain a data directory of Postgres "+string(V14))
+}
+
+func Test_UpgradeDataDir_ErrorWhenStarted(t *testing.T) {
+	database := NewDatabase()
+	database.started = true
+
+	err := database.UpgradeDataDir(t.TempDir(), V14, V15)
+
+	assert.EqualError(t, err, "server is already started")
+}
+
+func Test_upgradeArgs(t *testing.T) {
+	assert.Equal(t, []string{
+		"-b", filepath.Join("/binaries/14", "bin"),
+		"-B", filepath.Join("/binaries/15", "bin"),
+		"-d", "/data",
+		"-D", "/upgrade/data",
+		"-U", "postgres",
+		"-p", "5432",
+		"-P", "5432",
+	}, upgradeArgs("/binaries/14", "/binaries/15", "/data", "/upgrade/data", "postgres", 5432))
+}
+
+func Test_replaceDataDir(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "data")
+	upgradedDataDir := filepath.Join(tempDir, "upgraded")
+
+	require.NoError(t, os.Mkdir(dataDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "PG_VERSION"), []byte("14\n"), 0600))
+	require.NoError(t, os.Mkdir(upgradedDataDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(upgradedDataDir, "PG_VERSION"), []byte("15\n"), 0600))
+
+	require.NoError(t, replaceDataDir(dataDir, upgradedDataDir, filepath.Join(tempDir, "old")))
+
+	assert.True(t, dataDirIsValid(dataDir, V15))
+	assert.NoDirExists(t, upgradedDataDir)
+}
+
+func Test_replaceDataDir_KeepsDataDirWhenUpgradedDataDirMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "data")
+
+	require.NoError(t, os.Mkdir(dataDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "PG_VERSION"), []byte("14\n"), 0600))
+
+	err := replaceDataDir(dataDir, filepath.Join(tempDir, "upgraded"), filepath.Join(tempDir, "old"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to replace data directory "+dataDir)
+	assert.True(t, dataDirIsValid(dataDir, V14))
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // VersionStrategy provides a strategy that can be used to determine which version of Postgres should be used based on
@@ -13,6 +15,10 @@ type VersionStrategy func() (operatingSystem string, architecture string, postgr
 
 func defaultVersionStrategy(config Config, goos, arch string, linuxMachineName func() string, shouldUseAlpineLinuxBuild func() bool) VersionStrategy {
 	return func() (string, string, PostgresVersion) {
+		if config.platformOS != "" && config.platformArch != "" {
+			return config.platformOS, config.platformArch, config.version
+		}
+
 		goos := goos
 		arch := arch
 
@@ -32,7 +38,7 @@ func defaultVersionStrategy(config Config, goos, arch string, linuxMachineName f
 				}
 			}
 
-			if shouldUseAlpineLinuxBuild() {
+			if config.libc == LibcMusl || (config.libc == "" && shouldUseAlpineLinuxBuild()) {
 				arch += "-alpine"
 			}
 		}
@@ -62,7 +68,36 @@ func linuxMachineName() string {
 	return uname
 }
 
+var (
+	alpineLinuxBuildOnce sync.Once
+	alpineLinuxBuild     bool
+)
+
+// shouldUseAlpineLinuxBuild detects whether the host uses the musl C library, which the alpine builds are linked
+// against, rather than glibc. The C library of the host does not change, so it is only detected once.
 func shouldUseAlpineLinuxBuild() bool {
-	_, err := os.Stat("/etc/alpine-release")
-	return err == nil
+	alpineLinuxBuildOnce.Do(func() {
+		alpineLinuxBuild = detectMusl()
+	})
+
+	return alpineLinuxBuild
+}
+
+func detectMusl() bool {
+	if _, err := os.Stat("/etc/alpine-release"); err == nil {
+		return true
+	}
+
+	// ldd tells the C library the host programs are linked against, whereas a musl loader may be installed next to
+	// glibc, for instance by musl-tools, so the loader is only looked for when there is no ldd.
+	if _, err := exec.LookPath("ldd"); err == nil {
+		// ldd reports its version on stderr for musl, and exits with a non-zero code.
+		output, _ := exec.Command("ldd", "--version").CombinedOutput()
+
+		return strings.Contains(strings.ToLower(string(output)), "musl")
+	}
+
+	muslLoaders, err := filepath.Glob("/lib/ld-musl-*.so.1")
+
+	return err == nil && len(muslLoaders) > 0
 }
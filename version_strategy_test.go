@@ -142,8 +142,51 @@ func Test_DefaultVersionStrategy_Linux_Alpine(t *testing.T) {
 	assert.Equal(t, V15, postgresVersion)
 }
 
+func Test_DefaultVersionStrategy_Linux_LibcOverride(t *testing.T) {
+	_, muslArchitecture, _ := defaultVersionStrategy(
+		DefaultConfig().Libc(LibcMusl),
+		"linux",
+		"arm64",
+		linuxMachineName,
+		func() bool {
+			return false
+		},
+	)()
+
+	_, glibcArchitecture, _ := defaultVersionStrategy(
+		DefaultConfig().Libc(LibcGlibc),
+		"linux",
+		"amd64",
+		linuxMachineName,
+		func() bool {
+			return true
+		},
+	)()
+
+	assert.Equal(t, "arm64v8-alpine", muslArchitecture)
+	assert.Equal(t, "amd64", glibcArchitecture)
+}
+
+func Test_DefaultVersionStrategy_PlatformOverride(t *testing.T) {
+	operatingSystem, architecture, postgresVersion := defaultVersionStrategy(
+		DefaultConfig().Platform("linux", "amd64-alpine").Version(V14),
+		"darwin",
+		"arm64",
+		linuxMachineName,
+		func() bool {
+			return false
+		},
+	)()
+
+	assert.Equal(t, "linux", operatingSystem)
+	assert.Equal(t, "amd64-alpine", architecture)
+	assert.Equal(t, V14, postgresVersion)
+}
+
 func Test_DefaultVersionStrategy_shouldUseAlpineLinuxBuild(t *testing.T) {
 	assert.NotPanics(t, func() {
 		shouldUseAlpineLinuxBuild()
 	})
+
+	assert.Equal(t, detectMusl(), shouldUseAlpineLinuxBuild())
 }
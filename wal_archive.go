@@ -0,0 +1,217 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// recoverySignalFileName is the file which makes Postgres 12 and later start in targeted recovery, Postgres 11 and
+// earlier read the recovery settings from recoveryConfFileName instead.
+const (
+	recoverySignalFileName = "recovery.signal"
+	recoveryConfFileName   = "recovery.conf"
+)
+
+// WalArchivePath returns the directory WAL is archived to when configured with WalArchiving. It is placed within the
+// runtime directory, and so is emptied on every start.
+func (ep *EmbeddedPostgres) WalArchivePath() string {
+	return filepath.Join(ep.RuntimePath(), "wal-archive")
+}
+
+// archiveParameters returns the parameters which archive every completed WAL file to the archive directory.
+func archiveParameters(goos, archivePath string) map[string]string {
+	return map[string]string{
+		"archive_mode":    "on",
+		"archive_command": archiveCommand(goos, archivePath),
+	}
+}
+
+func archiveCommand(goos, archivePath string) string {
+	if goos == "windows" {
+		archivePath = strings.ReplaceAll(archivePath, `\`, `\\`)
+		return fmt.Sprintf(`if not exist "%s\\%%f" copy "%%p" "%s\\%%f"`, archivePath, archivePath)
+	}
+
+	return fmt.Sprintf(`test ! -f "%s/%%f" && cp "%%p" "%s/%%f"`, archivePath, archivePath)
+}
+
+func restoreCommand(goos, archivePath string) string {
+	if goos == "windows" {
+		archivePath = strings.ReplaceAll(archivePath, `\`, `\\`)
+		return fmt.Sprintf(`copy "%s\\%%f" "%%p"`, archivePath)
+	}
+
+	return fmt.Sprintf(`cp "%s/%%f" "%%p"`, archivePath)
+}
+
+// recoveryParameters returns the parameters which replay the archived WAL up to the target, and then promote the
+// server so that it accepts writes again.
+func recoveryParameters(goos, archivePath string, target time.Time) map[string]string {
+	return map[string]string{
+		"restore_command":        restoreCommand(goos, archivePath),
+		"recovery_target_time":   target.Format("2006-01-02 15:04:05.999999-07:00"),
+		"recovery_target_action": "promote",
+	}
+}
+
+// RecoverToTime performs a point in time recovery of the started server, which must be configured with WalArchiving.
+// The data directory is replaced with the base backup in backupDir, as written in plain format by BaseBackup, and the
+// archived WAL is replayed up to the target time. RecoverToTime returns once the recovered server accepts writes.
+// Point in time recovery requires Postgres 10 or later.
+func (ep *EmbeddedPostgres) RecoverToTime(ctx context.Context, backupDir string, target time.Time) error {
+	if !ep.started {
+		return errors.New("server has not been started")
+	}
+
+	if !ep.config.walArchiving {
+		return errors.New("WAL archiving is not enabled, configure it with WalArchiving")
+	}
+
+	if _, err := os.Stat(filepath.Join(backupDir, "PG_VERSION")); err != nil {
+		return fmt.Errorf("%s does not contain a base backup in plain format", backupDir)
+	}
+
+	serverVersion, err := ep.archiveCurrentWAL(ctx)
+	if err != nil {
+		return err
+	}
+
+	ep.stopMonitor()
+
+	if err := stopPostgres(ep); err != nil {
+		return fmt.Errorf("unable to stop postgres for recovery: %w", err)
+	}
+
+	parameters := recoveryParameters(runtime.GOOS, ep.WalArchivePath(), target)
+
+	if err := ep.restoreBaseBackup(backupDir, parameters, serverVersion); err != nil {
+		ep.started = false
+		ep.markExited(err)
+
+		return err
+	}
+
+	if err := startPostgres(ep); err != nil {
+		ep.started = false
+		ep.markExited(err)
+
+		return err
+	}
+
+	ep.startMonitor()
+
+	if err := writeOwnerPID(ep.config.dataPath); err != nil {
+		return fmt.Errorf("unable to record owner of data directory %s: %w", ep.config.dataPath, err)
+	}
+
+	if err := ep.waitForRecovery(ctx); err != nil {
+		return err
+	}
+
+	// the recovery settings are only read on start, but are removed so that a later restart cannot pick them up
+	return writeServerParameters(ep.config.dataPath, ep.serverParameters())
+}
+
+// archiveCurrentWAL switches to a new WAL file and waits until the completed one has been archived, so that every
+// change made so far can be recovered. It returns the version number of the server.
+func (ep *EmbeddedPostgres) archiveCurrentWAL(ctx context.Context) (int, error) {
+	var (
+		serverVersion int
+		walFile       string
+	)
+
+	if err := withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&serverVersion); err != nil {
+			return err
+		}
+
+		return db.QueryRowContext(ctx, "SELECT pg_walfile_name(pg_switch_wal())").Scan(&walFile)
+	}); err != nil {
+		return 0, fmt.Errorf("unable to switch WAL file: %w", err)
+	}
+
+	archivedFile := filepath.Join(ep.WalArchivePath(), walFile)
+
+	for {
+		if _, err := os.Stat(archivedFile); err == nil {
+			return serverVersion, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("WAL file %s has not been archived: %w", walFile, ctx.Err())
+		case <-time.After(replicationPollInterval):
+		}
+	}
+}
+
+// restoreBaseBackup replaces the data directory with the base backup, configured to recover using the parameters.
+func (ep *EmbeddedPostgres) restoreBaseBackup(backupDir string, parameters map[string]string, serverVersion int) error {
+	if err := os.RemoveAll(ep.config.dataPath); err != nil {
+		return fmt.Errorf("unable to clean up data directory %s with error: %s", ep.config.dataPath, err)
+	}
+
+	if err := copyDirectory(backupDir, ep.config.dataPath); err != nil {
+		return fmt.Errorf("unable to restore base backup %s: %w", backupDir, err)
+	}
+
+	// a backup written for a standby would otherwise keep following the archive rather than being promoted
+	_ = os.Remove(filepath.Join(ep.config.dataPath, "standby.signal"))
+
+	if serverVersion < 120000 {
+		content := formatParameters(parameters)
+		if err := os.WriteFile(filepath.Join(ep.config.dataPath, recoveryConfFileName), []byte(content), 0600); err != nil {
+			return fmt.Errorf("unable to write recovery settings to %s: %w", ep.config.dataPath, err)
+		}
+
+		return writeServerParameters(ep.config.dataPath, ep.serverParameters())
+	}
+
+	merged := ep.serverParameters()
+	for name, value := range parameters {
+		merged[name] = value
+	}
+
+	if err := writeServerParameters(ep.config.dataPath, merged); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(ep.config.dataPath, recoverySignalFileName), nil, 0600); err != nil {
+		return fmt.Errorf("unable to write recovery settings to %s: %w", ep.config.dataPath, err)
+	}
+
+	return nil
+}
+
+// waitForRecovery waits until the server has replayed the archived WAL up to the recovery target and been promoted.
+func (ep *EmbeddedPostgres) waitForRecovery(ctx context.Context) error {
+	if err := healthCheckDatabaseOrTimeout(ep.config); err != nil {
+		return err
+	}
+
+	return withDatabaseConnection(ep.config, ep.config.database, func(db *sql.DB) error {
+		for {
+			var inRecovery bool
+			if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+				return fmt.Errorf("unable to read recovery state: %w", err)
+			}
+
+			if !inRecovery {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("server has not finished recovery: %w", ctx.Err())
+			case <-time.After(replicationPollInterval):
+			}
+		}
+	})
+}
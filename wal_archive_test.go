@@ -0,0 +1,120 @@
+package embeddedpostgres
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RecoverToTime(t *testing.T) {
+	tempDir := t.TempDir()
+
+	database := NewDatabase(DefaultConfig().
+		RuntimePath(filepath.Join(tempDir, "runtime")).
+		Port(9879).
+		WalArchiving(true))
+	if err := database.Start(); err != nil {
+		shutdownDBAndFail(t, err, database)
+	}
+
+	defer func() {
+		if err := database.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	db, err := OpenDB("host=localhost port=9879 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	_, err = db.Exec("CREATE TABLE beers(name text)")
+	require.NoError(t, err)
+
+	backupDir := filepath.Join(tempDir, "backup")
+	require.NoError(t, database.BaseBackup(ctx, backupDir, BaseBackupOptions{}))
+
+	_, err = db.Exec("INSERT INTO beers VALUES ('Punk IPA')")
+	require.NoError(t, err)
+
+	var target time.Time
+	require.NoError(t, db.QueryRow("SELECT clock_timestamp()").Scan(&target))
+
+	_, err = db.Exec("INSERT INTO beers VALUES ('Dead Pony Club')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, database.RecoverToTime(ctx, backupDir, target))
+
+	db, err = OpenDB("host=localhost port=9879 user=postgres password=postgres dbname=postgres sslmode=disable")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var beers []string
+	rows, err := db.Query("SELECT name FROM beers")
+	require.NoError(t, err)
+
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		beers = append(beers, name)
+	}
+
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"Punk IPA"}, beers)
+
+	_, err = db.Exec("INSERT INTO beers VALUES ('Elvis Juice')")
+	assert.NoError(t, err)
+}
+
+func Test_RecoverToTime_ErrorWhenNotStarted(t *testing.T) {
+	database := NewDatabase(DefaultConfig().WalArchiving(true))
+
+	err := database.RecoverToTime(context.Background(), t.TempDir(), time.Now())
+
+	assert.EqualError(t, err, "server has not been started")
+}
+
+func Test_WalArchiving_ServerParameters(t *testing.T) {
+	database := NewDatabase(DefaultConfig().
+		RuntimePath("/tmp/runtime").
+		WalArchiving(true))
+
+	parameters := database.serverParameters()
+
+	assert.Equal(t, filepath.Join("/tmp/runtime", "wal-archive"), database.WalArchivePath())
+	assert.Equal(t, "on", parameters["archive_mode"])
+	assert.Equal(t, archiveCommand(runtime.GOOS, database.WalArchivePath()), parameters["archive_command"])
+}
+
+func Test_archiveCommand(t *testing.T) {
+	assert.Equal(t,
+		`test ! -f "/tmp/archive/%f" && cp "%p" "/tmp/archive/%f"`,
+		archiveCommand("linux", "/tmp/archive"))
+	assert.Equal(t,
+		`if not exist "C:\\archive\\%f" copy "%p" "C:\\archive\\%f"`,
+		archiveCommand("windows", `C:\archive`))
+}
+
+func Test_recoveryParameters(t *testing.T) {
+	target := time.Date(2023, 3, 14, 15, 9, 26, 535000000, time.UTC)
+
+	assert.Equal(t, map[string]string{
+		"restore_command":        `cp "/tmp/archive/%f" "%p"`,
+		"recovery_target_time":   "2023-03-14 15:09:26.535+00:00",
+		"recovery_target_action": "promote",
+	}, recoveryParameters("linux", "/tmp/archive", target))
+
+	assert.Equal(t,
+		`copy "C:\\archive\\%f" "%p"`,
+		recoveryParameters("windows", `C:\archive`, target)["restore_command"])
+}
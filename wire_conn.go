@@ -0,0 +1,774 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cancelRequestCode and sslRequestCode take the place of the protocol version in the special startup messages which
+// cancel a running query and request TLS.
+const (
+	cancelRequestCode = 80877102
+	sslRequestCode    = 80877103
+)
+
+// cancelGrace bounds how long a query is waited on after its cancellation was requested, before the connection is
+// given up on.
+const cancelGrace = 5 * time.Second
+
+// Type OIDs of the results decoded into Go values rather than strings.
+const (
+	boolOID        = 16
+	byteaOID       = 17
+	int8OID        = 20
+	int2OID        = 21
+	int4OID        = 23
+	oidOID         = 26
+	float4OID      = 700
+	float8OID      = 701
+	dateOID        = 1082
+	timestampOID   = 1114
+	timestamptzOID = 1184
+)
+
+// errMalformedMessage is returned for a message of the server which is shorter than its fields require, after which
+// the connection is not used again.
+var errMalformedMessage = errors.New("malformed message from the server")
+
+// wireDriver is the driver of the connections the library runs its own statements on, such as creating databases and
+// roles. It is neither registered with database/sql nor exported, as it supports only what those statements need: the
+// simple query protocol, with arguments interpolated as literals, without transactions, prepared statements or COPY.
+// The connections handed out to applications use the registered SQLDriver instead.
+type wireDriver struct{}
+
+// Open opens a connection for the DSN.
+func (d wireDriver) Open(dsn string) (driver.Conn, error) {
+	connector, err := newWireConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(context.Background())
+}
+
+// newWireConnector parses the DSN once for all the connections of a *sql.DB.
+func newWireConnector(dsn string) (*wireConnector, error) {
+	config, err := parseWireDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wireConnector{config: config}, nil
+}
+
+type wireConnector struct {
+	config *wireConfig
+}
+
+// Driver returns the driver of the connector.
+func (c *wireConnector) Driver() driver.Driver {
+	return wireDriver{}
+}
+
+// Connect dials the server, negotiates TLS as the sslmode requires and logs in.
+func (c *wireConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &wireSession{reader: bufio.NewReader(conn), writer: conn, username: c.config.user, password: c.config.password}
+
+	parameters := append([]string{"client_encoding", "UTF8", "datestyle", "ISO, MDY"}, c.config.parameters...)
+	if err := session.login(c.config.database, parameters...); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &wireConn{conn: conn, session: session, config: c.config}, nil
+}
+
+// dial connects to the server and negotiates TLS as the sslmode requires. The connection has the deadline of the context,
+// or else the connect timeout, which is to be cleared once logged in.
+func (c *wireConnector) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.config.connectTimeout}
+
+	conn, err := dialer.DialContext(ctx, c.config.network(), c.config.address())
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else if c.config.connectTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(c.config.connectTimeout))
+	}
+
+	conn, err = c.negotiateTLS(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// negotiateTLS sends an SSLRequest unless the sslmode is disable or allow, and wraps the connection in TLS if the server
+// accepts it. Only prefer falls back to an unencrypted connection when the server does not.
+func (c *wireConnector) negotiateTLS(conn net.Conn) (net.Conn, error) {
+	if c.config.sslMode == "disable" || c.config.sslMode == "allow" || c.config.network() == "unix" {
+		return conn, nil
+	}
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request, 8)
+	binary.BigEndian.PutUint32(request[4:], sslRequestCode)
+
+	if _, err := conn.Write(request); err != nil {
+		return conn, err
+	}
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return conn, err
+	}
+
+	if response[0] != 'S' {
+		if c.config.sslMode == "prefer" {
+			return conn, nil
+		}
+
+		return conn, errors.New("the server does not support SSL connections")
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return conn, err
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return conn, err
+	}
+
+	return tlsConn, nil
+}
+
+// tlsConfig verifies the server as the sslmode requires: verify-full checks the chain and host name, verify-ca, or
+// require with a root certificate, only the chain, and require and prefer otherwise nothing.
+func (c *wireConnector) tlsConfig() (*tls.Config, error) {
+	//nolint:gosec
+	tlsConfig := &tls.Config{ServerName: c.config.host}
+
+	if c.config.sslCert != "" || c.config.sslKey != "" {
+		certificate, err := tls.LoadX509KeyPair(c.config.sslCert, c.config.sslKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if c.config.sslRootCert != "" {
+		pem, err := os.ReadFile(c.config.sslRootCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read root certificate: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", c.config.sslRootCert)
+		}
+	}
+
+	switch {
+	case c.config.sslMode == "verify-full":
+		return tlsConfig, nil
+	case c.config.sslMode == "verify-ca" || (c.config.sslMode == "require" && tlsConfig.RootCAs != nil):
+		roots := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		}
+
+		return tlsConfig, nil
+	default:
+		tlsConfig.InsecureSkipVerify = true
+		return tlsConfig, nil
+	}
+}
+
+// verifyChain checks that the certificates presented by the server chain to the roots, or to the system roots when nil,
+// whatever host name they were issued for.
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("the server presented no certificate")
+	}
+
+	certificates := make([]*x509.Certificate, len(rawCerts))
+
+	for i, raw := range rawCerts {
+		certificate, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+
+		certificates[i] = certificate
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	_, err := certificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+
+	return err
+}
+
+// wireConn is a connection of wireDriver. Every statement is sent as a simple Query message, so that scripts of several
+// statements can be run, and the rows of the first result set are read as they are iterated.
+type wireConn struct {
+	conn    net.Conn
+	session *wireSession
+	config  *wireConfig
+	// bad is set when the connection is unusable after an I/O or protocol error, so that database/sql discards it
+	bad bool
+}
+
+var (
+	_ driver.ExecerContext   = (*wireConn)(nil)
+	_ driver.QueryerContext  = (*wireConn)(nil)
+	_ driver.Pinger          = (*wireConn)(nil)
+	_ driver.Validator       = (*wireConn)(nil)
+	_ driver.SessionResetter = (*wireConn)(nil)
+)
+
+// Prepare is not supported, statements are run through ExecContext and QueryContext instead.
+func (c *wireConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported by the internal connection")
+}
+
+// Begin is not supported, as none of the statements of the library need a transaction.
+func (c *wireConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported by the internal connection")
+}
+
+// Close terminates the session.
+func (c *wireConn) Close() error {
+	if !c.bad {
+		_ = c.session.send('X', nil)
+	}
+
+	return c.conn.Close()
+}
+
+// ExecContext runs the statements and returns the number of rows affected by the last one.
+func (c *wireConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+
+	query, err := interpolate(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := c.watchCancel(ctx)
+
+	affected, err := c.exec(query)
+	if err = stop(err); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+// QueryContext runs the statements and returns the rows of the first result set, which are read from the connection
+// as they are iterated.
+func (c *wireConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+
+	query, err := interpolate(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := c.watchCancel(ctx)
+
+	rows, err := c.query(query)
+	if err != nil {
+		return nil, stop(err)
+	}
+
+	rows.stop = stop
+
+	return rows, nil
+}
+
+// Ping sends an empty query.
+func (c *wireConn) Ping(ctx context.Context) error {
+	_, err := c.ExecContext(ctx, "", nil)
+
+	return err
+}
+
+// IsValid reports whether the connection can be reused.
+func (c *wireConn) IsValid() bool {
+	return !c.bad
+}
+
+// ResetSession discards the connection once it is unusable.
+func (c *wireConn) ResetSession(context.Context) error {
+	if c.bad {
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+// receive reads the next message of the server, marking the connection bad when it cannot.
+func (c *wireConn) receive() (byte, []byte, error) {
+	messageType, payload, err := c.session.receive()
+	if err != nil {
+		c.bad = true
+	}
+
+	return messageType, payload, err
+}
+
+// exec sends the query and reads its results until the server is ready for the next query, returning the rows affected
+// by the last statement. An error reported by the server is returned once it is ready.
+func (c *wireConn) exec(query string) (int64, error) {
+	if err := c.session.send('Q', append([]byte(query), 0)); err != nil {
+		c.bad = true
+		return 0, err
+	}
+
+	var affected int64
+
+	var queryErr error
+
+	for {
+		messageType, payload, err := c.receive()
+		if err != nil {
+			return 0, err
+		}
+
+		switch messageType {
+		case 'C':
+			affected = rowsAffected(string(bytes.TrimRight(payload, "\x00")))
+		case 'E':
+			if queryErr == nil {
+				queryErr = parseErrorResponse(payload)
+			}
+		case 'G':
+			c.refuseCopyIn()
+		case 'Z':
+			return affected, queryErr
+		}
+
+		// other messages, such as rows, notices and COPY TO STDOUT data, are of no interest
+	}
+}
+
+// query sends the query and reads its results up to the description of the first result set, or until the server is
+// ready for the next query when there is none.
+func (c *wireConn) query(query string) (*wireRows, error) {
+	if err := c.session.send('Q', append([]byte(query), 0)); err != nil {
+		c.bad = true
+		return nil, err
+	}
+
+	var queryErr error
+
+	for {
+		messageType, payload, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch messageType {
+		case 'T':
+			columns, types, err := parseRowDescription(payload)
+			if err != nil {
+				c.bad = true
+				return nil, err
+			}
+
+			return &wireRows{conn: c, columns: columns, types: types}, nil
+		case 'E':
+			if queryErr == nil {
+				queryErr = parseErrorResponse(payload)
+			}
+		case 'G':
+			c.refuseCopyIn()
+		case 'Z':
+			if queryErr != nil {
+				return nil, queryErr
+			}
+
+			return &wireRows{conn: c, done: true}, nil
+		}
+	}
+}
+
+// refuseCopyIn aborts a COPY FROM STDIN, which is not supported, so that the server reports an error instead of
+// waiting for data.
+func (c *wireConn) refuseCopyIn() {
+	_ = c.session.send('f', []byte("COPY FROM STDIN is not supported by the internal connection\x00"))
+}
+
+// watchCancel sends a cancel request for the running query once the context is done, and returns a function to call
+// when the query returned, which stops watching and replaces the error of a cancelled query with the context error.
+func (c *wireConn) watchCancel(ctx context.Context) func(error) error {
+	if ctx.Done() == nil {
+		return func(err error) error {
+			return err
+		}
+	}
+
+	finished := make(chan struct{})
+	watcherDone := make(chan struct{})
+
+	go func() {
+		defer close(watcherDone)
+
+		select {
+		case <-ctx.Done():
+			_ = c.cancel()
+			_ = c.conn.SetDeadline(time.Now().Add(cancelGrace))
+		case <-finished:
+		}
+	}()
+
+	return func(err error) error {
+		close(finished)
+		<-watcherDone
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			_ = c.conn.SetDeadline(time.Time{})
+
+			if err != nil {
+				return ctxErr
+			}
+		}
+
+		return err
+	}
+}
+
+// cancel asks the server to cancel the running query of the session, on a connection of its own.
+func (c *wireConn) cancel() error {
+	conn, err := net.DialTimeout(c.config.network(), c.config.address(), cancelGrace)
+	if err != nil {
+		return err
+	}
+
+	request := make([]byte, 16)
+	binary.BigEndian.PutUint32(request, 16)
+	binary.BigEndian.PutUint32(request[4:], cancelRequestCode)
+	binary.BigEndian.PutUint32(request[8:], c.session.backendPID)
+	binary.BigEndian.PutUint32(request[12:], c.session.backendKey)
+
+	_, err = conn.Write(request)
+
+	return connectionClose(conn, err)
+}
+
+// interpolate replaces the $1, $2, ... placeholders of the query with the arguments as literals, as the simple query
+// protocol takes no parameters. The query is scanned once, so that placeholders within the arguments are left as they
+// are, and those within string literals and quoted identifiers are skipped.
+func interpolate(query string, args []driver.NamedValue) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	for _, arg := range args {
+		if arg.Name != "" {
+			return "", fmt.Errorf("named parameter %s is not supported, use $%d", arg.Name, arg.Ordinal)
+		}
+	}
+
+	interpolated := &strings.Builder{}
+
+	var quote byte
+
+	for i := 0; i < len(query); i++ {
+		char := query[i]
+
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+
+			number, err := strconv.Atoi(query[i+1 : end])
+			if err != nil || number < 1 || number > len(args) {
+				return "", fmt.Errorf("no argument for %s", query[i:end])
+			}
+
+			literal, err := encodeLiteral(args[number-1].Value)
+			if err != nil {
+				return "", err
+			}
+
+			interpolated.WriteString(literal)
+			i = end - 1
+
+			continue
+		}
+
+		interpolated.WriteByte(char)
+	}
+
+	return interpolated.String(), nil
+}
+
+// encodeLiteral encodes an argument, of one of the types database/sql converts arguments to, as a literal.
+func encodeLiteral(value driver.Value) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quoteLiteral(v), nil
+	case []byte:
+		return quoteLiteral(`\x`+hex.EncodeToString(v)) + "::bytea", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return quoteLiteral(strconv.FormatFloat(v, 'g', -1, 64)) + "::float8", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return quoteLiteral(v.Format("2006-01-02 15:04:05.999999999Z07:00")) + "::timestamptz", nil
+	default:
+		return "", fmt.Errorf("unsupported argument type %T", value)
+	}
+}
+
+// parseRowDescription returns the names and type OIDs of the columns of a RowDescription message.
+func parseRowDescription(payload []byte) ([]string, []uint32, error) {
+	if len(payload) < 2 {
+		return nil, nil, errMalformedMessage
+	}
+
+	count := int(binary.BigEndian.Uint16(payload))
+	payload = payload[2:]
+
+	columns := make([]string, count)
+	types := make([]uint32, count)
+
+	for i := 0; i < count; i++ {
+		end := bytes.IndexByte(payload, 0)
+
+		// the table OID and column number precede the type OID, and its size, modifier and format follow it
+		if end < 0 || len(payload) < end+1+18 {
+			return nil, nil, errMalformedMessage
+		}
+
+		columns[i] = string(payload[:end])
+		payload = payload[end+1:]
+
+		types[i] = binary.BigEndian.Uint32(payload[6:])
+		payload = payload[18:]
+	}
+
+	return columns, types, nil
+}
+
+// parseDataRow decodes the text format values of a DataRow message into dest.
+func parseDataRow(payload []byte, types []uint32, dest []driver.Value) error {
+	if len(payload) < 2 {
+		return errMalformedMessage
+	}
+
+	count := int(binary.BigEndian.Uint16(payload))
+	payload = payload[2:]
+
+	if count != len(types) || count > len(dest) {
+		return errMalformedMessage
+	}
+
+	for i := 0; i < count; i++ {
+		if len(payload) < 4 {
+			return errMalformedMessage
+		}
+
+		length := int32(binary.BigEndian.Uint32(payload))
+		payload = payload[4:]
+
+		if length < 0 {
+			dest[i] = nil
+			continue
+		}
+
+		if int(length) > len(payload) {
+			return errMalformedMessage
+		}
+
+		dest[i] = decodeValue(types[i], string(payload[:length]))
+		payload = payload[length:]
+	}
+
+	return nil
+}
+
+// decodeValue decodes a value in text format into the Go type of its Postgres type, falling back to the text itself
+// for other types, or for values such as infinity which have no Go equivalent.
+func decodeValue(typeOID uint32, text string) driver.Value {
+	switch typeOID {
+	case boolOID:
+		return text == "t"
+	case int2OID, int4OID, int8OID, oidOID:
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return value
+		}
+	case float4OID, float8OID:
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	case byteaOID:
+		if value, err := hex.DecodeString(strings.TrimPrefix(text, `\x`)); err == nil {
+			return value
+		}
+	case dateOID:
+		if value, err := time.Parse("2006-01-02", text); err == nil {
+			return value
+		}
+	case timestampOID:
+		if value, err := time.Parse("2006-01-02 15:04:05.999999999", text); err == nil {
+			return value
+		}
+	case timestamptzOID:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07:00:00"} {
+			if value, err := time.Parse(layout, text); err == nil {
+				return value
+			}
+		}
+	}
+
+	return text
+}
+
+// rowsAffected returns the row count of a command tag such as "INSERT 0 5" or "UPDATE 3", or 0 for commands without.
+func rowsAffected(tag string) int64 {
+	fields := strings.Fields(tag)
+	if len(fields) < 2 {
+		return 0
+	}
+
+	count, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+// wireRows reads the rows of the first result set of a query from the connection as they are iterated. Any further
+// result sets are skipped.
+type wireRows struct {
+	conn    *wireConn
+	columns []string
+	types   []uint32
+	// stop stops watching the context of the query once the rows are closed
+	stop func(error) error
+	// finished is set once the first result set is complete, and done once the server is ready for the next query
+	finished bool
+	done     bool
+	err      error
+}
+
+func (r *wireRows) Columns() []string {
+	return r.columns
+}
+
+// Close reads the remaining results, so that the connection is ready for the next query.
+func (r *wireRows) Close() error {
+	if !r.done {
+		// without a destination the rows are skipped until the server is ready for the next query
+		_ = r.Next(nil)
+	}
+
+	if r.stop != nil {
+		err := r.stop(nil)
+		r.stop = nil
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *wireRows) Next(dest []driver.Value) error {
+	for !r.done {
+		messageType, payload, err := r.conn.receive()
+		if err != nil {
+			r.done = true
+			return err
+		}
+
+		switch messageType {
+		case 'D':
+			if r.finished || dest == nil {
+				continue
+			}
+
+			if err := parseDataRow(payload, r.types, dest); err != nil {
+				r.conn.bad = true
+				r.done = true
+
+				return err
+			}
+
+			return nil
+		case 'C', 'T':
+			r.finished = true
+		case 'E':
+			r.finished = true
+
+			if r.err == nil {
+				r.err = parseErrorResponse(payload)
+			}
+		case 'G':
+			r.conn.refuseCopyIn()
+		case 'Z':
+			r.done = true
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	return io.EOF
+}
@@ -0,0 +1,412 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openWireDB opens a database using the internal connection.
+func openWireDB(t *testing.T, dsn string) *sql.DB {
+	connector, err := newWireConnector(dsn)
+	require.NoError(t, err)
+
+	return sql.OpenDB(connector)
+}
+
+// fakeDB opens a database of a single internal connection to a fake server, which logs the client in and hands the
+// session to serve. The database is closed and serve waited for when the test ends.
+func fakeDB(t *testing.T, serve func(session *wireSession)) *sql.DB {
+	done := make(chan struct{})
+
+	port := fakeServer(t, func(t *testing.T, session *wireSession, _ []byte) {
+		defer close(done)
+
+		sendReady(session)
+		serve(session)
+	})
+
+	db := openWireDB(t, fmt.Sprintf("host=localhost port=%d user=gin password=wine dbname=beer sslmode=disable", port))
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		<-done
+	})
+
+	return db
+}
+
+// receiveQuery reads the messages of a query up to its Query or Sync message, and returns their types and payloads.
+func receiveQuery(session *wireSession) (string, []string) {
+	types := &strings.Builder{}
+
+	var payloads []string
+
+	for {
+		messageType, payload, err := session.receive()
+		if err != nil {
+			return types.String(), payloads
+		}
+
+		types.WriteByte(messageType)
+		payloads = append(payloads, string(payload))
+
+		if messageType == 'Q' || messageType == 'S' {
+			return types.String(), payloads
+		}
+	}
+}
+
+// rowDescription describes columns given as name and type OID pairs.
+func rowDescription(columns ...interface{}) []byte {
+	payload := &bytes.Buffer{}
+	_ = binary.Write(payload, binary.BigEndian, int16(len(columns)/2))
+
+	for i := 0; i < len(columns); i += 2 {
+		payload.WriteString(columns[i].(string))
+		payload.WriteByte(0)
+		// the table OID and column number precede the type OID, and its size, modifier and format follow it
+		payload.Write(make([]byte, 6))
+		_ = binary.Write(payload, binary.BigEndian, int32(columns[i+1].(int)))
+		payload.Write(make([]byte, 8))
+	}
+
+	return payload.Bytes()
+}
+
+// dataRow encodes text values, with nil pointers as NULL.
+func dataRow(values ...*string) []byte {
+	payload := &bytes.Buffer{}
+	_ = binary.Write(payload, binary.BigEndian, int16(len(values)))
+
+	for _, value := range values {
+		if value == nil {
+			_ = binary.Write(payload, binary.BigEndian, int32(-1))
+			continue
+		}
+
+		_ = binary.Write(payload, binary.BigEndian, int32(len(*value)))
+		payload.WriteString(*value)
+	}
+
+	return payload.Bytes()
+}
+
+func text(value string) *string {
+	return &value
+}
+
+func Test_wireConn_QueryWithArguments(t *testing.T) {
+	received := make(chan []string, 1)
+
+	db := fakeDB(t, func(session *wireSession) {
+		types, payloads := receiveQuery(session)
+		received <- append([]string{types}, payloads...)
+
+		_ = session.send('T', rowDescription("name", 25, "abv", 701, "brewed", 16, "label", 17, "score", 23, "missing", 25))
+		_ = session.send('D', dataRow(text("stout"), text("4.5"), text("t"), text(`\xcafe`), text("42"), nil))
+		_ = session.send('C', []byte("SELECT 1\x00"))
+		_ = session.send('Z', []byte("I"))
+	})
+
+	var (
+		name    string
+		abv     float64
+		brewed  bool
+		label   []byte
+		score   int
+		missing sql.NullString
+	)
+
+	err := db.QueryRow("SELECT $1, $2, '$1'", "it's", []byte{0xca, 0xfe}).Scan(&name, &abv, &brewed, &label, &score, &missing)
+	require.NoError(t, err)
+
+	assert.Equal(t, "stout", name)
+	assert.Equal(t, 4.5, abv)
+	assert.True(t, brewed)
+	assert.Equal(t, []byte{0xca, 0xfe}, label)
+	assert.Equal(t, 42, score)
+	assert.False(t, missing.Valid)
+
+	messages := <-received
+	assert.Equal(t, "Q", messages[0])
+	assert.Equal(t, "SELECT 'it''s', E'\\\\xcafe'::bytea, '$1'\x00", messages[1])
+}
+
+func Test_wireConn_StreamsRowsOfFirstResultSet(t *testing.T) {
+	db := fakeDB(t, func(session *wireSession) {
+		receiveQuery(session)
+		_ = session.send('T', rowDescription("n", 23))
+		_ = session.send('D', dataRow(text("1")))
+		_ = session.send('D', dataRow(text("2")))
+		_ = session.send('D', dataRow(text("3")))
+		_ = session.send('C', []byte("SELECT 3\x00"))
+		_ = session.send('T', rowDescription("other", 25))
+		_ = session.send('D', dataRow(text("skipped")))
+		_ = session.send('C', []byte("SELECT 1\x00"))
+		_ = session.send('Z', []byte("I"))
+
+		receiveQuery(session)
+		_ = session.send('T', rowDescription("n", 23))
+		_ = session.send('D', dataRow(text("1")))
+		_ = session.send('D', dataRow(text("2")))
+		_ = session.send('C', []byte("SELECT 2\x00"))
+		_ = session.send('Z', []byte("I"))
+
+		receiveQuery(session)
+		_ = session.send('I', nil)
+		_ = session.send('Z', []byte("I"))
+	})
+
+	rows, err := db.Query("SELECT generate_series(1, 3); SELECT 'skipped'")
+	require.NoError(t, err)
+
+	var values []int
+
+	for rows.Next() {
+		var value int
+		require.NoError(t, rows.Scan(&value))
+
+		values = append(values, value)
+	}
+
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int{1, 2, 3}, values)
+
+	// closing the rows early reads the rest, so that the connection is ready for the next query
+	rows, err = db.Query("SELECT generate_series(1, 2)")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	assert.NoError(t, db.Ping())
+}
+
+func Test_wireConn_ExecAndErrors(t *testing.T) {
+	db := fakeDB(t, func(session *wireSession) {
+		receiveQuery(session)
+		_ = session.send('C', []byte("INSERT 0 3\x00"))
+		_ = session.send('Z', []byte("I"))
+
+		receiveQuery(session)
+		_ = session.send('E', []byte("SERROR\x00C42P01\x00Mrelation \"missing\" does not exist\x00\x00"))
+		_ = session.send('Z', []byte("I"))
+
+		receiveQuery(session)
+		_ = session.send('I', nil)
+		_ = session.send('Z', []byte("I"))
+	})
+
+	result, err := db.Exec("INSERT INTO beers SELECT generate_series(1, 3)")
+	require.NoError(t, err)
+
+	rows, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rows)
+
+	_, err = result.LastInsertId()
+	assert.Error(t, err)
+
+	_, err = db.Exec("SELECT * FROM missing")
+	assert.EqualError(t, err, `ERROR: relation "missing" does not exist (SQLSTATE 42P01)`)
+
+	// the connection remains usable after an error
+	assert.NoError(t, db.Ping())
+}
+
+func Test_wireConn_TransactionsAndPreparedStatementsNotSupported(t *testing.T) {
+	db := fakeDB(t, func(session *wireSession) {})
+
+	_, err := db.Begin()
+	assert.EqualError(t, err, "transactions are not supported by the internal connection")
+
+	_, err = db.Prepare("SELECT 1")
+	assert.EqualError(t, err, "prepared statements are not supported by the internal connection")
+}
+
+func Test_wireConn_RefusesCopyIn(t *testing.T) {
+	refusal := make(chan string, 1)
+
+	db := fakeDB(t, func(session *wireSession) {
+		receiveQuery(session)
+		_ = session.send('G', []byte{0, 0, 0})
+
+		messageType, payload, _ := session.receive()
+		refusal <- string(messageType) + string(payload)
+
+		_ = session.send('E', []byte("SERROR\x00C57014\x00MCOPY from stdin failed\x00\x00"))
+		_ = session.send('Z', []byte("I"))
+	})
+
+	_, err := db.Exec("COPY beers FROM STDIN")
+	assert.EqualError(t, err, "ERROR: COPY from stdin failed (SQLSTATE 57014)")
+
+	assert.Equal(t, "fCOPY FROM STDIN is not supported by the internal connection\x00", <-refusal)
+}
+
+func Test_wireConn_ErrorWhenMalformedDataRow(t *testing.T) {
+	db := fakeDB(t, func(session *wireSession) {
+		receiveQuery(session)
+		_ = session.send('T', rowDescription("name", 25))
+		// a value longer than the message
+		_ = session.send('D', []byte{0, 1, 0, 0, 0, 9, 'a'})
+		_ = session.send('Z', []byte("I"))
+	})
+
+	rows, err := db.Query("SELECT name FROM beers")
+	require.NoError(t, err)
+
+	assert.False(t, rows.Next())
+	assert.ErrorIs(t, rows.Err(), errMalformedMessage)
+}
+
+func Test_parseRowDescription_ErrorWhenMalformed(t *testing.T) {
+	_, _, err := parseRowDescription(nil)
+	assert.ErrorIs(t, err, errMalformedMessage)
+
+	description := rowDescription("name", 25)
+
+	_, _, err = parseRowDescription(description[:len(description)-1])
+	assert.ErrorIs(t, err, errMalformedMessage)
+
+	columns, types, err := parseRowDescription(description)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name"}, columns)
+	assert.Equal(t, []uint32{25}, types)
+}
+
+func Test_interpolate(t *testing.T) {
+	args := func(values ...interface{}) []driver.NamedValue {
+		named := make([]driver.NamedValue, len(values))
+		for i, value := range values {
+			named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+		}
+
+		return named
+	}
+
+	query, err := interpolate(`SELECT $1, $2, $3, $4, $5, "$1", '$2', $6`,
+		args(nil, int64(-3), 1.5, true, time.Date(2023, 5, 17, 12, 30, 1, 0, time.UTC), "$2"))
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT NULL, -3, '1.5'::float8, true, '2023-05-17 12:30:01Z'::timestamptz, "$1", '$2', '$2'`, query)
+
+	query, err = interpolate("SELECT 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", query)
+
+	_, err = interpolate("SELECT $2", args("one"))
+	assert.EqualError(t, err, "no argument for $2")
+
+	_, err = interpolate("SELECT @name", []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "one"}})
+	assert.EqualError(t, err, "named parameter name is not supported, use $1")
+
+	_, err = interpolate("SELECT $1", args(struct{}{}))
+	assert.EqualError(t, err, "unsupported argument type struct {}")
+}
+
+func Test_wireConn_CancelsQueryWhenContextDone(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	cancelRequest := make(chan []byte, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		session := &wireSession{reader: bufio.NewReader(conn), writer: conn}
+
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(session.reader, header); err != nil {
+			return
+		}
+
+		if _, err := io.ReadFull(session.reader, make([]byte, binary.BigEndian.Uint32(header)-4)); err != nil {
+			return
+		}
+
+		_ = session.send('R', authentication(authenticationOk))
+		_ = session.send('K', []byte{0, 0, 0, 42, 0, 0, 0, 7})
+		_ = session.send('Z', []byte("I"))
+
+		receiveQuery(session)
+
+		cancelConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		request := make([]byte, 16)
+		_, _ = io.ReadFull(cancelConn, request)
+		_ = cancelConn.Close()
+		cancelRequest <- request
+
+		_ = session.send('E', []byte("SERROR\x00C57014\x00Mcanceling statement due to user request\x00\x00"))
+		_ = session.send('Z', []byte("I"))
+		_, _, _ = session.receive()
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	db := openWireDB(t, fmt.Sprintf("host=localhost port=%d user=gin dbname=beer sslmode=disable", port))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "SELECT pg_sleep(10)")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	request := <-cancelRequest
+	assert.Equal(t, uint32(16), binary.BigEndian.Uint32(request))
+	assert.Equal(t, uint32(cancelRequestCode), binary.BigEndian.Uint32(request[4:]))
+	assert.Equal(t, []byte{0, 0, 0, 42, 0, 0, 0, 7}, request[8:])
+
+	require.NoError(t, db.Close())
+	<-done
+}
+
+func Test_decodeValue(t *testing.T) {
+	assert.Equal(t, int64(-7), decodeValue(int8OID, "-7"))
+	assert.Equal(t, false, decodeValue(boolOID, "f"))
+	assert.Equal(t, time.Date(2023, 5, 17, 0, 0, 0, 0, time.UTC), decodeValue(dateOID, "2023-05-17"))
+	assert.Equal(t, time.Date(2023, 5, 17, 12, 30, 1, 500000000, time.UTC), decodeValue(timestampOID, "2023-05-17 12:30:01.5"))
+
+	timestamp, ok := decodeValue(timestamptzOID, "2023-05-17 12:30:01+05:30").(time.Time)
+	require.True(t, ok)
+	assert.True(t, time.Date(2023, 5, 17, 7, 0, 1, 0, time.UTC).Equal(timestamp))
+
+	assert.Equal(t, "infinity", decodeValue(timestampOID, "infinity"))
+	assert.Equal(t, "1.50", decodeValue(1700, "1.50"))
+}
+
+func Test_rowsAffected(t *testing.T) {
+	assert.Equal(t, int64(5), rowsAffected("INSERT 0 5"))
+	assert.Equal(t, int64(3), rowsAffected("UPDATE 3"))
+	assert.Equal(t, int64(0), rowsAffected("CREATE TABLE"))
+}
@@ -0,0 +1,254 @@
+package embeddedpostgres
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wireConfig is the part of a DSN understood by the internal connection.
+type wireConfig struct {
+	host           string
+	port           uint16
+	user           string
+	password       string
+	database       string
+	sslMode        string
+	sslRootCert    string
+	sslCert        string
+	sslKey         string
+	connectTimeout time.Duration
+	// parameters are further startup parameters, such as application_name, as name and value pairs
+	parameters []string
+}
+
+// clientOnlyKeys are the keys of libpq and lib/pq DSNs which configure the client rather than the session, and which the
+// internal connection has no use for. They are not sent to the server, which would reject them as unknown parameters.
+var clientOnlyKeys = map[string]bool{
+	"binary_parameters":              true,
+	"disable_prepared_binary_result": true,
+	"krbsrvname":                     true,
+	"krbspn":                         true,
+	"sslsni":                         true,
+	"sslinline":                      true,
+	"sslpassword":                    true,
+	"target_session_attrs":           true,
+}
+
+// parseWireDSN parses a key/value DSN such as "host=localhost port=5432 user=postgres sslmode=disable", or a
+// postgres:// URL. Unset values default as in libpq, except that only a single host is supported.
+func parseWireDSN(dsn string) (*wireConfig, error) {
+	settings, err := parseDSNSettings(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &wireConfig{host: "localhost", port: 5432, sslMode: "prefer"}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := settings[key]
+
+		switch key {
+		case "host":
+			config.host = value
+		case "port":
+			port, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %q", value)
+			}
+
+			config.port = uint16(port)
+		case "user":
+			config.user = value
+		case "password":
+			config.password = value
+		case "dbname":
+			config.database = value
+		case "sslmode":
+			switch value {
+			case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+				config.sslMode = value
+			default:
+				return nil, fmt.Errorf("unsupported sslmode %q", value)
+			}
+		case "sslrootcert":
+			config.sslRootCert = value
+		case "sslcert":
+			config.sslCert = value
+		case "sslkey":
+			config.sslKey = value
+		case "connect_timeout":
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid connect_timeout %q", value)
+			}
+
+			config.connectTimeout = time.Duration(seconds) * time.Second
+		case "fallback_application_name":
+			if _, ok := settings["application_name"]; !ok {
+				config.parameters = append(config.parameters, "application_name", value)
+			}
+		default:
+			if !clientOnlyKeys[key] {
+				config.parameters = append(config.parameters, key, value)
+			}
+		}
+	}
+
+	if config.user == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, errors.New("no user in the DSN and the current user is unknown")
+		}
+
+		config.user = current.Username
+	}
+
+	if config.database == "" {
+		config.database = config.user
+	}
+
+	return config, nil
+}
+
+// network and address return where to dial the server, a host name, or a Unix socket directory when the host is a path.
+func (c *wireConfig) network() string {
+	if strings.HasPrefix(c.host, "/") {
+		return "unix"
+	}
+
+	return "tcp"
+}
+
+func (c *wireConfig) address() string {
+	if c.network() == "unix" {
+		return fmt.Sprintf("%s/.s.PGSQL.%d", strings.TrimSuffix(c.host, "/"), c.port)
+	}
+
+	return net.JoinHostPort(c.host, strconv.Itoa(int(c.port)))
+}
+
+// parseDSNSettings parses a key/value DSN or a postgres:// URL into the settings it sets, leaving out those left unset.
+func parseDSNSettings(dsn string) (map[string]string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return parseURLSettings(dsn)
+	}
+
+	return parseKeyValueSettings(dsn)
+}
+
+func parseURLSettings(dsn string) (map[string]string, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+
+	if host := parsed.Hostname(); host != "" {
+		settings["host"] = host
+	}
+
+	if port := parsed.Port(); port != "" {
+		settings["port"] = port
+	}
+
+	if parsed.User != nil {
+		settings["user"] = parsed.User.Username()
+
+		if password, ok := parsed.User.Password(); ok {
+			settings["password"] = password
+		}
+	}
+
+	if database := strings.TrimPrefix(parsed.Path, "/"); database != "" {
+		settings["dbname"] = database
+	}
+
+	for key, values := range parsed.Query() {
+		settings[key] = values[len(values)-1]
+	}
+
+	return settings, nil
+}
+
+// parseKeyValueSettings parses whitespace separated key=value settings, where values may be single quoted and
+// backslashes escape the next character.
+func parseKeyValueSettings(dsn string) (map[string]string, error) {
+	settings := map[string]string{}
+	input := []rune(dsn)
+
+	skipSpaces := func(i int) int {
+		for i < len(input) && (input[i] == ' ' || input[i] == '\t' || input[i] == '\n' || input[i] == '\r') {
+			i++
+		}
+
+		return i
+	}
+
+	for i := skipSpaces(0); i < len(input); i = skipSpaces(i) {
+		start := i
+		for i < len(input) && input[i] != '=' && input[i] != ' ' {
+			i++
+		}
+
+		key := string(input[start:i])
+
+		if i = skipSpaces(i); i >= len(input) || input[i] != '=' {
+			return nil, fmt.Errorf("missing value for %q in DSN", key)
+		}
+
+		i = skipSpaces(i + 1)
+
+		value := &strings.Builder{}
+		quoted := i < len(input) && input[i] == '\''
+
+		if quoted {
+			i++
+		}
+
+		for ; i < len(input); i++ {
+			if !quoted && (input[i] == ' ' || input[i] == '\t' || input[i] == '\n' || input[i] == '\r') {
+				break
+			}
+
+			if quoted && input[i] == '\'' {
+				break
+			}
+
+			if input[i] == '\\' {
+				i++
+				if i >= len(input) {
+					break
+				}
+			}
+
+			value.WriteRune(input[i])
+		}
+
+		if quoted {
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated quoted value for %q in DSN", key)
+			}
+
+			i++
+		}
+
+		settings[key] = value.String()
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,345 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// protocolVersion is version 3.0 of the frontend/backend protocol, as sent in the startup message.
+const protocolVersion = 3 << 16
+
+// Authentication requests sent by the server, as the first field of an authentication message.
+const (
+	authenticationOk                = 0
+	authenticationCleartextPassword = 3
+	authenticationMD5Password       = 5
+	authenticationSASL              = 10
+	authenticationSASLContinue      = 11
+	authenticationSASLFinal         = 12
+)
+
+// wireSession speaks the frontend/backend protocol of Postgres on a connection, which is all the library needs to check
+// that a server is ready and to run its own statements without an SQL driver.
+type wireSession struct {
+	reader   *bufio.Reader
+	writer   io.Writer
+	username string
+	password string
+	scram    *scramExchange
+
+	// backendPID and backendKey identify the session in cancel requests
+	backendPID uint32
+	backendKey uint32
+}
+
+// login sends the startup message for the user, database and any further parameters, given as name and value pairs,
+// and authenticates until the server reports it is ready for queries.
+func (s *wireSession) login(database string, parameters ...string) error {
+	startup := &bytes.Buffer{}
+	_ = binary.Write(startup, binary.BigEndian, int32(protocolVersion))
+
+	for _, parameter := range append([]string{"user", s.username, "database", database}, parameters...) {
+		startup.WriteString(parameter)
+		startup.WriteByte(0)
+	}
+
+	startup.WriteByte(0)
+
+	if err := s.send(0, startup.Bytes()); err != nil {
+		return err
+	}
+
+	for {
+		messageType, payload, err := s.receive()
+		if err != nil {
+			return err
+		}
+
+		switch messageType {
+		case 'R':
+			if err := s.authenticate(payload); err != nil {
+				return err
+			}
+		case 'K':
+			if len(payload) >= 8 {
+				s.backendPID, s.backendKey = binary.BigEndian.Uint32(payload), binary.BigEndian.Uint32(payload[4:])
+			}
+		case 'E':
+			return parseErrorResponse(payload)
+		case 'Z':
+			return nil
+		}
+
+		// parameter statuses and notices before the server is ready are of no interest
+	}
+}
+
+func (s *wireSession) authenticate(payload []byte) error {
+	if len(payload) < 4 {
+		return errors.New("invalid authentication request")
+	}
+
+	request, data := binary.BigEndian.Uint32(payload), payload[4:]
+
+	switch request {
+	case authenticationOk:
+		if s.scram != nil && !s.scram.verified {
+			return errors.New("the server did not complete the SCRAM exchange")
+		}
+
+		return nil
+	case authenticationCleartextPassword:
+		return s.send('p', append([]byte(s.password), 0))
+	case authenticationMD5Password:
+		if len(data) < 4 {
+			return errors.New("invalid md5 authentication request")
+		}
+
+		return s.send('p', append([]byte(md5Password(s.username, s.password, data[:4])), 0))
+	case authenticationSASL:
+		if !bytes.Contains(data, []byte("SCRAM-SHA-256\x00")) {
+			return fmt.Errorf("unsupported SASL mechanisms %q", strings.Trim(string(data), "\x00"))
+		}
+
+		scram, err := newScramExchange(s.password)
+		if err != nil {
+			return err
+		}
+
+		s.scram = scram
+
+		message := &bytes.Buffer{}
+		message.WriteString("SCRAM-SHA-256\x00")
+		_ = binary.Write(message, binary.BigEndian, int32(len(scram.clientFirst())))
+		message.WriteString(scram.clientFirst())
+
+		return s.send('p', message.Bytes())
+	case authenticationSASLContinue:
+		if s.scram == nil {
+			return errors.New("unexpected SASL continuation")
+		}
+
+		clientFinal, err := s.scram.clientFinal(string(data))
+		if err != nil {
+			return err
+		}
+
+		return s.send('p', []byte(clientFinal))
+	case authenticationSASLFinal:
+		if s.scram == nil {
+			return errors.New("unexpected SASL completion")
+		}
+
+		return s.scram.verifyServerFinal(string(data))
+	default:
+		return fmt.Errorf("unsupported authentication request %d", request)
+	}
+}
+
+// send writes a message, or the startup message when the type is 0.
+func (s *wireSession) send(messageType byte, payload []byte) error {
+	_, err := s.writer.Write(appendMessage(nil, messageType, payload))
+
+	return err
+}
+
+// appendMessage appends a message to buf, so that several messages can be sent with a single write.
+func appendMessage(buf []byte, messageType byte, payload []byte) []byte {
+	if messageType != 0 {
+		buf = append(buf, messageType)
+	}
+
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(payload)+4))
+
+	return append(append(buf, length...), payload...)
+}
+
+func (s *wireSession) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 || length > 1<<30 {
+		return 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(s.reader, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+// wireError is an error reported by the server, such as `FATAL: database "beer" does not exist (SQLSTATE 3D000)`.
+type wireError struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (e *wireError) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+}
+
+// parseErrorResponse turns the fields of an error response into a *wireError.
+func parseErrorResponse(payload []byte) error {
+	fields := map[byte]string{}
+
+	for _, field := range bytes.Split(payload, []byte{0}) {
+		if len(field) > 1 {
+			fields[field[0]] = string(field[1:])
+		}
+	}
+
+	return &wireError{Severity: fields['S'], Code: fields['C'], Message: fields['M']}
+}
+
+func md5Password(username, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + username))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramExchange is the client side of a SCRAM-SHA-256 exchange. Postgres ignores the username sent in the exchange in
+// favour of the one in the startup message, so it is left empty.
+type scramExchange struct {
+	password    string
+	clientNonce string
+	// serverSignature is the signature the server must prove it knows the password with, and verified is set once it did
+	serverSignature []byte
+	verified        bool
+}
+
+func newScramExchange(password string) (*scramExchange, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &scramExchange{password: password, clientNonce: base64.RawStdEncoding.EncodeToString(nonce)}, nil
+}
+
+func (s *scramExchange) clientFirstBare() string {
+	return "n=,r=" + s.clientNonce
+}
+
+func (s *scramExchange) clientFirst() string {
+	return "n,," + s.clientFirstBare()
+}
+
+func (s *scramExchange) clientFinal(serverFirst string) (string, error) {
+	attributes := map[string]string{}
+
+	for _, attribute := range strings.Split(serverFirst, ",") {
+		if key, value, ok := strings.Cut(attribute, "="); ok {
+			attributes[key] = value
+		}
+	}
+
+	nonce, encodedSalt, encodedIterations := attributes["r"], attributes["s"], attributes["i"]
+	if !strings.HasPrefix(nonce, s.clientNonce) {
+		return "", errors.New("invalid SCRAM server nonce")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+
+	iterations, err := strconv.Atoi(encodedIterations)
+	if err != nil || iterations < 1 {
+		return "", fmt.Errorf("invalid SCRAM iteration count %q", encodedIterations)
+	}
+
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := s.clientFirstBare() + "," + serverFirst + "," + withoutProof
+	saltedPassword := pbkdf2SHA256([]byte(s.password), salt, iterations)
+
+	s.serverSignature = scramServerSignature(saltedPassword, authMessage)
+	proof := scramClientProof(saltedPassword, authMessage)
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServerFinal checks the signature of the final server message, which proves the server knows the password
+// rather than accepting any proof.
+func (s *scramExchange) verifyServerFinal(serverFinal string) error {
+	if s.serverSignature == nil {
+		return errors.New("unexpected SASL completion")
+	}
+
+	if strings.HasPrefix(serverFinal, "e=") {
+		return fmt.Errorf("SCRAM authentication failed: %s", strings.TrimPrefix(serverFinal, "e="))
+	}
+
+	attribute, _, _ := strings.Cut(serverFinal, ",")
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(attribute, "v="))
+	if err != nil || !strings.HasPrefix(attribute, "v=") || !hmac.Equal(signature, s.serverSignature) {
+		return errors.New("invalid SCRAM server signature")
+	}
+
+	s.verified = true
+
+	return nil
+}
+
+// scramClientProof computes the proof of a SCRAM-SHA-256 exchange as described in RFC 5802.
+func scramClientProof(saltedPassword []byte, authMessage string) []byte {
+	clientKey := hmacSHA256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := hmacSHA256(storedKey[:], []byte(authMessage))
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	return proof
+}
+
+// scramServerSignature computes the signature the server sends at the end of a SCRAM-SHA-256 exchange.
+func scramServerSignature(saltedPassword []byte, authMessage string) []byte {
+	serverKey := hmacSHA256(saltedPassword, []byte("Server Key"))
+
+	return hmacSHA256(serverKey, []byte(authMessage))
+}
+
+// pbkdf2SHA256 derives a key of one SHA-256 block, which is all SCRAM-SHA-256 needs.
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	u := hmacSHA256(password, append(append([]byte(nil), salt...), 0, 0, 0, 1))
+	key := append([]byte(nil), u...)
+
+	for i := 1; i < iterations; i++ {
+		u = hmacSHA256(password, u)
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+
+	return key
+}
+
+func hmacSHA256(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+
+	return mac.Sum(nil)
+}
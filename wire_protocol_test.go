@@ -0,0 +1,140 @@
+package embeddedpostgres
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer accepts one connection on a free port, reads its startup message and hands the session to serve.
+func fakeServer(t *testing.T, serve func(t *testing.T, session *wireSession, startup []byte)) uint32 {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		reader := bufio.NewReader(conn)
+
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+
+		startup := make([]byte, binary.BigEndian.Uint32(header)-4)
+		if _, err := io.ReadFull(reader, startup); err != nil {
+			return
+		}
+
+		serve(t, &wireSession{reader: reader, writer: conn}, startup)
+	}()
+
+	return uint32(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func authentication(request uint32, data ...byte) []byte {
+	payload := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(payload, request)
+
+	return append(payload, data...)
+}
+
+func sendReady(session *wireSession) {
+	_ = session.send('R', authentication(authenticationOk))
+	_ = session.send('S', []byte("server_version\x0015.3\x00"))
+	_ = session.send('Z', []byte("I"))
+}
+
+func Test_wireSession_LoginParametersAndBackendKey(t *testing.T) {
+	port := fakeServer(t, func(t *testing.T, session *wireSession, startup []byte) {
+		assert.Equal(t, "user\x00gin\x00database\x00beer\x00client_encoding\x00UTF8\x00\x00", string(startup[4:]))
+
+		_ = session.send('R', authentication(authenticationOk))
+		_ = session.send('K', []byte{0, 0, 0, 42, 0, 0, 0, 7})
+		_ = session.send('Z', []byte("I"))
+	})
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("localhost", fmt.Sprint(port)))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	session := &wireSession{reader: bufio.NewReader(conn), writer: conn, username: "gin"}
+	require.NoError(t, session.login("beer", "client_encoding", "UTF8"))
+
+	assert.Equal(t, uint32(42), session.backendPID)
+	assert.Equal(t, uint32(7), session.backendKey)
+}
+
+func Test_parseErrorResponse(t *testing.T) {
+	err := parseErrorResponse([]byte("SERROR\x00VERROR\x00C42P01\x00Mrelation \"beer\" does not exist\x00\x00"))
+
+	var wireErr *wireError
+	require.True(t, errors.As(err, &wireErr))
+	assert.Equal(t, "42P01", wireErr.Code)
+	assert.EqualError(t, err, `ERROR: relation "beer" does not exist (SQLSTATE 42P01)`)
+}
+
+func Test_scramClientProof(t *testing.T) {
+	// the example exchange of RFC 7677
+	salt, err := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	require.NoError(t, err)
+
+	authMessage := "n=user,r=rOprNGfwEbeRWgbNEkqO," +
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096," +
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+
+	saltedPassword := pbkdf2SHA256([]byte("pencil"), salt, 4096)
+
+	assert.Equal(t, "dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", base64.StdEncoding.EncodeToString(scramClientProof(saltedPassword, authMessage)))
+	assert.Equal(t, "6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=", base64.StdEncoding.EncodeToString(scramServerSignature(saltedPassword, authMessage)))
+}
+
+func Test_scramExchange_InvalidServerFirst(t *testing.T) {
+	scram, err := newScramExchange("wine")
+	require.NoError(t, err)
+
+	_, err = scram.clientFinal("r=other,s=c2FsdA==,i=4096")
+	assert.EqualError(t, err, "invalid SCRAM server nonce")
+
+	_, err = scram.clientFinal("r=" + scram.clientNonce + ",s=c2FsdA==,i=none")
+	assert.EqualError(t, err, `invalid SCRAM iteration count "none"`)
+}
+
+func Test_scramExchange_VerifiesServerSignature(t *testing.T) {
+	scram, err := newScramExchange("wine")
+	require.NoError(t, err)
+
+	assert.EqualError(t, scram.verifyServerFinal("v=c2lnbmF0dXJl"), "unexpected SASL completion")
+
+	_, err = scram.clientFinal("r=" + scram.clientNonce + "server,s=c2FsdA==,i=4096")
+	require.NoError(t, err)
+
+	assert.EqualError(t, scram.verifyServerFinal("v=c2lnbmF0dXJl"), "invalid SCRAM server signature")
+	assert.EqualError(t, scram.verifyServerFinal("e=invalid-proof"), "SCRAM authentication failed: invalid-proof")
+	assert.False(t, scram.verified)
+
+	require.NoError(t, scram.verifyServerFinal("v="+base64.StdEncoding.EncodeToString(scram.serverSignature)))
+	assert.True(t, scram.verified)
+}